@@ -74,6 +74,7 @@ require go.uber.org/zap v1.28.0
 
 // utils
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/fatih/color v1.19.0
 	github.com/go-json-experiment/json v0.0.0-20260623181947-01eb4420fa68
 	github.com/go-think/openssl v1.22.0
@@ -47,13 +47,13 @@ var (
 	CarryMetricProvider = contextx.Carry[tCtxMetricProvider, otelapimetric.MeterProvider]
 
 	MeterGathererFrom   = contextx.From[tCtxMetricGatherer, prometheus.Gatherer]
-	MustMetricGatherer  = contextx.Must[tCtxMetricProvider, prometheus.Gatherer]
-	WithMetricGatherer  = contextx.With[tCtxMetricProvider, prometheus.Gatherer]
-	CarryMetricGatherer = contextx.Carry[tCtxMetricProvider, prometheus.Gatherer]
+	MustMetricGatherer  = contextx.Must[tCtxMetricGatherer, prometheus.Gatherer]
+	WithMetricGatherer  = contextx.With[tCtxMetricGatherer, prometheus.Gatherer]
+	CarryMetricGatherer = contextx.Carry[tCtxMetricGatherer, prometheus.Gatherer]
 )
 
 func MetricProviderFrom(ctx context.Context) otelapimetric.MeterProvider {
-	return contextx.FromOr[tCtxMetricProvider](ctx, noop.NewMeterProvider())
+	return contextx.FromOr[tCtxMetricProvider, otelapimetric.MeterProvider](ctx, noop.NewMeterProvider())
 }
 
 func MeterFrom(ctx context.Context) otelapimetric.Meter {
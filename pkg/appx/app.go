@@ -7,8 +7,11 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -32,6 +35,23 @@ func WithRoot(root string) Option {
 	}
 }
 
+// WithSources sets ordered configuration sources, values from later sources
+// override earlier ones. eg:
+//
+//	appx.WithSources(
+//		envx.FromNestedYAML("/etc/app/config.yml"), // ConfigMap
+//		envx.FromSecretDir("/etc/app/secrets"),     // mounted secrets
+//		envx.FromEnv(),
+//	)
+//
+// When omitted, config/local.yml under [AppCtx.Root] is injected into unset
+// env vars and process environment is the only source.
+func WithSources(sources ...envx.Source) Option {
+	return func(app *AppCtx) {
+		app.sources = append(app.sources, sources...)
+	}
+}
+
 // WithMeta sets the application [Meta], replacing [DefaultMeta].
 func WithMeta(meta Meta) Option {
 	return func(app *AppCtx) {
@@ -87,7 +107,9 @@ func NewAppContext(main func() error, options ...Option) *AppCtx {
 	app := &AppCtx{
 		cmd:    &cobra.Command{},
 		root:   must.NoErrorV(os.Getwd()),
+		locals: make(map[string]string),
 		option: AppOption{Meta: new(DefaultMeta)},
+		exit:   os.Exit,
 	}
 
 	app.AddCommand(
@@ -96,7 +118,18 @@ func NewAppContext(main func() error, options ...Option) *AppCtx {
 		func( /*cmd *cobra.Command,*/ args []string) error {
 			fmt.Printf("%s\n\n", color.HiCyanString(app.Version()))
 			app.log()
+			if app.option.Managed {
+				if code := app.runManaged(main); code != EXIT_CODE_OK {
+					app.exit(code)
+				}
+				return nil
+			}
+			app.watch()
+			if err := app.health.serve(); err != nil {
+				return err
+			}
 			app.option.PreRun()
+			app.runners.start(app.Context())
 			app.option.Serve()
 			return main()
 		},
@@ -116,6 +149,8 @@ func NewAppContext(main func() error, options ...Option) *AppCtx {
 			cmd.Println(app.Version())
 		},
 	})
+	app.cmd.AddCommand(app.configCommand())
+	app.cmd.AddCommand(app.migrateCommand())
 
 	return app
 }
@@ -127,7 +162,28 @@ type AppCtx struct {
 	root       string
 	dfts       []*envx.Group
 	vars       []*envx.Group
+	refs       []*envx.Reference
 	components []reflect.Value
+	// ordered holds components in topological order of initialization
+	ordered []*component
+	// locals holds env vars injected from config/local.yml
+	locals map[string]string
+	// sources are configuration sources set by WithSources
+	sources []envx.Source
+	// values are merged from sources
+	values map[string]string
+
+	// ctx is the context returned by Conf, watchers derive from it
+	ctx    context.Context
+	cancel context.CancelFunc
+	mtx    sync.Mutex
+
+	// health aggregates liveness of components, served if WithHealth set
+	health health
+	// runners supervises Runnable and Servable components on `run`
+	runners runners
+	// exit terminates process in managed run, replaced in tests
+	exit func(code int)
 
 	option AppOption
 }
@@ -148,20 +204,25 @@ func (app *AppCtx) Root() string {
 	return app.root
 }
 
-// Conf loads one or more config pointers from the environment, writes default
+// Conf loads one or more config pointers from sources, writes default
 // config files under [AppCtx.Root]/config, runs [WithPreInit], then initializes
 // fields that support types.InitByContext. Meta is injected into ctx via
 // [WithAppMeta] before init.
 //
 // Each named config type becomes an envx group (e.g. APP__OTEL). Anonymous
 // structs are allowed only when a single configuration is passed.
+//
+// Values are validated by rules in `env` tag (see envx.NewValidator), all
+// violations across groups are reported at once by a panic.
 func (app *AppCtx) Conf(ctx context.Context, configurations ...any) context.Context {
-	app.injectLocalConfig()
+	must.NoErrorF(app.load(), "failed to load config sources")
 
 	app.dfts = make([]*envx.Group, 0, len(configurations))
 	app.vars = make([]*envx.Group, 0, len(configurations))
+	app.refs = make([]*envx.Reference, 0, len(configurations))
 	app.components = make([]reflect.Value, 0, len(configurations))
 	names := map[string]struct{}{}
+	errs := make([]error, 0)
 
 	for _, c := range configurations {
 		rv := reflect.ValueOf(c)
@@ -177,27 +238,89 @@ func (app *AppCtx) Conf(ctx context.Context, configurations ...any) context.Cont
 		group := app.group(name)
 
 		app.dfts = append(app.dfts, app.marshalDefaults(group, rv))
-		app.vars = append(app.vars, app.scanEnvironment(group, rv))
+		app.refs = append(app.refs, app.describe(group, rv))
+
+		vars, err := app.scanEnvironment(group, rv)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		app.vars = append(app.vars, vars)
 		app.components = append(app.components, rv)
 	}
+	must.NoErrorF(errors.Join(errs...), "invalid configurations")
+
+	ordered, err := app.graph()
+	must.NoErrorF(err, "invalid component dependencies")
+	app.ordered = ordered
 
 	app.mustWriteDefault()
 
 	app.option.PreInit()
-	return app.initial(WithAppMeta(ctx, *app.option.Meta))
+	app.ctx = app.initial(WithAppMeta(ctx, *app.option.Meta))
+
+	app.health.meta = app.option.Meta
+	app.health.names, app.health.checkers, app.health.results = nil, nil, nil
+	for i := range app.components {
+		app.health.discover(app.vars[i], app.components[i])
+	}
+	app.health.ready.Store(true)
+
+	app.runners.list = nil
+	for i := range app.components {
+		app.runners.discover(app.vars[i], app.components[i])
+	}
+	return app.ctx
 }
 
 // Close shuts down the application.
 //
 // It first closes components registered through [AppCtx.Conf] via
-// types.CloseByContext (no [WithClose] needed for those), then runs any
-// callbacks registered with [WithClose]. Errors are joined and returned.
+// types.CloseByContext in reverse order of initialization (no [WithClose]
+// needed for those), then runs any
+// callbacks registered with [WithClose]. Watchers registered by [WithReload]
+// and health server of [WithHealth] are stopped before closing, and /readyz
+// reports unavailable since then. Runnable components are canceled and
+// Servable components are shut down before closing. Errors are joined and
+// returned.
 func (app *AppCtx) Close(ctx context.Context) error {
-	errs := make([]error, 0, len(app.components))
-	for i := range app.components {
-		if err := types.CloseByContext(ctx, app.components[i]); err != nil {
+	return app.close(ctx, nil)
+}
+
+// close closes app and reports closing duration of each closable component
+// if report is not nil
+func (app *AppCtx) close(ctx context.Context, report func(name string, d time.Duration, err error)) error {
+	if app.cancel != nil {
+		app.cancel()
+	}
+
+	errs := make([]error, 0, len(app.ordered))
+	if err := app.health.shutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := app.runners.stop(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	for i := len(app.ordered) - 1; i >= 0; i-- {
+		c := app.ordered[i]
+		if !types.IsClosable(c.value) || app.runners.shutdown(c.value) {
+			continue
+		}
+		// fields are closed by a closable configuration, and fields of a
+		// configuration skipped initializing are not closed
+		if p := c.parent; p != nil && (p.skipped.Load() || types.IsClosable(p.value)) {
+			continue
+		}
+		start := time.Now()
+		err := types.CloseByContext(ctx, c.value)
+		if errors.Is(err, types.ErrSkipClosing) {
+			continue
+		}
+		if err != nil {
 			errs = append(errs, err)
 		}
+		if report != nil {
+			report(c.key(), time.Since(start), err)
+		}
 	}
 	for i := range app.option.CloseFns {
 		if f := app.option.CloseFns[i]; f != nil {
@@ -237,20 +360,44 @@ func (app *AppCtx) AddCommand(name, short string, runE func(args []string) error
 	)
 }
 
+// injectLocalConfig sets env vars from config/local.yml if they are not set by
+// process environment. vars injected previously are updated or unset to keep
+// consistent with local.yml when reloading.
 func (app *AppCtx) injectLocalConfig() {
+	kv := make(map[string]string)
 	local, err := os.ReadFile(filepath.Join(app.root, "./config/local.yml"))
 	if err == nil {
-		kv := make(map[string]string)
-		if err = yaml.Unmarshal(local, &kv); err == nil {
-			for k, v := range kv {
-				if _, ok := os.LookupEnv(k); !ok {
-					_ = os.Setenv(k, v)
-				}
-			}
+		if err = yaml.Unmarshal(local, &kv); err != nil {
+			return
+		}
+	}
+
+	for k := range app.locals {
+		if _, ok := kv[k]; !ok {
+			_ = os.Unsetenv(k)
+			delete(app.locals, k)
+		}
+	}
+	for k, v := range kv {
+		_, injected := app.locals[k]
+		if _, ok := os.LookupEnv(k); !ok || injected {
+			_ = os.Setenv(k, v)
+			app.locals[k] = v
 		}
 	}
 }
 
+// load merges configuration sources into values
+func (app *AppCtx) load() (err error) {
+	sources := app.sources
+	if len(sources) == 0 {
+		app.injectLocalConfig()
+		sources = []envx.Source{envx.FromEnv()}
+	}
+	app.values, err = envx.Merge(sources...)
+	return err
+}
+
 func (app *AppCtx) marshalDefaults(group string, v any) *envx.Group {
 	dft := envx.NewGroup(group)
 	must.NoErrorF(envx.NewDecoder(dft).Decode(v), "failed to decode default")
@@ -258,11 +405,22 @@ func (app *AppCtx) marshalDefaults(group string, v any) *envx.Group {
 	return dft
 }
 
-func (app *AppCtx) scanEnvironment(group string, v any) *envx.Group {
-	vars := envx.ParseGroupFromEnv(group)
-	must.NoErrorF(envx.NewDecoder(vars).Decode(v), "failed to decode env")
+// describe documents keys of v, v should hold default values
+func (app *AppCtx) describe(group string, v any) *envx.Reference {
+	ref := envx.NewReference(envx.NewGroup(group))
+	must.NoErrorF(ref.Describe(v), "failed to describe config")
+	return ref
+}
+
+// scanEnvironment decodes and validates v from group vars. validation errors
+// are returned to report all misconfigurations at once.
+func (app *AppCtx) scanEnvironment(group string, v any) (*envx.Group, error) {
+	vars := envx.ParseGroup(group, app.values)
+	if err := envx.NewDecoder(vars).Decode(v); err != nil {
+		return vars, fmt.Errorf("failed to decode env [group:%s]: %w", group, err)
+	}
 	must.NoErrorF(envx.NewEncoder(vars).Encode(v), "failed to encode env")
-	return vars
+	return vars, envx.NewValidator(vars).Validate(v)
 }
 
 func initialize(ctx context.Context, v reflect.Value, g *envx.Group, field string) context.Context {
@@ -285,22 +443,17 @@ func initialize(ctx context.Context, v reflect.Value, g *envx.Group, field strin
 	return ctx
 }
 
-func (app *AppCtx) initial(ctx context.Context) context.Context {
-	for i := range app.components {
-		ctx = initialize(ctx, app.components[i], app.vars[i], "")
-	}
-	return ctx
-}
-
 func (app *AppCtx) log() {
 	app.option.Meta.Print()
 
-	sort.Slice(app.vars, func(i, j int) bool {
-		return app.vars[i].Name() < app.vars[j].Name()
+	// sort a copy, vars are indexed in the same order of components
+	vars := slices.Clone(app.vars)
+	sort.Slice(vars, func(i, j int) bool {
+		return vars[i].Name() < vars[j].Name()
 	})
 
-	for i := range app.vars {
-		fmt.Print(color.HiBlueString("%s", app.vars[i].MaskBytes()))
+	for i := range vars {
+		fmt.Print(color.HiBlueString("%s", vars[i].MaskBytes()))
 	}
 	fmt.Println("")
 }
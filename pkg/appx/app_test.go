@@ -543,3 +543,100 @@ func TestAppCtx_ConfOrchestration(t *testing.T) {
 		Expect(t, b, Equal(true))
 	})
 }
+
+func TestWithSources(t *testing.T) {
+	root := t.TempDir()
+	must.NoError(os.MkdirAll(filepath.Join(root, "config"), os.ModePerm))
+	must.NoError(os.WriteFile(filepath.Join(root, "config/local.yml"), []byte(`TEST__CONFIG1__Endpoint_Auth_Username: "local"`), os.ModePerm))
+	must.NoError(os.WriteFile(filepath.Join(root, "config.yml"), []byte(`
+TEST__CONFIG1:
+  WorkerID: 2
+  Endpoint:
+    Address: http://localhost:80/nested
+`), os.ModePerm))
+
+	t.Setenv("TEST__CONFIG1__WorkerID", "3")
+
+	app := NewAppContext(
+		_main,
+		WithMeta(Meta{Name: "TEST"}),
+		WithRoot(root),
+		WithSources(
+			envx.FromNestedYAML(filepath.Join(root, "config.yml")),
+			envx.FromEnv(),
+			envx.FromMap(map[string]string{"TEST__CONFIG1__Endpoint_Address": "http://localhost:80/override"}),
+		),
+	)
+	c := &Config1{}
+	app.Conf(context.Background(), c)
+
+	Expect(t, c.WorkerID, Equal(3))
+	Expect(t, c.Endpoint.Address, Equal("http://localhost:80/override"))
+	// local.yml is not injected when sources are set
+	_, ok := os.LookupEnv("TEST__CONFIG1__Endpoint_Auth_Username")
+	Expect(t, ok, BeFalse())
+	Expect(t, c.Endpoint.Auth.Username, Equal(""))
+}
+
+type (
+	ValidatedA struct {
+		Address string `env:",required,url"`
+	}
+	ValidatedB struct {
+		PoolSize int `env:",min=1,max=10"`
+	}
+)
+
+func TestAppCtx_ConfValidation(t *testing.T) {
+	app := NewAppContext(
+		_main,
+		WithMeta(Meta{Name: "TEST"}),
+		WithRoot(t.TempDir()),
+		WithSources(envx.FromMap(map[string]string{
+			"TEST__VALIDATEDB__PoolSize": "20",
+		})),
+	)
+
+	ExpectPanic[error](
+		t, func() { app.Conf(context.Background(), &ValidatedA{}, &ValidatedB{}) },
+		ErrorContains("TEST__VALIDATEDA__Address"),
+		ErrorContains("TEST__VALIDATEDB__PoolSize"),
+	)
+}
+
+func TestAppCtx_ConfigDoc(t *testing.T) {
+	app := NewAppContext(
+		_main,
+		WithMeta(Meta{Name: "TEST"}),
+		WithRoot(t.TempDir()),
+		WithSources(envx.FromMap(map[string]string{
+			"TEST__CONFIG1__WorkerID": "3",
+		})),
+	)
+	app.Conf(context.Background(), &Config1{WorkerID: 1})
+
+	t.Run("Markdown", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		app.cmd.SetOut(buf)
+		app.cmd.SetArgs([]string{"config", "doc"})
+		Expect(t, app.cmd.Execute(), Succeed())
+		Expect(t, buf.String(), ContainsSubString("## TEST__CONFIG1\n"))
+		// defaults are documented instead of values from sources
+		Expect(t, buf.String(), ContainsSubString("| `TEST__CONFIG1__WorkerID` | `int` | `1` |"))
+		Expect(t, buf.String(), ContainsSubString("component connection endpoint address"))
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "schema.json")
+		app.cmd.SetArgs([]string{"config", "doc", "--format", "schema", "-o", output})
+		Expect(t, app.cmd.Execute(), Succeed())
+		content, err := os.ReadFile(output)
+		Expect(t, err, Succeed())
+		Expect(t, string(content), ContainsSubString(`"TEST__CONFIG1__Endpoint_Address"`))
+	})
+
+	t.Run("UnsupportedFormat", func(t *testing.T) {
+		_, err := app.ConfigDoc("html")
+		Expect(t, err, Failed())
+	})
+}
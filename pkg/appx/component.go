@@ -0,0 +1,440 @@
+package appx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xoctopus/x/misc/must"
+	"github.com/xoctopus/x/reflectx"
+
+	"github.com/xoctopus/confx/pkg/envx"
+	"github.com/xoctopus/confx/pkg/types"
+)
+
+// WithParallelInit initializes independent components concurrently. components
+// in the same topological level are initialized in parallel and values they
+// injected are visible to the next level.
+func WithParallelInit() Option {
+	return func(app *AppCtx) {
+		app.option.ParallelInit = true
+	}
+}
+
+// WithInitTimeout sets the default timeout of initializing each component.
+// `init:",timeout=5s"` in struct tag overrides it for a field. the timeout is
+// not propagated to ctx of Init, and Init must not rely on the deadline.
+func WithInitTimeout(timeout time.Duration) Option {
+	return func(app *AppCtx) {
+		app.option.InitTimeout = timeout
+	}
+}
+
+// component is a node of the initialization graph, which is a configuration or
+// an exported field of a configuration. fields depend on their configuration
+// implicitly, other dependencies are declared by types.Dependent or struct tag
+//
+//	type Config struct {
+//		Log  conflogx.Log
+//		Otel confotel.Config
+//		Job  confxxl.Endpoint `init:",depends=Log|Otel,timeout=10s"`
+//	}
+type component struct {
+	// name is `Config.Field` of a field, or `Config` of a configuration
+	name    string
+	conf    string
+	field   string
+	group   *envx.Group
+	value   reflect.Value
+	index   int
+	timeout time.Duration
+	depends []string
+
+	deps []*component
+	// parent is the configuration of a field
+	parent *component
+	// skipped marks the configuration skipped initializing, its fields are
+	// skipped as well
+	skipped atomic.Bool
+}
+
+func (c *component) String() string {
+	if c.name == "" {
+		return "<anonymous>"
+	}
+	return c.name
+}
+
+// key returns env key of component, such as APP__CONFIG__Redis
+func (c *component) key() string {
+	if c.field == "" {
+		return c.group.Name()
+	}
+	return c.group.Key(c.field)
+}
+
+// init initializes the component and returns ctx with its injection. fields of
+// a configuration are initialized by their own components, and are skipped if
+// the configuration skipped initializing.
+func (c *component) init(ctx context.Context) context.Context {
+	if c.parent != nil {
+		if c.parent.skipped.Load() {
+			return ctx
+		}
+		return initialize(ctx, c.value, c.group, c.field)
+	}
+	err := types.InitByContext(ctx, c.value)
+	if errors.Is(err, types.ErrSkipInitializing) {
+		c.skipped.Store(true)
+		return ctx
+	}
+	must.NoErrorF(err, "failed to init [group:%s] [field:%s]", c.group.Name(), c.field)
+	return types.Inject(ctx, c.value)
+}
+
+// run initializes the component in timeout. the timeout only bounds waiting,
+// Init receives ctx without deadline, so that components can keep it after
+// initialized, eg: servers spawned in Init. Init must not rely on the deadline,
+// and it keeps running when timed out, which panics.
+func (c *component) run(ctx context.Context) context.Context {
+	if c.timeout <= 0 {
+		return c.init(ctx)
+	}
+
+	type result struct {
+		ctx context.Context
+		err any
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{err: r}
+			}
+		}()
+		done <- result{ctx: c.init(ctx)}
+	}()
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			panic(r.err)
+		}
+		return r.ctx
+	case <-timer.C:
+		must.NoErrorF(context.DeadlineExceeded, "failed to init [group:%s] [field:%s] in %s", c.group.Name(), c.field, c.timeout)
+		return ctx
+	}
+}
+
+// values looks up values from ctxs in reverse order and falls back to Context.
+// it merges injections of components initialized concurrently.
+type values struct {
+	context.Context
+	ctxs []context.Context
+}
+
+func (c *values) Value(key any) any {
+	for i := len(c.ctxs) - 1; i >= 0; i-- {
+		if v := c.ctxs[i].Value(key); v != nil {
+			return v
+		}
+	}
+	return c.Context.Value(key)
+}
+
+// graph builds initialization graph of configurations and returns components
+// in topological order, ties are broken by declaration order.
+func (app *AppCtx) graph() ([]*component, error) {
+	var (
+		nodes []*component
+		names = make(map[string]*component)
+	)
+
+	for i, rv := range app.components {
+		conf := reflectx.Indirect(rv).Type().Name()
+		c := &component{
+			name:    conf,
+			conf:    conf,
+			group:   app.vars[i],
+			value:   rv,
+			index:   len(nodes),
+			timeout: app.option.InitTimeout,
+			depends: types.DependsOn(rv),
+		}
+		nodes = append(nodes, c)
+		names[c.name] = c
+
+		v := reflectx.Indirect(rv)
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		for j := 0; j < v.NumField(); j++ {
+			f := v.Type().Field(j)
+			if !f.IsExported() {
+				continue
+			}
+			fc := &component{
+				name:    f.Name,
+				conf:    conf,
+				field:   f.Name,
+				group:   app.vars[i],
+				value:   v.Field(j),
+				index:   len(nodes),
+				timeout: app.option.InitTimeout,
+				depends: slices.Clone(types.DependsOn(v.Field(j))),
+				deps:    []*component{c},
+				parent:  c,
+			}
+			if conf != "" {
+				fc.name = conf + "." + f.Name
+			}
+			if flag := reflectx.ParseTag(f.Tag).Get("init"); flag != nil {
+				for opt := range flag.Options() {
+					switch opt.Key() {
+					case "depends":
+						fc.depends = append(fc.depends, strings.Split(opt.Unquoted(), "|")...)
+					case "timeout":
+						d, err := time.ParseDuration(opt.Unquoted())
+						if err != nil {
+							return nil, fmt.Errorf("invalid init timeout of %s: %w", fc, err)
+						}
+						fc.timeout = d
+					}
+				}
+			}
+			nodes = append(nodes, fc)
+			names[fc.name] = fc
+		}
+	}
+
+	// resolve dependencies: sibling field, configuration, qualified field name
+	// or unique field name of any configuration
+	for _, c := range nodes {
+		for _, name := range c.depends {
+			if name == "" {
+				continue
+			}
+			var dep *component
+			if d, ok := names[c.conf+"."+name]; ok && c.conf != "" {
+				dep = d
+			} else if d, ok = names[name]; ok {
+				dep = d
+			} else {
+				for _, n := range nodes {
+					if n.field == name {
+						if dep != nil {
+							return nil, fmt.Errorf("ambiguous dependency `%s` of %s", name, c)
+						}
+						dep = n
+					}
+				}
+			}
+			if dep == nil {
+				return nil, fmt.Errorf("unknown dependency `%s` of %s", name, c)
+			}
+			if dep == c {
+				return nil, fmt.Errorf("%s depends on itself", c)
+			}
+			if !slices.Contains(c.deps, dep) {
+				c.deps = append(c.deps, dep)
+			}
+		}
+	}
+
+	return sorted(nodes)
+}
+
+// sorted sorts nodes topologically, nodes are picked by declaration order when
+// ready. a cycle is reported if exists.
+func sorted(nodes []*component) ([]*component, error) {
+	var (
+		ordered = make([]*component, 0, len(nodes))
+		done    = make(map[*component]bool)
+	)
+
+	for len(ordered) < len(nodes) {
+		picked := false
+		for _, c := range nodes {
+			if done[c] {
+				continue
+			}
+			ready := true
+			for _, d := range c.deps {
+				if !done[d] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				done[c] = true
+				ordered = append(ordered, c)
+				picked = true
+				break
+			}
+		}
+		if !picked {
+			return nil, fmt.Errorf("dependency cycle: %s", cycle(nodes, done))
+		}
+	}
+	return ordered, nil
+}
+
+// cycle finds a dependency cycle in nodes not done
+func cycle(nodes []*component, done map[*component]bool) string {
+	var (
+		path    []*component
+		visited = make(map[*component]bool)
+		visit   func(*component) []*component
+	)
+	visit = func(c *component) []*component {
+		if i := slices.Index(path, c); i >= 0 {
+			return append(slices.Clone(path[i:]), c)
+		}
+		if visited[c] || done[c] {
+			return nil
+		}
+		visited[c] = true
+		path = append(path, c)
+		for _, d := range c.deps {
+			if found := visit(d); found != nil {
+				return found
+			}
+		}
+		path = path[:len(path)-1]
+		return nil
+	}
+
+	for _, c := range nodes {
+		if found := visit(c); found != nil {
+			names := make([]string, 0, len(found))
+			for _, n := range found {
+				names = append(names, n.String())
+			}
+			return strings.Join(names, " -> ")
+		}
+	}
+	return ""
+}
+
+// levels groups ordered components by topological level, components in the same
+// level are independent.
+func levels(ordered []*component) [][]*component {
+	var (
+		level  = make(map[*component]int)
+		groups [][]*component
+	)
+	for _, c := range ordered {
+		l := 0
+		for _, d := range c.deps {
+			l = max(l, level[d]+1)
+		}
+		level[c] = l
+		if l == len(groups) {
+			groups = append(groups, nil)
+		}
+		groups[l] = append(groups[l], c)
+	}
+	for _, g := range groups {
+		slices.SortFunc(g, func(a, b *component) int { return a.index - b.index })
+	}
+	return groups
+}
+
+// initial initializes components in topological order
+func (app *AppCtx) initial(ctx context.Context) context.Context {
+	if !app.option.ParallelInit {
+		for _, c := range app.ordered {
+			ctx = c.run(ctx)
+		}
+		return ctx
+	}
+
+	for _, level := range levels(app.ordered) {
+		if len(level) == 1 {
+			ctx = level[0].run(ctx)
+			continue
+		}
+
+		var (
+			wg     = &sync.WaitGroup{}
+			ctxs   = make([]context.Context, len(level))
+			panics = make([]any, len(level))
+		)
+		for i, c := range level {
+			wg.Go(func() {
+				defer func() {
+					panics[i] = recover()
+				}()
+				ctxs[i] = c.run(ctx)
+			})
+		}
+		wg.Wait()
+		for i := range panics {
+			if panics[i] != nil {
+				panic(panics[i])
+			}
+		}
+		ctx = &values{Context: ctx, ctxs: ctxs}
+	}
+	return ctx
+}
+
+// walk visits v and its exported fields recursively with env key, such as
+// APP__CONFIG__Redis. fields of a value are not visited if visit returns true.
+func walk(g *envx.Group, v reflect.Value, visit func(key string, rv reflect.Value) bool) {
+	var fields func(pw *envx.PathWalker, rv reflect.Value)
+
+	fields = func(pw *envx.PathWalker, rv reflect.Value) {
+		if !rv.IsValid() {
+			return
+		}
+		if rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+			if rv.IsNil() {
+				return
+			}
+			fields(pw, rv.Elem())
+			return
+		}
+
+		key := g.Name()
+		if path := pw.String(); path != "" {
+			key = g.Key(path)
+		}
+		if visit(key, rv) || rv.Kind() != reflect.Struct {
+			return
+		}
+
+		_ = envx.WalkFields(pw, rv.Type(), func(i int, _ string, _ *reflectx.Flag) error {
+			fields(pw, rv.Field(i))
+			return nil
+		})
+	}
+
+	fields(envx.NewPathWalker(), v)
+}
+
+// capability returns rv or its address as T
+func capability[T any](rv reflect.Value) (T, bool) {
+	if rv.CanAddr() && rv.Addr().CanInterface() {
+		if x, ok := rv.Addr().Interface().(T); ok {
+			return x, true
+		}
+	}
+	if rv.CanInterface() {
+		if x, ok := rv.Interface().(T); ok {
+			return x, true
+		}
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,233 @@
+package appx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/types"
+)
+
+type depKey string
+
+type depLog struct {
+	mtx  sync.Mutex
+	logs []string
+}
+
+func (l *depLog) add(s string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.logs = append(l.logs, s)
+}
+
+type depNode struct {
+	name string
+	log  *depLog
+	// wait blocks Init until closed
+	wait chan struct{}
+	// start is closed when Init starts
+	start chan struct{}
+	saw   []string
+}
+
+func (n *depNode) Init(ctx context.Context) error {
+	if n.start != nil {
+		close(n.start)
+	}
+	if n.wait != nil {
+		select {
+		case <-n.wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	for _, k := range []string{"A", "B"} {
+		if ctx.Value(depKey(k)) != nil {
+			n.saw = append(n.saw, k)
+		}
+	}
+	n.log.add(n.name + ".init")
+	return nil
+}
+
+func (n *depNode) WithContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, depKey(n.name), true)
+}
+
+func (n *depNode) Close() { n.log.add(n.name + ".close") }
+
+type depOnA struct{ depNode }
+
+func (depOnA) DependsOn() []string { return []string{"A"} }
+
+// depConf is a configuration with fields
+type depConf struct {
+	A    depNode
+	B    depNode
+	skip bool
+	log  *depLog
+}
+
+func (c *depConf) Init() error {
+	if c.skip {
+		return types.ErrSkipInitializing
+	}
+	c.log.add("conf.init")
+	return nil
+}
+
+// closableConf closes its fields by itself
+type closableConf struct {
+	A   depNode
+	B   depNode
+	log *depLog
+}
+
+func (c *closableConf) Init() { c.log.add("conf.init") }
+
+func (c *closableConf) Close() {
+	c.B.Close()
+	c.A.Close()
+	c.log.add("conf.close")
+}
+
+// keeper keeps ctx of Init, eg: a server spawned in Init
+type keeper struct {
+	ctx context.Context
+}
+
+func (k *keeper) Init(ctx context.Context) { k.ctx = ctx }
+
+func TestAppCtx_InitDependencies(t *testing.T) {
+	t.Run("TopologicalOrder", func(t *testing.T) {
+		log := &depLog{}
+		cfg := &struct {
+			C depOnA
+			B depNode `init:",depends=A"`
+			A depNode
+		}{
+			C: depOnA{depNode{name: "C", log: log}},
+			B: depNode{name: "B", log: log},
+			A: depNode{name: "A", log: log},
+		}
+		app := newOrchApp(t)
+		app.Conf(context.Background(), cfg)
+		Expect(t, log.logs, Equal([]string{"A.init", "C.init", "B.init"}))
+		Expect(t, cfg.B.saw, Equal([]string{"A"}))
+
+		log.logs = nil
+		Expect(t, app.Close(context.Background()), Succeed())
+		Expect(t, log.logs, Equal([]string{"B.close", "C.close", "A.close"}))
+	})
+
+	t.Run("SkipFields", func(t *testing.T) {
+		log := &depLog{}
+		cfg := &depConf{
+			A:    depNode{name: "A", log: log},
+			B:    depNode{name: "B", log: log},
+			skip: true,
+			log:  log,
+		}
+		app := newOrchApp(t)
+		app.Conf(context.Background(), cfg)
+		Expect(t, len(log.logs), Equal(0))
+		Expect(t, app.Close(context.Background()), Succeed())
+		Expect(t, len(log.logs), Equal(0))
+	})
+
+	t.Run("ClosedByConfiguration", func(t *testing.T) {
+		log := &depLog{}
+		cfg := &closableConf{
+			A:   depNode{name: "A", log: log},
+			B:   depNode{name: "B", log: log},
+			log: log,
+		}
+		app := newOrchApp(t)
+		app.Conf(context.Background(), cfg)
+		Expect(t, log.logs, Equal([]string{"conf.init", "A.init", "B.init"}))
+
+		log.logs = nil
+		Expect(t, app.Close(context.Background()), Succeed())
+		Expect(t, log.logs, Equal([]string{"B.close", "A.close", "conf.close"}))
+	})
+
+	t.Run("Parallel", func(t *testing.T) {
+		log := &depLog{}
+		cfg := &struct {
+			A depNode
+			B depNode
+			C depNode `init:",depends=A|B"`
+		}{
+			A: depNode{name: "A", log: log, wait: make(chan struct{})},
+			B: depNode{name: "B", log: log, start: make(chan struct{})},
+			C: depNode{name: "C", log: log},
+		}
+		// A waits until B started, which deadlocks if initialized sequentially
+		go func() {
+			<-cfg.B.start
+			close(cfg.A.wait)
+		}()
+
+		app := NewAppContext(
+			_main,
+			WithMeta(Meta{Name: "ORCH"}),
+			WithRoot(t.TempDir()),
+			WithParallelInit(),
+			WithInitTimeout(time.Second),
+		)
+		ctx := app.Conf(context.Background(), cfg)
+		Expect(t, cfg.C.saw, Equal([]string{"A", "B"}))
+		Expect(t, log.logs[2], Equal("C.init"))
+		Expect(t, ctx.Value(depKey("C")), Equal[any](true))
+		_, ok := ctx.Deadline()
+		Expect(t, ok, BeFalse())
+	})
+
+	t.Run("KeepContextInTimeout", func(t *testing.T) {
+		cfg := &struct {
+			A keeper `init:",timeout=10ms"`
+		}{}
+		newOrchApp(t).Conf(context.Background(), cfg)
+		time.Sleep(20 * time.Millisecond)
+		Expect(t, cfg.A.ctx.Err(), Succeed())
+		_, ok := cfg.A.ctx.Deadline()
+		Expect(t, ok, BeFalse())
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		cfg := &struct {
+			A depNode `init:",timeout=10ms"`
+		}{
+			A: depNode{name: "A", log: &depLog{}, wait: make(chan struct{})},
+		}
+		ExpectPanic[error](
+			t, func() { newOrchApp(t).Conf(context.Background(), cfg) },
+			ErrorContains("failed to init [group:ORCH] [field:A]"),
+		)
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		cfg := &struct {
+			A depNode `init:",depends=B"`
+			B depNode `init:",depends=A"`
+		}{}
+		ExpectPanic[error](
+			t, func() { newOrchApp(t).Conf(context.Background(), cfg) },
+			ErrorContains("dependency cycle: A -> B -> A"),
+		)
+	})
+
+	t.Run("UnknownDependency", func(t *testing.T) {
+		cfg := &struct {
+			A depNode `init:",depends=X"`
+		}{}
+		ExpectPanic[error](
+			t, func() { newOrchApp(t).Conf(context.Background(), cfg) },
+			ErrorContains("unknown dependency `X` of A"),
+		)
+	})
+}
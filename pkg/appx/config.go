@@ -0,0 +1,62 @@
+package appx
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/xoctopus/confx/pkg/envx"
+)
+
+const (
+	// DOC_FORMAT_MARKDOWN renders configuration reference as markdown tables.
+	DOC_FORMAT_MARKDOWN = "markdown"
+	// DOC_FORMAT_SCHEMA renders configuration reference as JSON Schema.
+	DOC_FORMAT_SCHEMA = "schema"
+)
+
+// ConfigDoc renders reference of configurations registered by [AppCtx.Conf].
+// format is "markdown" or "schema" (JSON Schema). each key is documented with
+// go type, default value, optional and masked flags, validation rules and
+// description from field comments.
+func (app *AppCtx) ConfigDoc(format string) ([]byte, error) {
+	switch format {
+	case DOC_FORMAT_MARKDOWN, "":
+		return envx.Markdown(app.refs...), nil
+	case DOC_FORMAT_SCHEMA:
+		return envx.JSONSchema(app.refs...)
+	default:
+		return nil, fmt.Errorf("unsupported doc format: %s", format)
+	}
+}
+
+// configCommand builds `config` command with `doc` subcommand
+func (app *AppCtx) configCommand() *cobra.Command {
+	var format, output string
+
+	doc := &cobra.Command{
+		Use:   "doc",
+		Short: "generate configuration reference",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			content, err := app.ConfigDoc(format)
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				_, err = cmd.OutOrStdout().Write(content)
+				return err
+			}
+			return os.WriteFile(output, content, 0o644)
+		},
+	}
+	doc.Flags().StringVarP(&format, "format", "f", DOC_FORMAT_MARKDOWN, "output format: markdown or schema")
+	doc.Flags().StringVarP(&output, "output", "o", "", "output file, print to stdout if empty")
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "configuration tools",
+	}
+	cmd.AddCommand(doc)
+	return cmd
+}
@@ -5,7 +5,7 @@
 //
 //  1. [NewAppContext](Main, options...) with [WithMeta], optional
 //     [WithRoot] / [WithPreInit] / [WithPreRun] / [WithServe] /
-//     [WithClose].
+//     [WithClose] / [WithSources] / [WithReload].
 //  2. [AppCtx.Conf] loads config from the environment (and optional
 //     config/local.yml), writes defaults under config/, runs [WithPreInit],
 //     then initializes fields that implement confx types Init hooks.
@@ -36,6 +36,57 @@
 // config/default.yml and config/.env → [WithPreInit] → [types.InitByContext]
 // on fields.
 // Anonymous structs are allowed only when a single configuration is passed.
+// Rules in `env` tag such as `env:",required,min=1"` are validated after
+// decoding and all violations are reported together.
+//
+// [WithSources] replaces local.yml and env with an ordered list of
+// envx.Source (env, dotenv, YAML, JSON, TOML, secret dirs); later sources
+// override earlier ones.
+//
+// `config doc` renders a reference of all registered keys with type, default,
+// optional and masked flags, rules and description from field comments
+// (docx), as markdown or JSON Schema (`--format schema`). See [AppCtx.ConfigDoc].
+//
+// # Initialization order
+//
+// Each configuration and its exported fields are components. By default they
+// are initialized in declaration order; a component declares dependencies by
+// implementing [types.Dependent] or by `init` tag, and is initialized after
+// them. [AppCtx.Close] closes components in reverse order. Cycles and unknown
+// dependencies panic in [AppCtx.Conf].
+//
+//	type Config struct {
+//		Job  confxxl.Endpoint `init:",depends=Log|Otel,timeout=10s"`
+//		Log  conflogx.LoggerConfig
+//		Otel confotel.Otel
+//	}
+//
+// [WithParallelInit] initializes independent components concurrently and
+// [WithInitTimeout] limits time of initializing each component.
+//
+// # Hot reload
+//
+// [WithReload] registers [Watcher]s ([WatchSignal], [WatchFile]) started on
+// `run`. When notified, [AppCtx.Reload] reloads sources, decodes
+// each group into a shadow copy and diffs it with current values. Fields
+// implementing [types.Reloadable] receive new values and the changed keys;
+// other fields keep current values until restarted, and their changes are
+// reported by [ErrRestartRequired].
+//
+//	app := appx.NewAppContext(
+//		Main,
+//		appx.WithReload(appx.WatchSignal(), appx.WatchFile("config/local.yml", 0)),
+//	)
+//
+// # Health
+//
+// [AppCtx.Conf] discovers components implementing liveness.Checker (such as
+// confredis, confrdb, confpulsar endpoints and types.Endpoint). [WithHealth]
+// serves /livez, /readyz and /healthz on a separate listener on `run`, probing
+// components concurrently in timeout with cached results. [AppCtx.HealthHandler]
+// mounts the same handler on an application server.
+//
+//	app := appx.NewAppContext(Main, appx.WithHealth(":8081", 0, 0))
 //
 // # Lifecycle hooks and shutdown
 //
@@ -59,13 +110,27 @@
 //   - [WithClose]: extra close hooks; Conf components close automatically
 //     in [AppCtx.Close] and do not need registration
 //
+// types.Runnable and types.Servable components found in configurations are
+// started on `run` after PreRun, restarted on failure as [WithRestart] set, and
+// the first exhausted failure stops the others. Servables are shut down on
+// [AppCtx.Close], so a confws.Endpoint field is enough to listen.
+//
+// [WithManagedRun] lets the framework own process lifetime instead: `run`
+// traps SIGINT/SIGTERM, cancels [AppCtx.Context], waits Serves and Main in a
+// drain deadline, closes components (logging each duration) and exits with
+// EXIT_CODE_* when shutdown is not graceful. Main may be nil then.
+//
+//	app := appx.NewAppContext(nil, appx.WithManagedRun(30*time.Second))
+//
 // Prefer serve-first: put long-lived work in [WithServe]; use
 // [AppCtx.AddCommand] for oneshot CLI tools. Main still owns when to exit.
 //
 // # CLI
 //
 // [AppCtx.Execute] runs the root cobra command from main.
-// [NewAppContext] registers `run` (via [AppCtx.AddCommand]) and `version`.
+// [NewAppContext] registers `run` (via [AppCtx.AddCommand]), `version`,
+// `config doc` and `migrate plan|up|down|status`. `migrate` runs on
+// types.Migratable components (eg: confrdb endpoints) found by [AppCtx.Conf].
 //
 // # Runtime
 //
@@ -0,0 +1,300 @@
+package appx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatih/color"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/xoctopus/confx/pkg/envx"
+	"github.com/xoctopus/confx/pkg/types/liveness"
+)
+
+const (
+	// DefaultHealthTimeout limits time of probing each component
+	DefaultHealthTimeout = 3 * time.Second
+	// DefaultHealthCacheTTL is the duration probing results are reused
+	DefaultHealthCacheTTL = 5 * time.Second
+)
+
+// WithHealth serves health probes on addr (eg: ":8081") on `run`. each component
+// exposing liveness.Checker is probed concurrently in timeout, and results are
+// cached in ttl to protect components from frequent probes. concurrent requests
+// share one probing, and a component is never probed again before its previous
+// probing returns. zero timeout and ttl use [DefaultHealthTimeout] and
+// [DefaultHealthCacheTTL].
+//
+//	GET /livez   process is alive, components are not probed
+//	GET /readyz  200 if all components are reachable, otherwise 503
+//	GET /healthz as /readyz with meta and result of each component
+func WithHealth(addr string, timeout, ttl time.Duration) Option {
+	return func(app *AppCtx) {
+		if timeout <= 0 {
+			timeout = DefaultHealthTimeout
+		}
+		if ttl <= 0 {
+			ttl = DefaultHealthCacheTTL
+		}
+		app.health.addr = addr
+		app.health.timeout = timeout
+		app.health.ttl = ttl
+	}
+}
+
+// health aggregates liveness.Checker of components discovered in Conf
+type health struct {
+	addr    string
+	timeout time.Duration
+	ttl     time.Duration
+	meta    *Meta
+
+	// ready is true after components initialized and before closing
+	ready    atomic.Bool
+	names    []string
+	checkers map[string]*prober
+	server   *http.Server
+
+	group   singleflight.Group
+	mtx     sync.Mutex
+	at      time.Time
+	results map[string]liveness.Result
+}
+
+// discover finds checkers in v. a found checker is not walked into, and zero
+// values (eg: an Endpoint without Address) are skipped.
+func (h *health) discover(g *envx.Group, v reflect.Value) {
+	if h.checkers == nil {
+		h.checkers = make(map[string]*prober)
+	}
+	walk(g, v, func(name string, rv reflect.Value) bool {
+		c, ok := capability[liveness.Checker](rv)
+		if !ok {
+			return false
+		}
+		if z, ok := c.(interface{ IsZero() bool }); ok && z.IsZero() {
+			return true
+		}
+		if _, exists := h.checkers[name]; !exists {
+			h.names = append(h.names, name)
+		}
+		h.checkers[name] = &prober{checker: c}
+		return true
+	})
+}
+
+// check probes all checkers concurrently, results in ttl are reused. callers
+// missing cache share one probing by singleflight. probing runs outside the
+// lock and is detached from cancellation of ctx, so that a disconnected client
+// cannot fail probes. results failed by cancellation are not cached.
+func (h *health) check(ctx context.Context) map[string]liveness.Result {
+	h.mtx.Lock()
+	if h.results != nil && time.Since(h.at) < h.ttl {
+		defer h.mtx.Unlock()
+		return h.results
+	}
+	h.mtx.Unlock()
+
+	v, _, _ := h.group.Do("", func() (any, error) {
+		return h.probe(context.WithoutCancel(ctx)), nil
+	})
+	return v.(map[string]liveness.Result)
+}
+
+// probe probes all checkers and caches results if none is canceled
+func (h *health) probe(ctx context.Context) map[string]liveness.Result {
+	timeout := h.timeout
+	if timeout <= 0 {
+		timeout = DefaultHealthTimeout
+	}
+
+	var (
+		wg     = &sync.WaitGroup{}
+		probes = make([]liveness.Result, len(h.names))
+	)
+	for i, name := range h.names {
+		p := h.checkers[name]
+		wg.Go(func() {
+			probes[i] = p.probe(ctx, timeout)
+		})
+	}
+	wg.Wait()
+
+	results := make(map[string]liveness.Result, len(h.names))
+	canceled := false
+	for i, name := range h.names {
+		results[name] = probes[i]
+		if r := probes[i]; r != nil && errors.Is(r.FailureReason(), context.Canceled) {
+			canceled = true
+		}
+	}
+
+	if !canceled {
+		h.mtx.Lock()
+		h.results, h.at = results, time.Now()
+		h.mtx.Unlock()
+	}
+	return results
+}
+
+// prober probes a checker. at most one LivenessCheck of checker is running, a
+// checker not respecting ctx is left running after timeout, and later probes
+// wait for it instead of starting another, which prevents goroutines and
+// resources of components (eg: connections of kafka or pulsar) from piling up.
+type prober struct {
+	checker liveness.Checker
+
+	mtx     sync.Mutex
+	running *probing
+}
+
+// probing is a running LivenessCheck, result is set before done closed
+type probing struct {
+	done   chan struct{}
+	result liveness.Result
+}
+
+// probe waits result of running probing, or starts one if none, in timeout
+func (p *prober) probe(ctx context.Context, timeout time.Duration) liveness.Result {
+	p.mtx.Lock()
+	x := p.running
+	if x == nil {
+		x = &probing{done: make(chan struct{})}
+		p.running = x
+		go p.run(ctx, x, timeout)
+	}
+	p.mtx.Unlock()
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+
+	select {
+	case <-x.done:
+		return x.result
+	case <-t.C:
+		v := liveness.NewLivenessData()
+		v.End(fmt.Errorf("probing timeout in %s", timeout))
+		return v
+	}
+}
+
+func (p *prober) run(ctx context.Context, x *probing, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer func() {
+		if r := recover(); r != nil {
+			v := liveness.NewLivenessData()
+			v.End(fmt.Errorf("panic: %v", r))
+			x.result = v
+		}
+		cancel()
+		p.mtx.Lock()
+		p.running = nil
+		p.mtx.Unlock()
+		close(x.done)
+	}()
+	x.result = p.checker.LivenessCheck(ctx)
+}
+
+// busy reports if a LivenessCheck is running
+func (p *prober) busy() bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.running != nil
+}
+
+func healthy(results map[string]liveness.Result) bool {
+	for _, r := range results {
+		if r == nil || r.FailureReason() != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *health) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/livez", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !h.ready.Load() {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "unavailable"})
+			return
+		}
+		results := h.check(r.Context())
+		if !healthy(results) {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "unavailable", "components": results})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		var (
+			code    = http.StatusOK
+			status  = "ok"
+			results = h.check(r.Context())
+		)
+		if !h.ready.Load() || !healthy(results) {
+			code, status = http.StatusServiceUnavailable, "unavailable"
+		}
+		writeJSON(w, code, map[string]any{
+			"status":     status,
+			"ready":      h.ready.Load(),
+			"meta":       h.meta,
+			"components": results,
+		})
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// serve starts health server if addr configured
+func (h *health) serve() error {
+	if h.addr == "" {
+		return nil
+	}
+	l, err := net.Listen("tcp", h.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen health probes: %w", err)
+	}
+	h.server = &http.Server{Handler: h.Handler(), ReadHeaderTimeout: h.timeout}
+	go func() {
+		if err := h.server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Println(color.RedString("health server stopped: %v", err))
+		}
+	}()
+	return nil
+}
+
+func (h *health) shutdown(ctx context.Context) error {
+	h.ready.Store(false)
+	if h.server == nil {
+		return nil
+	}
+	return h.server.Shutdown(ctx)
+}
+
+// HealthHandler returns handler serving /livez, /readyz and /healthz, which can
+// be mounted on an application server instead of the listener of [WithHealth].
+func (app *AppCtx) HealthHandler() http.Handler {
+	return app.health.Handler()
+}
+
+// CheckHealth probes components exposing liveness.Checker and returns results
+// keyed by env key of component, such as APP__CONFIG__Redis.
+func (app *AppCtx) CheckHealth(ctx context.Context) map[string]liveness.Result {
+	return app.health.check(ctx)
+}
@@ -0,0 +1,183 @@
+package appx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/types/liveness"
+)
+
+type healthChecker struct {
+	Address string
+
+	mtx   sync.Mutex
+	err   error
+	delay time.Duration
+	count atomic.Int32
+}
+
+func (c *healthChecker) IsZero() bool { return c.Address == "" }
+
+func (c *healthChecker) set(err error, delay time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.err, c.delay = err, delay
+}
+
+func (c *healthChecker) LivenessCheck(ctx context.Context) liveness.Result {
+	c.count.Add(1)
+	c.mtx.Lock()
+	err, delay := c.err, c.delay
+	c.mtx.Unlock()
+
+	v := liveness.NewLivenessData()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	v.End(err)
+	return v
+}
+
+type HealthConfig struct {
+	Redis  healthChecker
+	Pulsar *healthChecker `env:"mq"`
+	Zero   healthChecker
+	Nested struct {
+		DB healthChecker
+	}
+}
+
+func TestAppCtx_Health(t *testing.T) {
+	cfg := &HealthConfig{
+		Redis:  healthChecker{Address: "redis://localhost"},
+		Pulsar: &healthChecker{Address: "pulsar://localhost"},
+	}
+	cfg.Nested.DB.Address = "postgres://localhost"
+
+	app := NewAppContext(
+		_main,
+		WithMeta(Meta{Name: "TEST"}),
+		WithRoot(t.TempDir()),
+		WithHealth("", 50*time.Millisecond, time.Hour),
+	)
+	app.Conf(context.Background(), cfg)
+
+	Expect(t, app.health.names, Equal([]string{
+		"TEST__HEALTHCONFIG__Redis",
+		"TEST__HEALTHCONFIG__MQ",
+		"TEST__HEALTHCONFIG__Nested_DB",
+	}))
+
+	get := func(path string) (int, map[string]any) {
+		w := httptest.NewRecorder()
+		app.HealthHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		body := map[string]any{}
+		Expect(t, json.Unmarshal(w.Body.Bytes(), &body), Succeed())
+		return w.Code, body
+	}
+
+	t.Run("Healthy", func(t *testing.T) {
+		code, _ := get("/livez")
+		Expect(t, code, Equal(http.StatusOK))
+
+		code, body := get("/readyz")
+		Expect(t, code, Equal(http.StatusOK))
+		Expect(t, body["status"], Equal[any]("ok"))
+
+		code, body = get("/healthz")
+		Expect(t, code, Equal(http.StatusOK))
+		Expect(t, body["components"].(map[string]any), HaveLen[map[string]any](3))
+
+		// results are cached in ttl
+		Expect(t, cfg.Redis.count.Load(), Equal(int32(1)))
+	})
+
+	t.Run("Unhealthy", func(t *testing.T) {
+		cfg.Redis.set(errors.New("redis: lost connection"), 0)
+		cfg.Pulsar.set(nil, time.Second)
+		app.health.results = nil
+
+		results := app.CheckHealth(context.Background())
+		Expect(t, results["TEST__HEALTHCONFIG__Redis"].FailureReason(), ErrorContains("lost connection"))
+		Expect(t, results["TEST__HEALTHCONFIG__MQ"].FailureReason(), ErrorContains("probing timeout"))
+		Expect(t, results["TEST__HEALTHCONFIG__Nested_DB"].FailureReason(), Succeed())
+
+		code, body := get("/readyz")
+		Expect(t, code, Equal(http.StatusServiceUnavailable))
+		components := body["components"].(map[string]any)
+		redis := components["TEST__HEALTHCONFIG__Redis"].(map[string]any)
+		Expect(t, redis["reachable"], Equal[any](false))
+		Expect(t, redis["msg"], Equal[any]("redis: lost connection"))
+
+		// timed out probing is not started again before it returns
+		mq := app.health.checkers["TEST__HEALTHCONFIG__MQ"]
+		count := cfg.Pulsar.count.Load()
+		app.health.results = nil
+		results = app.CheckHealth(context.Background())
+		Expect(t, results["TEST__HEALTHCONFIG__MQ"].FailureReason(), ErrorContains("probing timeout"))
+		Expect(t, cfg.Pulsar.count.Load(), Equal(count))
+		Expect(t, mq.busy(), BeTrue())
+		for mq.busy() {
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("Coalesced", func(t *testing.T) {
+		cfg.Redis.set(nil, 20*time.Millisecond)
+		cfg.Pulsar.set(nil, 0)
+		app.health.results = nil
+		count := cfg.Redis.count.Load()
+
+		wg := sync.WaitGroup{}
+		for range 8 {
+			wg.Go(func() {
+				Expect(t, healthy(app.CheckHealth(context.Background())), BeTrue())
+			})
+		}
+		wg.Wait()
+		Expect(t, cfg.Redis.count.Load(), Equal(count+1))
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		cfg.Redis.set(nil, 0)
+		cfg.Pulsar.set(nil, 0)
+		app.health.results = nil
+
+		// probes are detached from cancellation of request
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		results := app.CheckHealth(ctx)
+		Expect(t, healthy(results), BeTrue())
+		Expect(t, app.health.results, NotBeNil[map[string]liveness.Result]())
+
+		// results failed by cancellation are not cached
+		cfg.Redis.set(context.Canceled, 0)
+		app.health.results = nil
+		results = app.CheckHealth(context.Background())
+		Expect(t, healthy(results), BeFalse())
+		Expect(t, app.health.results, BeNil[map[string]liveness.Result]())
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		cfg.Redis.set(nil, 0)
+		app.health.results = nil
+		Expect(t, app.Close(context.Background()), Succeed())
+
+		code, _ := get("/livez")
+		Expect(t, code, Equal(http.StatusOK))
+		code, _ = get("/readyz")
+		Expect(t, code, Equal(http.StatusServiceUnavailable))
+	})
+}
@@ -0,0 +1,170 @@
+package appx
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// exit codes of managed run
+const (
+	// EXIT_CODE_OK denotes app is shut down gracefully
+	EXIT_CODE_OK = 0
+	// EXIT_CODE_FAILURE denotes main entry or a Runnable/Servable component
+	// returned an error
+	EXIT_CODE_FAILURE = 1
+	// EXIT_CODE_DRAIN_TIMEOUT denotes serves or main entry did not return in
+	// drain deadline
+	EXIT_CODE_DRAIN_TIMEOUT = 2
+	// EXIT_CODE_CLOSE_FAILED denotes closing components failed
+	EXIT_CODE_CLOSE_FAILED = 3
+)
+
+// DefaultDrainTimeout is the default drain deadline of managed run
+const DefaultDrainTimeout = 30 * time.Second
+
+// WithManagedRun makes `run` manage process lifetime. signals (SIGINT and
+// SIGTERM by default) or return of main cancel [AppCtx.Context], then serves
+// and main are waited and [AppCtx.Close] is called in drain deadline (zero
+// uses [DefaultDrainTimeout]). closing duration of each component is logged and
+// process exits with EXIT_CODE_* if not shut down gracefully. a second signal
+// while draining exits immediately with 128+signal.
+//
+// In managed run, main may be nil, Serves and main should return when
+// [AppCtx.Context] is done and main should not call Close.
+func WithManagedRun(drain time.Duration, signals ...os.Signal) Option {
+	return func(app *AppCtx) {
+		if drain <= 0 {
+			drain = DefaultDrainTimeout
+		}
+		if len(signals) == 0 {
+			signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+		}
+		app.option.Managed = true
+		app.option.DrainTimeout = drain
+		app.option.Signals = signals
+	}
+}
+
+// Context returns the context returned by [AppCtx.Conf]. In managed run, it
+// is canceled when shutting down.
+func (app *AppCtx) Context() context.Context {
+	if app.ctx == nil {
+		return context.Background()
+	}
+	return app.ctx
+}
+
+// runManaged runs PreRun, Serves and main, and shuts down on signals or main
+// returned. it returns exit code.
+func (app *AppCtx) runManaged(main func() error) int {
+	ctx, cancel := context.WithCancel(app.Context())
+	defer cancel()
+	app.ctx = ctx
+
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, app.option.Signals...)
+	defer signal.Stop(sigs)
+
+	app.watch()
+	if err := app.health.serve(); err != nil {
+		fmt.Println(color.HiRedString("%v", err))
+		return EXIT_CODE_FAILURE
+	}
+	app.option.PreRun()
+	app.runners.start(ctx)
+
+	tasks := &sync.WaitGroup{}
+	for _, serve := range app.option.Serves {
+		if serve != nil {
+			tasks.Go(serve)
+		}
+	}
+
+	code := EXIT_CODE_OK
+	done := make(chan error, 1)
+	go func() {
+		if main == nil {
+			<-ctx.Done()
+			done <- nil
+			return
+		}
+		done <- main()
+	}()
+
+	exited := false
+	select {
+	case s := <-sigs:
+		fmt.Println(color.HiYellowString("received signal %s, shutting down", s))
+	case err := <-done:
+		exited = true
+		if err != nil {
+			code = EXIT_CODE_FAILURE
+			fmt.Println(color.HiRedString("main exited: %v, shutting down", err))
+		}
+	case <-app.runners.done():
+		code = EXIT_CODE_FAILURE
+		fmt.Println(color.HiRedString("%v, shutting down", app.runners.err))
+	}
+	cancel()
+
+	drain, stop := context.WithTimeout(context.Background(), app.option.DrainTimeout)
+	defer stop()
+
+	// a second signal forces exit
+	go func() {
+		select {
+		case s := <-sigs:
+			fmt.Println(color.HiRedString("received signal %s again, exit", s))
+			if x, ok := s.(syscall.Signal); ok {
+				app.exit(128 + int(x))
+				return
+			}
+			app.exit(EXIT_CODE_FAILURE)
+		case <-drain.Done():
+		}
+	}()
+
+	var (
+		drained = make(chan struct{})
+		failed  error
+	)
+	go func() {
+		tasks.Wait()
+		if !exited {
+			failed = <-done
+		}
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		if failed != nil && code == EXIT_CODE_OK {
+			code = EXIT_CODE_FAILURE
+			fmt.Println(color.HiRedString("main exited: %v", failed))
+		}
+	case <-drain.Done():
+		code = EXIT_CODE_DRAIN_TIMEOUT
+		fmt.Println(color.HiRedString("serves are not drained in %s", app.option.DrainTimeout))
+	}
+
+	start := time.Now()
+	err := app.close(drain, func(name string, d time.Duration, err error) {
+		if err != nil {
+			fmt.Println(color.HiRedString("closed %s in %s: %v", name, d, err))
+			return
+		}
+		fmt.Println(color.HiBlueString("closed %s in %s", name, d))
+	})
+	if err != nil && code == EXIT_CODE_OK {
+		code = EXIT_CODE_CLOSE_FAILED
+	}
+	fmt.Println(color.HiBlueString("shut down in %s with code %d", time.Since(start), code))
+	return code
+}
@@ -0,0 +1,90 @@
+package appx
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/xoctopus/x/testx"
+)
+
+type managedComponent struct {
+	closed atomic.Bool
+	err    error
+}
+
+func (c *managedComponent) Close(ctx context.Context) error {
+	c.closed.Store(true)
+	return c.err
+}
+
+type ManagedConfig struct {
+	Component managedComponent
+}
+
+func newManagedApp(t *testing.T, main func() error, options ...Option) (*AppCtx, *ManagedConfig) {
+	t.Helper()
+	app := NewAppContext(main, append([]Option{
+		WithMeta(Meta{Name: "TEST"}),
+		WithRoot(t.TempDir()),
+	}, options...)...)
+	cfg := &ManagedConfig{}
+	app.Conf(context.Background(), cfg)
+	return app, cfg
+}
+
+func TestAppCtx_ManagedRun(t *testing.T) {
+	t.Run("MainReturned", func(t *testing.T) {
+		served := atomic.Bool{}
+		app, cfg := newManagedApp(t, func() error { return nil }, WithManagedRun(time.Second))
+		app.option.Serves = []func(){func() {
+			<-app.Context().Done()
+			served.Store(true)
+		}}
+
+		Expect(t, app.runManaged(func() error { return nil }), Equal(EXIT_CODE_OK))
+		Expect(t, served.Load(), BeTrue())
+		Expect(t, cfg.Component.closed.Load(), BeTrue())
+		Expect(t, app.Context().Err(), Equal(context.Canceled))
+	})
+
+	t.Run("MainFailed", func(t *testing.T) {
+		code := -1
+		app, cfg := newManagedApp(t, func() error { return errors.New("any") }, WithManagedRun(time.Second))
+		app.exit = func(c int) { code = c }
+
+		app.cmd.SetArgs([]string{"run"})
+		Expect(t, app.Execute(), Succeed())
+		Expect(t, code, Equal(EXIT_CODE_FAILURE))
+		Expect(t, cfg.Component.closed.Load(), BeTrue())
+	})
+
+	t.Run("Signal", func(t *testing.T) {
+		app, cfg := newManagedApp(t, nil, WithManagedRun(time.Second, syscall.SIGUSR1))
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_ = syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+		}()
+		Expect(t, app.runManaged(nil), Equal(EXIT_CODE_OK))
+		Expect(t, cfg.Component.closed.Load(), BeTrue())
+	})
+
+	t.Run("DrainTimeout", func(t *testing.T) {
+		app, cfg := newManagedApp(t, nil, WithManagedRun(50*time.Millisecond))
+		block := make(chan struct{})
+		defer close(block)
+		app.option.Serves = []func(){func() { <-block }}
+
+		Expect(t, app.runManaged(func() error { return nil }), Equal(EXIT_CODE_DRAIN_TIMEOUT))
+		Expect(t, cfg.Component.closed.Load(), BeTrue())
+	})
+
+	t.Run("CloseFailed", func(t *testing.T) {
+		app, cfg := newManagedApp(t, nil, WithManagedRun(time.Second))
+		cfg.Component.err = errors.New("any")
+		Expect(t, app.runManaged(func() error { return nil }), Equal(EXIT_CODE_CLOSE_FAILED))
+	})
+}
@@ -0,0 +1,85 @@
+package appx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/spf13/cobra"
+
+	"github.com/xoctopus/confx/pkg/types"
+)
+
+// migratable is a types.Migratable component found in configurations
+type migratable struct {
+	name string
+	types.Migratable
+}
+
+// migratables finds types.Migratable components in configurations, a found one
+// is not walked into.
+func (app *AppCtx) migratables() []migratable {
+	list := make([]migratable, 0)
+	for i := range app.components {
+		walk(app.vars[i], app.components[i], func(name string, rv reflect.Value) bool {
+			if m, ok := capability[types.Migratable](rv); ok {
+				list = append(list, migratable{name: name, Migratable: m})
+				return true
+			}
+			return false
+		})
+	}
+	return list
+}
+
+// Migrate runs f on each types.Migratable component in configurations in order
+// of registration. it stops at the first failure.
+func (app *AppCtx) Migrate(ctx context.Context, f func(types.Migratable) error) error {
+	list := app.migratables()
+	if len(list) == 0 {
+		return errors.New("no migratable component found in configurations")
+	}
+	for _, m := range list {
+		if err := f(m.Migratable); err != nil {
+			return fmt.Errorf("%s: %w", m.name, err)
+		}
+	}
+	return nil
+}
+
+// migrateCommand builds `migrate` command with `plan`, `up`, `down` and
+// `status` subcommands on types.Migratable components
+func (app *AppCtx) migrateCommand() *cobra.Command {
+	sub := func(use, short string, run func(m types.Migratable, ctx context.Context, w io.Writer) error) *cobra.Command {
+		return &cobra.Command{
+			Use:   use,
+			Short: short,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				ctx := app.Context()
+				return app.Migrate(ctx, func(m types.Migratable) error {
+					return run(m, ctx, cmd.OutOrStdout())
+				})
+			},
+		}
+	}
+
+	steps := 1
+	down := sub("down", "roll back the latest applied migrations", func(m types.Migratable, ctx context.Context, w io.Writer) error {
+		return m.MigrateDown(ctx, w, steps)
+	})
+	down.Flags().IntVarP(&steps, "steps", "n", 1, "number of migrations to roll back")
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "versioned migration tools",
+	}
+	cmd.AddCommand(
+		sub("plan", "display pending migrations", types.Migratable.MigratePlan),
+		sub("up", "apply pending migrations", types.Migratable.MigrateUp),
+		sub("status", "display applied and pending migrations", types.Migratable.MigrateStatus),
+		down,
+	)
+	return cmd
+}
@@ -0,0 +1,90 @@
+package appx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/envx"
+)
+
+type MigratableDB struct {
+	Address string
+
+	applied int
+}
+
+func (d *MigratableDB) MigratePlan(_ context.Context, w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s pending %d\n", d.Address, 2-d.applied)
+	return err
+}
+
+func (d *MigratableDB) MigrateUp(_ context.Context, w io.Writer) error {
+	d.applied = 2
+	_, err := fmt.Fprintf(w, "%s applied\n", d.Address)
+	return err
+}
+
+func (d *MigratableDB) MigrateDown(_ context.Context, _ io.Writer, steps int) error {
+	if steps > d.applied {
+		return errors.New("irreversible")
+	}
+	d.applied -= steps
+	return nil
+}
+
+func (d *MigratableDB) MigrateStatus(_ context.Context, w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s applied %d\n", d.Address, d.applied)
+	return err
+}
+
+type MigrateConfig struct {
+	Main    MigratableDB
+	Archive *MigratableDB
+}
+
+func TestAppCtx_Migrate(t *testing.T) {
+	app := NewAppContext(
+		_main,
+		WithMeta(Meta{Name: "TEST"}),
+		WithRoot(t.TempDir()),
+		WithSources(envx.FromMap(map[string]string{
+			"TEST__MIGRATECONFIG__Main_Address": "main",
+		})),
+	)
+	c := &MigrateConfig{Archive: &MigratableDB{Address: "archive"}}
+	app.Conf(context.Background(), c)
+
+	buf := bytes.NewBuffer(nil)
+	app.cmd.SetOut(buf)
+	exec := func(args ...string) error {
+		buf.Reset()
+		app.cmd.SetArgs(append([]string{"migrate"}, args...))
+		return app.cmd.Execute()
+	}
+
+	Expect(t, exec("plan"), Succeed())
+	Expect(t, buf.String(), Equal("main pending 2\narchive pending 2\n"))
+
+	Expect(t, exec("up"), Succeed())
+	Expect(t, c.Main.applied, Equal(2))
+	Expect(t, c.Archive.applied, Equal(2))
+
+	Expect(t, exec("down", "--steps", "2"), Succeed())
+	Expect(t, exec("status"), Succeed())
+	Expect(t, buf.String(), Equal("main applied 0\narchive applied 0\n"))
+
+	err := exec("down")
+	Expect(t, err, ErrorContains("TEST__MIGRATECONFIG__Main: irreversible"))
+
+	t.Run("NoMigratable", func(t *testing.T) {
+		app := NewAppContext(_main, WithMeta(Meta{Name: "TEST"}), WithRoot(t.TempDir()))
+		app.Conf(context.Background(), &Config1{})
+		Expect(t, app.Migrate(context.Background(), nil), Failed())
+	})
+}
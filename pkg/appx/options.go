@@ -2,6 +2,7 @@ package appx
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -111,6 +112,20 @@ type AppOption struct {
 	// [WithClose]. Components from Conf are closed automatically and need not
 	// be listed here.
 	CloseFns []func() error
+	// ParallelInit initializes independent components concurrently, set by
+	// [WithParallelInit].
+	ParallelInit bool
+	// InitTimeout limits time of initializing each component, set by
+	// [WithInitTimeout].
+	InitTimeout time.Duration
+	// Managed makes `run` trap Signals, drain Serves and main in DrainTimeout
+	// and close app. set by [WithManagedRun].
+	Managed      bool
+	DrainTimeout time.Duration
+	Signals      []os.Signal
+	// Watchers observe configuration changes and trigger [AppCtx.Reload] on
+	// `run`. Registered via [WithReload].
+	Watchers []Watcher
 }
 
 // PreInit runs [WithPreInit] callbacks sequentially before component Init.
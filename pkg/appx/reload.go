@@ -0,0 +1,414 @@
+package appx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/xoctopus/x/reflectx"
+
+	"github.com/xoctopus/confx/pkg/envx"
+	"github.com/xoctopus/confx/pkg/types"
+)
+
+// Watcher observes a source of configuration changes and calls notify when the
+// configuration should be reloaded. It blocks until ctx is done.
+type Watcher func(ctx context.Context, app *AppCtx, notify func())
+
+// WithReload enables hot reload on `run`: each watcher runs in its own
+// goroutine and triggers [AppCtx.Reload] when notified. Watchers are stopped
+// by [AppCtx.Close].
+func WithReload(watchers ...Watcher) Option {
+	return func(app *AppCtx) {
+		app.option.Watchers = append(app.option.Watchers, watchers...)
+	}
+}
+
+// WatchSignal notifies when the process receives one of sigs. SIGHUP is
+// watched when sigs is empty.
+func WatchSignal(sigs ...os.Signal) Watcher {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	return func(ctx context.Context, _ *AppCtx, notify func()) {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, sigs...)
+		defer signal.Stop(ch)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				notify()
+			}
+		}
+	}
+}
+
+// WatchFile polls filename every interval and notifies when its modification
+// time or size changed. A relative filename is resolved against
+// [AppCtx.Root], eg: WatchFile("config/local.yml", 0). interval defaults to 5s.
+func WatchFile(filename string, interval time.Duration) Watcher {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return func(ctx context.Context, app *AppCtx, notify func()) {
+		name := filename
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(app.Root(), name)
+		}
+
+		stat := func() (time.Time, int64) {
+			if fi, err := os.Stat(name); err == nil {
+				return fi.ModTime(), fi.Size()
+			}
+			return time.Time{}, -1
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		modified, size := stat()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m, s := stat()
+				if !m.Equal(modified) || s != size {
+					modified, size = m, s
+					notify()
+				}
+			}
+		}
+	}
+}
+
+// ErrRestartRequired reports changed values which cannot be applied until the
+// application restarted
+var ErrRestartRequired = errors.New("restart required")
+
+// Reload reloads configuration sources (see [WithSources]), decodes every group
+// loaded by [AppCtx.Conf] into a shadow copy and diffs it with current values.
+//
+// Fields implementing [types.Reloadable] which have changed keys are assigned
+// the changed values and then notified with the changed keys relative to the
+// field. The assignment is done while the field locked if it implements
+// [sync.Locker]. Changes to other fields are not applied, those fields keep
+// current values and the changes are reported by [ErrRestartRequired]. Values
+// of a group are committed only after applied, so that changes failed to be
+// applied or restart required are reloaded again next time. Errors are joined
+// and returned.
+func (app *AppCtx) Reload(ctx context.Context) error {
+	app.mtx.Lock()
+	defer app.mtx.Unlock()
+
+	if err := app.load(); err != nil {
+		return err
+	}
+
+	errs := make([]error, 0, len(app.components))
+	for i := range app.components {
+		if err := app.reload(ctx, i); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (app *AppCtx) reload(ctx context.Context, i int) error {
+	var (
+		name   = app.vars[i].Name()
+		live   = app.components[i]
+		shadow = reflect.New(reflectx.Indirect(live).Type())
+		next   = envx.NewGroup(name)
+	)
+
+	if err := envx.NewDecoder(app.dfts[i]).Decode(shadow); err != nil {
+		return fmt.Errorf("failed to decode default [group:%s]: %w", name, err)
+	}
+	if err := envx.NewDecoder(envx.ParseGroup(name, app.values)).Decode(shadow); err != nil {
+		return fmt.Errorf("failed to decode env [group:%s]: %w", name, err)
+	}
+	if err := envx.NewValidator(next).Validate(shadow); err != nil {
+		return err
+	}
+	if err := envx.NewEncoder(next).Encode(shadow); err != nil {
+		return fmt.Errorf("failed to encode env [group:%s]: %w", name, err)
+	}
+
+	changed := diff(app.vars[i], next)
+	if len(changed) == 0 {
+		return nil
+	}
+
+	r := &reloader{group: name, reached: make(map[string]bool)}
+	err := r.walk(ctx, envx.NewPathWalker(), live, shadow, changed)
+
+	// vars are encoded before Init as Conf does, to keep diff stable
+	app.vars[i] = commit(app.vars[i], next, r.reached)
+
+	pending := make([]string, 0)
+	for _, k := range changed {
+		if _, ok := r.reached[k]; !ok {
+			pending = append(pending, k)
+		}
+	}
+	if len(pending) > 0 {
+		err = errors.Join(err, fmt.Errorf("[group:%s] [keys:%s]: %w", name, strings.Join(pending, ","), ErrRestartRequired))
+	}
+	return err
+}
+
+// watch starts watchers registered by [WithReload]
+func (app *AppCtx) watch() {
+	if len(app.option.Watchers) == 0 {
+		return
+	}
+
+	ctx := app.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, app.cancel = context.WithCancel(ctx)
+
+	notify := func() {
+		if err := app.Reload(ctx); err != nil {
+			fmt.Println(color.HiRedString("failed to reload config: %v", err))
+		}
+	}
+	for _, w := range app.option.Watchers {
+		if w != nil {
+			go w(ctx, app, notify)
+		}
+	}
+}
+
+// diff returns sorted keys whose values are different between prev and next
+func diff(prev, next *envx.Group) []string {
+	keys := make([]string, 0)
+	for k, v := range next.Values() {
+		if x := prev.Get(k); x == nil || x.Value() != v.Value() {
+			keys = append(keys, k)
+		}
+	}
+	for k := range prev.Values() {
+		if next.Get(k) == nil {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// commit returns vars of next with changes applied, changes not applied keep
+// previous vars. applied maps changed keys to whether they were applied.
+func commit(prev, next *envx.Group, applied map[string]bool) *envx.Group {
+	g := envx.NewGroup(next.Name())
+	for k, v := range next.Values() {
+		if x := prev.Get(k); (x == nil || x.Value() != v.Value()) && !applied[k] {
+			if x != nil {
+				g.Add(x)
+			}
+			continue
+		}
+		g.Add(v)
+	}
+	for k, v := range prev.Values() {
+		if next.Get(k) == nil && !applied[k] {
+			g.Add(v)
+		}
+	}
+	return g
+}
+
+type reloader struct {
+	group string
+	// reached maps changed keys reached by reloadable fields to whether they
+	// were applied
+	reached map[string]bool
+}
+
+// match returns keys under field name, which are relative to the field
+func match(keys []string, name string) []string {
+	matched := make([]string, 0)
+	for _, k := range keys {
+		switch {
+		case k == name:
+			matched = append(matched, "")
+		case strings.HasPrefix(k, name+"_"):
+			matched = append(matched, strings.TrimPrefix(k, name+"_"))
+		}
+	}
+	return matched
+}
+
+// owned returns keys belonging to fields of flattened struct type rt
+func owned(keys []string, rt reflect.Type) []string {
+	rt = reflectx.Deref(rt)
+	matched := make([]string, 0)
+	_ = envx.WalkFields(envx.NewPathWalker(), rt, func(i int, name string, _ *reflectx.Flag) error {
+		if name == "" {
+			matched = append(matched, owned(keys, rt.Field(i).Type)...)
+			return nil
+		}
+		for _, k := range keys {
+			if k == name || strings.HasPrefix(k, name+"_") {
+				matched = append(matched, k)
+			}
+		}
+		return nil
+	})
+	return matched
+}
+
+// fields walks fields of struct type rt with keys of each field, the keys are
+// relative to the field. path of pw is entered for named fields.
+func fields(pw *envx.PathWalker, rt reflect.Type, keys []string, fn func(i int, keys []string, flatten bool) error) error {
+	errs := make([]error, 0)
+	_ = envx.WalkFields(pw, rt, func(i int, name string, _ *reflectx.Flag) error {
+		var sub []string
+		if name == "" {
+			sub = owned(keys, rt.Field(i).Type)
+		} else {
+			sub = match(keys, name)
+		}
+		if len(sub) > 0 {
+			if err := fn(i, sub, name == ""); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return nil
+	})
+	return errors.Join(errs...)
+}
+
+// join returns key in path
+func join(path, key string) string {
+	switch {
+	case path == "":
+		return key
+	case key == "":
+		return path
+	default:
+		return path + "_" + key
+	}
+}
+
+func (r *reloader) walk(ctx context.Context, pw *envx.PathWalker, live, shadow reflect.Value, keys []string) error {
+	path := pw.String()
+
+	if types.IsReloadable(live) {
+		err := r.apply(ctx, pw, live, shadow, keys)
+		if err != nil {
+			err = fmt.Errorf("failed to assign [group:%s] [field:%s]: %w", r.group, path, err)
+		} else if err = types.Reload(ctx, live, keys); err != nil {
+			err = fmt.Errorf("failed to reload [group:%s] [field:%s]: %w", r.group, path, err)
+		}
+		for _, k := range keys {
+			r.reached[join(path, k)] = err == nil
+		}
+		return err
+	}
+
+	for live.Kind() == reflect.Pointer {
+		if live.IsNil() || shadow.IsNil() {
+			return nil
+		}
+		live, shadow = live.Elem(), shadow.Elem()
+	}
+	if live.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return fields(pw, live.Type(), keys, func(i int, keys []string, _ bool) error {
+		return r.walk(ctx, pw, live.Field(i), shadow.Field(i), keys)
+	})
+}
+
+// apply assigns changed values from shadow to live component. the assignment
+// is done while component locked if it implements sync.Locker, which guards
+// the fields read by its own goroutines.
+func (r *reloader) apply(ctx context.Context, pw *envx.PathWalker, live, shadow reflect.Value, keys []string) error {
+	if l := locker(live); l != nil {
+		l.Lock()
+		defer l.Unlock()
+	}
+	return r.assign(ctx, pw, live, shadow, keys, false)
+}
+
+// locker returns sync.Locker implemented by v or its address
+func locker(v reflect.Value) sync.Locker {
+	for {
+		if v.CanInterface() {
+			if l, ok := v.Interface().(sync.Locker); ok {
+				return l
+			}
+		}
+		if v.Kind() != reflect.Pointer || v.IsNil() {
+			break
+		}
+		v = v.Elem()
+	}
+	if v.CanAddr() && v.Addr().CanInterface() {
+		if l, ok := v.Addr().Interface().(sync.Locker); ok {
+			return l
+		}
+	}
+	return nil
+}
+
+// assign copies values of changed keys from src to dst. struct fields are
+// copied one by one to keep unexported runtime states of dst. a named field can
+// be initialized, such as types.Userinfo, is resolved by Init, so it is
+// replaced as a whole and initialized again. the component itself and the
+// flattened fields have no path of their own, they are never replaced.
+func (r *reloader) assign(ctx context.Context, pw *envx.PathWalker, dst, src reflect.Value, keys []string, named bool) error {
+	if named && dst.CanSet() && types.CanBeInitialized(reflect.New(dst.Type())) {
+		dst.Set(src)
+		if err := types.InitByContext(ctx, dst); err != nil && !errors.Is(err, types.ErrSkipInitializing) {
+			return err
+		}
+		return nil
+	}
+
+	for dst.Kind() == reflect.Pointer {
+		if dst.IsNil() || src.IsNil() {
+			if dst.CanSet() {
+				dst.Set(src)
+			}
+			return nil
+		}
+		dst, src = dst.Elem(), src.Elem()
+	}
+
+	switch dst.Kind() {
+	case reflect.Func, reflect.Chan, reflect.Interface:
+		return nil
+	}
+
+	rt := dst.Type()
+	if dst.Kind() != reflect.Struct ||
+		rt.Implements(envx.TextMarshallerT) ||
+		reflect.PointerTo(rt).Implements(envx.TextMarshallerT) {
+		if dst.CanSet() {
+			dst.Set(src)
+		}
+		return nil
+	}
+
+	return fields(pw, rt, keys, func(i int, keys []string, flatten bool) error {
+		return r.assign(ctx, pw, dst.Field(i), src.Field(i), keys, !flatten)
+	})
+}
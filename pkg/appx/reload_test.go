@@ -0,0 +1,248 @@
+package appx
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xoctopus/x/misc/must"
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/types"
+)
+
+type ReloadableLog struct {
+	Level  string
+	Format string
+
+	state   string
+	changed []string
+	count   int
+}
+
+func (l *ReloadableLog) SetDefault() {
+	if l.Level == "" {
+		l.Level = "debug"
+	}
+	if l.Format == "" {
+		l.Format = "json"
+	}
+}
+
+func (l *ReloadableLog) Init() { l.state = "inited" }
+
+func (l *ReloadableLog) Reload(_ context.Context, changed []string) error {
+	l.changed = changed
+	l.count++
+	if l.Level == "invalid" {
+		return errors.New("invalid level")
+	}
+	return nil
+}
+
+type ReloadableAuth struct {
+	Auth  types.Userinfo
+	Limit int
+	Mode  string
+
+	sync.Mutex
+	locked int
+}
+
+func (a *ReloadableAuth) Init() { a.Mode = strings.ToUpper(a.Mode) }
+
+func (a *ReloadableAuth) Lock() {
+	a.Mutex.Lock()
+	a.locked++
+}
+
+func (a *ReloadableAuth) Reload(context.Context, []string) error { return nil }
+
+type ReloadConfig struct {
+	Log      ReloadableLog
+	Pool     *ReloadableLog `env:"pool"`
+	Static   string
+	Disabled string `env:"-"`
+	Auth     ReloadableAuth
+}
+
+func TestAppCtx_Reload(t *testing.T) {
+	root := t.TempDir()
+	must.NoError(os.MkdirAll(filepath.Join(root, "config"), os.ModePerm))
+	local := filepath.Join(root, "config/local.yml")
+	must.NoError(os.WriteFile(local, []byte(`TEST__RELOADCONFIG__Static: "local"`), os.ModePerm))
+
+	t.Setenv("TEST__RELOADCONFIG__Log_Level", "info")
+
+	app := NewAppContext(_main, WithMeta(Meta{Name: "TEST"}), WithRoot(root))
+	c := &ReloadConfig{Pool: &ReloadableLog{}}
+	app.Conf(context.Background(), c)
+	t.Cleanup(func() { _ = os.Unsetenv("TEST__RELOADCONFIG__Static") })
+
+	Expect(t, c.Log.Level, Equal("info"))
+	Expect(t, c.Log.state, Equal("inited"))
+	Expect(t, c.Static, Equal("local"))
+
+	t.Run("NothingChanged", func(t *testing.T) {
+		Expect(t, app.Reload(context.Background()), Succeed())
+		Expect(t, c.Log.count, Equal(0))
+		Expect(t, c.Pool.count, Equal(0))
+	})
+
+	t.Run("ReloadableChanged", func(t *testing.T) {
+		t.Setenv("TEST__RELOADCONFIG__Log_Level", "warn")
+		t.Setenv("TEST__RELOADCONFIG__POOL_Format", "text")
+
+		Expect(t, app.Reload(context.Background()), Succeed())
+		Expect(t, c.Log.Level, Equal("warn"))
+		Expect(t, c.Log.changed, Equal([]string{"Level"}))
+		Expect(t, c.Log.state, Equal("inited"))
+		Expect(t, c.Pool.Format, Equal("text"))
+		Expect(t, c.Pool.changed, Equal([]string{"Format"}))
+
+		Expect(t, app.Reload(context.Background()), Succeed())
+		Expect(t, c.Log.count, Equal(1))
+		Expect(t, c.Pool.count, Equal(1))
+	})
+
+	t.Run("NonReloadableChanged", func(t *testing.T) {
+		must.NoError(os.WriteFile(local, []byte(`TEST__RELOADCONFIG__Static: "changed"`), os.ModePerm))
+		err := app.Reload(context.Background())
+		Expect(t, errors.Is(err, ErrRestartRequired), BeTrue())
+		Expect(t, err, ErrorContains("[keys:Static]"))
+		Expect(t, os.Getenv("TEST__RELOADCONFIG__Static"), Equal("changed"))
+		Expect(t, c.Static, Equal("local"))
+
+		// not committed and reported until restarted
+		Expect(t, errors.Is(app.Reload(context.Background()), ErrRestartRequired), BeTrue())
+		Expect(t, app.vars[0].Get("Static").Value(), Equal("local"))
+	})
+
+	t.Run("ReloadFailed", func(t *testing.T) {
+		t.Setenv("TEST__RELOADCONFIG__Log_Level", "invalid")
+		count, level := c.Log.count, app.vars[0].Get("Log_Level").Value()
+		Expect(t, app.Reload(context.Background()), Failed())
+		Expect(t, app.vars[0].Get("Log_Level").Value(), Equal(level))
+		// reloaded again
+		Expect(t, app.Reload(context.Background()), Failed())
+		Expect(t, c.Log.count, Equal(count+2))
+	})
+
+	t.Run("WatchFile", func(t *testing.T) {
+		app.option.Watchers = []Watcher{WatchFile("config/local.yml", 10*time.Millisecond)}
+		app.watch()
+		defer app.cancel()
+
+		count := c.Pool.count
+		time.Sleep(50 * time.Millisecond)
+		must.NoError(os.WriteFile(local, []byte(`TEST__RELOADCONFIG__POOL_Level: "error"`), os.ModePerm))
+		for range 100 {
+			app.mtx.Lock()
+			reloaded := c.Pool.count > count
+			app.mtx.Unlock()
+			if reloaded {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		Expect(t, c.Pool.Level, Equal("error"))
+		Expect(t, os.Getenv("TEST__RELOADCONFIG__Static"), Equal(""))
+	})
+}
+
+func TestAppCtx_Reload_Assign(t *testing.T) {
+	t.Setenv("TEST_RELOAD_SECRET", "secret1")
+	t.Setenv("TEST__RELOADCONFIG__Auth_Auth_Password", "env://TEST_RELOAD_SECRET")
+	t.Setenv("TEST__RELOADCONFIG__Auth_Mode", "lower")
+
+	app := NewAppContext(_main, WithMeta(Meta{Name: "TEST"}), WithRoot(t.TempDir()))
+	c := &ReloadConfig{Pool: &ReloadableLog{}}
+	app.Conf(context.Background(), c)
+	Expect(t, c.Auth.Auth.Password, Equal(types.Password("secret1")))
+	Expect(t, c.Auth.Mode, Equal("LOWER"))
+
+	t.Run("ChangedOnly", func(t *testing.T) {
+		t.Setenv("TEST__RELOADCONFIG__Auth_Limit", "10")
+		Expect(t, app.Reload(context.Background()), Succeed())
+		Expect(t, c.Auth.Limit, Equal(10))
+		Expect(t, c.Auth.locked, Equal(1))
+		// resolved and initialized values are kept
+		Expect(t, c.Auth.Auth.Password, Equal(types.Password("secret1")))
+		Expect(t, c.Auth.Mode, Equal("LOWER"))
+	})
+
+	t.Run("ResolvedAgain", func(t *testing.T) {
+		t.Setenv("TEST_RELOAD_SECRET2", "secret2")
+		t.Setenv("TEST__RELOADCONFIG__Auth_Auth_Password", "env://TEST_RELOAD_SECRET2")
+		Expect(t, app.Reload(context.Background()), Succeed())
+		Expect(t, c.Auth.Auth.Password, Equal(types.Password("secret2")))
+		Expect(t, c.Auth.locked, Equal(2))
+	})
+}
+
+type Counter struct {
+	Name string
+
+	state string
+}
+
+func (c *Counter) Init() { c.state = "inited" }
+
+type FlattenReloadable struct {
+	Counter
+	Pool int
+
+	changed []string
+}
+
+func (f *FlattenReloadable) Reload(_ context.Context, changed []string) error {
+	f.changed = changed
+	return nil
+}
+
+type FlattenConfig struct {
+	Flat FlattenReloadable
+}
+
+func TestAppCtx_Reload_Flatten(t *testing.T) {
+	app := NewAppContext(_main, WithMeta(Meta{Name: "TEST"}), WithRoot(t.TempDir()))
+	c := &FlattenConfig{}
+	app.Conf(context.Background(), c)
+	Expect(t, c.Flat.state, Equal("inited"))
+	c.Flat.state = "live"
+
+	t.Run("SiblingChanged", func(t *testing.T) {
+		t.Setenv("TEST__FLATTENCONFIG__Flat_Pool", "10")
+		Expect(t, app.Reload(context.Background()), Succeed())
+		Expect(t, c.Flat.Pool, Equal(10))
+		Expect(t, c.Flat.changed, Equal([]string{"Pool"}))
+		// flattened field is not replaced
+		Expect(t, c.Flat.state, Equal("live"))
+	})
+
+	t.Run("FlattenedChanged", func(t *testing.T) {
+		t.Setenv("TEST__FLATTENCONFIG__Flat_Pool", "10")
+		t.Setenv("TEST__FLATTENCONFIG__Flat_Name", "name")
+		Expect(t, app.Reload(context.Background()), Succeed())
+		Expect(t, c.Flat.Name, Equal("name"))
+		Expect(t, c.Flat.changed, Equal([]string{"Name"}))
+		Expect(t, c.Flat.state, Equal("live"))
+	})
+}
+
+func TestReloader_Match(t *testing.T) {
+	keys := []string{"Log_Level", "Pool", "PoolSize", "Name"}
+	Expect(t, match(keys, "Log"), Equal([]string{"Level"}))
+	Expect(t, match(keys, "Pool"), Equal([]string{""}))
+	Expect(t, match(keys, "Other"), HaveLen[[]string](0))
+
+	rt := reflect.TypeFor[FlattenReloadable]()
+	Expect(t, owned(keys, rt), Equal([]string{"Name", "Pool"}))
+	Expect(t, owned(keys, rt.Field(0).Type), Equal([]string{"Name"}))
+}
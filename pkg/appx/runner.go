@@ -0,0 +1,196 @@
+package appx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/xoctopus/confx/pkg/envx"
+	"github.com/xoctopus/confx/pkg/types"
+)
+
+const (
+	// DefaultRestartBackoff is the initial backoff of restarting a failed
+	// Runnable or Servable, it doubles after each restart
+	DefaultRestartBackoff = time.Second
+	// MaxRestartBackoff limits backoff of restarting
+	MaxRestartBackoff = time.Minute
+)
+
+// WithRestart sets how many times a Runnable or Servable component is
+// restarted after it returned an error, backoff is the initial interval
+// between restarts and it doubles each time (zero uses DefaultRestartBackoff).
+// components are not restarted by default. once restarting exhausted, other
+// components are stopped and managed run shuts down with EXIT_CODE_FAILURE.
+func WithRestart(max int, backoff time.Duration) Option {
+	return func(app *AppCtx) {
+		if backoff <= 0 {
+			backoff = DefaultRestartBackoff
+		}
+		app.runners.max = max
+		app.runners.backoff = backoff
+	}
+}
+
+// runner runs a types.Runnable or types.Servable component
+type runner struct {
+	name     string
+	value    reflect.Value
+	run      func(context.Context) error
+	servable types.Servable
+	once     sync.Once
+}
+
+// shutdown shuts down servable once
+func (r *runner) shutdown(ctx context.Context) (err error) {
+	if r.servable == nil {
+		return nil
+	}
+	r.once.Do(func() { err = r.servable.Shutdown(ctx) })
+	return err
+}
+
+// runners supervises runners discovered in Conf. the first failure exhausted
+// restarting cancels others as errgroup does.
+type runners struct {
+	max     int
+	backoff time.Duration
+
+	list   []*runner
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+	once   sync.Once
+	err    error
+	failed chan struct{}
+}
+
+// discover finds types.Servable and types.Runnable in v, a found one is not
+// walked into.
+func (rs *runners) discover(g *envx.Group, v reflect.Value) {
+	walk(g, v, func(name string, rv reflect.Value) bool {
+		if s, ok := capability[types.Servable](rv); ok {
+			rs.list = append(rs.list, &runner{name: name, value: rv, run: s.Serve, servable: s})
+			return true
+		}
+		if r, ok := capability[types.Runnable](rv); ok {
+			rs.list = append(rs.list, &runner{name: name, value: rv, run: r.Run})
+			return true
+		}
+		return false
+	})
+}
+
+// start starts all runners in background
+func (rs *runners) start(ctx context.Context) {
+	rs.failed = make(chan struct{})
+	ctx, rs.cancel = context.WithCancel(ctx)
+	for _, r := range rs.list {
+		rs.wg.Go(func() {
+			if err := rs.supervise(ctx, r); err != nil {
+				rs.once.Do(func() {
+					rs.err = fmt.Errorf("%s: %w", r.name, err)
+					close(rs.failed)
+					rs.cancel()
+				})
+			}
+		})
+	}
+}
+
+// supervise runs r and restarts it on failure. it returns nil if r returned
+// nil or ctx is canceled
+func (rs *runners) supervise(ctx context.Context, r *runner) error {
+	backoff := rs.backoff
+	for restarts := 0; ; restarts++ {
+		err := run(ctx, r)
+		if err == nil || ctx.Err() != nil {
+			return nil
+		}
+		if restarts >= rs.max {
+			fmt.Println(color.HiRedString("%s stopped: %v", r.name, err))
+			return err
+		}
+		fmt.Println(color.HiYellowString("%s failed: %v, restart in %s", r.name, err, backoff))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, MaxRestartBackoff)
+	}
+}
+
+// run runs r and converts panic to error
+func run(ctx context.Context, r *runner) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic: %v", v)
+		}
+	}()
+	return r.run(ctx)
+}
+
+// done returns a channel closed when a runner failed
+func (rs *runners) done() <-chan struct{} {
+	return rs.failed
+}
+
+// stop cancels runners, shuts down servables and waits runners returned in ctx
+func (rs *runners) stop(ctx context.Context) error {
+	if rs.cancel == nil {
+		return nil
+	}
+	rs.cancel()
+
+	errs := make([]error, 0)
+	for _, r := range rs.list {
+		if err := r.shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shutdown %s: %w", r.name, err))
+		}
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		rs.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("runners are not stopped: %w", ctx.Err()))
+	}
+	return errors.Join(errs...)
+}
+
+// shutdown reports if v is a servable already shut down and closing v only
+// shuts it down again
+func (rs *runners) shutdown(v reflect.Value) bool {
+	for _, r := range rs.list {
+		if r.servable == nil || !same(r.value, v) {
+			continue
+		}
+		switch r.servable.(type) {
+		case types.Closable, types.ClosableWithError, types.ClosableByContext, types.ClosableByContextWithError:
+			return false
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// same reports if a and b refer to the same variable
+func same(a, b reflect.Value) bool {
+	for a.Kind() == reflect.Pointer && !a.IsNil() {
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Pointer && !b.IsNil() {
+		b = b.Elem()
+	}
+	return a.CanAddr() && b.CanAddr() && a.Type() == b.Type() && a.Addr().Pointer() == b.Addr().Pointer()
+}
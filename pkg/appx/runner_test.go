@@ -0,0 +1,113 @@
+package appx
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/xoctopus/x/testx"
+)
+
+type testServable struct {
+	Addr string
+
+	serving   chan struct{}
+	stopped   chan struct{}
+	shutdowns atomic.Int32
+}
+
+func (s *testServable) Init() {
+	s.serving = make(chan struct{})
+	s.stopped = make(chan struct{})
+}
+
+func (s *testServable) Serve(ctx context.Context) error {
+	close(s.serving)
+	<-s.stopped
+	return errors.New("server closed")
+}
+
+func (s *testServable) Shutdown(ctx context.Context) error {
+	if s.shutdowns.Add(1) == 1 {
+		close(s.stopped)
+	}
+	return nil
+}
+
+type testRunnable struct {
+	// fails is the times of failure before succeeded
+	fails int
+	runs  atomic.Int32
+}
+
+func (r *testRunnable) Run(ctx context.Context) error {
+	if int(r.runs.Add(1)) <= r.fails {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+type RunnerConfig struct {
+	Server    testServable
+	Migration testRunnable
+	Nested    struct {
+		Job *testRunnable
+	}
+}
+
+func TestAppCtx_Runners(t *testing.T) {
+	t.Run("Supervise", func(t *testing.T) {
+		cfg := &RunnerConfig{Migration: testRunnable{fails: 2}}
+		cfg.Nested.Job = &testRunnable{}
+
+		app := NewAppContext(
+			nil,
+			WithMeta(Meta{Name: "TEST"}),
+			WithRoot(t.TempDir()),
+			WithRestart(2, time.Millisecond),
+		)
+		app.Conf(context.Background(), cfg)
+
+		names := make([]string, 0)
+		for _, r := range app.runners.list {
+			names = append(names, r.name)
+		}
+		Expect(t, names, Equal([]string{
+			"TEST__RUNNERCONFIG__Server",
+			"TEST__RUNNERCONFIG__Migration",
+			"TEST__RUNNERCONFIG__Nested_Job",
+		}))
+
+		app.runners.start(app.Context())
+		<-cfg.Server.serving
+		for range 100 {
+			if cfg.Migration.runs.Load() == 3 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		Expect(t, app.Close(context.Background()), Succeed())
+
+		Expect(t, cfg.Server.shutdowns.Load(), Equal(int32(1)))
+		Expect(t, cfg.Migration.runs.Load(), Equal(int32(3)))
+		Expect(t, cfg.Nested.Job.runs.Load(), Equal(int32(1)))
+		Expect(t, app.runners.err, Succeed())
+	})
+
+	t.Run("FailurePropagation", func(t *testing.T) {
+		cfg := &RunnerConfig{Migration: testRunnable{fails: 1}}
+		app := NewAppContext(
+			nil,
+			WithMeta(Meta{Name: "TEST"}),
+			WithRoot(t.TempDir()),
+			WithManagedRun(time.Second),
+		)
+		app.Conf(context.Background(), cfg)
+
+		Expect(t, app.runManaged(nil), Equal(EXIT_CODE_FAILURE))
+		Expect(t, app.runners.err, ErrorContains("TEST__RUNNERCONFIG__Migration: failed"))
+		Expect(t, cfg.Server.shutdowns.Load(), Equal(int32(1)))
+	})
+}
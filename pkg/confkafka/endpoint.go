@@ -0,0 +1,297 @@
+// Package confkafka defines component of kafka
+// +genx:doc
+package confkafka
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/xoctopus/logx"
+	"github.com/xoctopus/x/codex"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+
+	"github.com/xoctopus/confx/pkg/types"
+	"github.com/xoctopus/confx/pkg/types/liveness"
+	"github.com/xoctopus/confx/pkg/types/mq"
+	"github.com/xoctopus/confx/pkg/types/mq/mqotel"
+)
+
+// Endpoint kafka component endpoint
+type Endpoint struct {
+	types.Endpoint[Option]
+
+	brokers   []string
+	dialer    *kafka.Dialer
+	transport *kafka.Transport
+	// retrier republishes NACKed messages and dead letters
+	retrier *kafka.Writer
+	closed  atomic.Bool
+
+	mq.ResourceManager `env:"-"`
+}
+
+var _ mq.PubSub[ProducerMessage, ConsumerMessage] = (*Endpoint)(nil)
+
+func (e *Endpoint) SetDefault() {
+	if e.Endpoint.Address == "" {
+		e.Endpoint.Address = "kafka://localhost:9092"
+	}
+
+	if e.ResourceManager == nil {
+		e.ResourceManager = mq.NewResourceManager()
+	}
+
+	e.Option.SetDefault()
+	e.closed.Store(false)
+}
+
+func (e *Endpoint) Init(ctx context.Context) (err error) {
+	if err = e.Endpoint.InitByContext(ctx); err != nil {
+		return err
+	}
+
+	u := e.URL()
+	e.brokers = append(e.brokers[:0], u.Host)
+	for _, addr := range e.ExtraAddress {
+		if x, _ := url.Parse(addr); x != nil && x.Host != "" {
+			e.brokers = append(e.brokers, x.Host)
+		}
+	}
+	if len(e.brokers[0]) == 0 {
+		return codex.Errorf(ERROR__CLI_INIT_ERROR, "broker address is required")
+	}
+
+	if e.transport == nil {
+		var mechanism sasl.Mechanism
+		if !e.Auth.IsZero() {
+			mechanism = plain.Mechanism{
+				Username: e.Auth.Username,
+				Password: e.Auth.Password.String(),
+			}
+		}
+
+		e.dialer = &kafka.Dialer{
+			ClientID:      e.Option.ClientID,
+			Timeout:       time.Duration(e.Option.DialTimeout),
+			DualStack:     true,
+			SASLMechanism: mechanism,
+		}
+		e.transport = &kafka.Transport{
+			ClientID:    e.Option.ClientID,
+			DialTimeout: time.Duration(e.Option.DialTimeout),
+			IdleTimeout: time.Duration(e.Option.IdleTimeout),
+			MetadataTTL: time.Duration(e.Option.MetadataTTL),
+			SASL:        mechanism,
+		}
+		if !e.Endpoint.Cert.IsZero() {
+			e.dialer.TLS = e.Endpoint.Cert.Config()
+			e.transport.TLS = e.Endpoint.Cert.Config()
+		}
+		e.retrier = &kafka.Writer{
+			Addr:                   kafka.TCP(e.brokers...),
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			Transport:              e.transport,
+			AllowAutoTopicCreation: true,
+		}
+	}
+
+	return e.LivenessCheck(ctx).FailureReason()
+}
+
+// LivenessCheck helps to probe liveness of brokers. it publishes a message to
+// partition 0 of `liveness` topic and waits the echo.
+// Note: for avoiding backlogs. devs should config retention for `liveness` topic
+// eg: kafka-configs.sh --alter --entity-type topics --entity-name liveness --add-config retention.ms=30000
+func (e *Endpoint) LivenessCheck(ctx context.Context) (v liveness.Result) {
+	v = liveness.NewLivenessData()
+	v.Start()
+
+	if e.closed.Load() || e.transport == nil {
+		v.End(codex.New(ERROR__CLI_CLOSED))
+		return
+	}
+
+	var (
+		err     error
+		r       *kafka.Reader
+		p       mq.Producer[ProducerMessage]
+		echo    kafka.Message
+		body    = []byte(ulid.Make().String())
+		topic   = "liveness"
+		since   = time.Now()
+		timeout = time.Second * 10
+		cause   = fmt.Errorf("echo timeout in 10 seconds")
+		cancel  context.CancelFunc
+	)
+	defer func() {
+		v.End(err)
+	}()
+
+	ctx, cancel = context.WithTimeoutCause(ctx, timeout, cause)
+	defer cancel()
+
+	p, err = e.NewProducer(
+		ctx,
+		WithPubTopic(topic),
+		WithSyncPublish(),
+		WithPubBalancer(kafka.BalancerFunc(func(kafka.Message, ...int) int { return 0 })),
+	)
+	if err != nil {
+		return
+	}
+	defer func() { _ = p.Close() }()
+
+	if err = p.PublishMessage(ctx, NewProducerMessage(topic, body)); err != nil {
+		return
+	}
+
+	r = kafka.NewReader(kafka.ReaderConfig{
+		Brokers: e.brokers,
+		Topic:   topic,
+		Dialer:  e.dialer,
+		MaxWait: time.Duration(e.Option.MaxWait),
+	})
+	defer func() { _ = r.Close() }()
+
+	if err = r.SetOffsetAt(ctx, since); err != nil {
+		return
+	}
+	for {
+		if echo, err = r.FetchMessage(ctx); err == nil {
+			if bytes.Equal(echo.Value, body) {
+				return
+			}
+			continue
+		}
+		return
+	}
+}
+
+func (e *Endpoint) NewProducer(ctx context.Context, options ...mq.OptionApplier) (_ mq.Producer[ProducerMessage], err error) {
+	var (
+		_, log = logx.Enter(ctx)
+		x      *producer
+		opt    = e.Option.PubOption(options...)
+	)
+	defer func() {
+		if err != nil {
+			log.Error(err)
+		} else {
+			e.AddProducer(x)
+			log.Info("pub created")
+		}
+		log.End()
+	}()
+
+	if e.closed.Load() || e.transport == nil {
+		return nil, codex.New(ERROR__CLI_CLOSED)
+	}
+
+	log = log.With("topic", opt.topic, "sync", opt.sync)
+	middlewares := opt.middlewares
+	if !opt.disableTracing {
+		middlewares = append(
+			[]mq.PubMiddleware[ProducerMessage]{mqotel.PubTracing[ProducerMessage]("kafka")},
+			middlewares...,
+		)
+	}
+
+	x = &producer{
+		cli:         e,
+		pub:         opt.Writer(),
+		log:         logx.NewStd().With("producer", opt.name, "topic", opt.topic),
+		topic:       opt.topic,
+		name:        opt.name,
+		sync:        opt.sync,
+		callback:    opt.callback,
+		middlewares: middlewares,
+	}
+	x.pub.Addr = kafka.TCP(e.brokers...)
+	x.pub.Transport = e.transport
+	if !x.sync {
+		x.pub.Completion = x.completion
+	}
+	return x, nil
+}
+
+func (e *Endpoint) NewConsumer(ctx context.Context, options ...mq.OptionApplier) (_ mq.Consumer[ConsumerMessage], err error) {
+	var (
+		_, log = logx.Enter(ctx)
+		x      *consumer
+	)
+
+	defer func() {
+		if err != nil {
+			log.Error(err)
+		} else {
+			e.AddConsumer(x)
+			log.Info("sub created")
+		}
+		log.End()
+	}()
+
+	if e.closed.Load() || e.transport == nil {
+		return nil, codex.New(ERROR__CLI_CLOSED)
+	}
+
+	opt := e.Option.SubOption(options...)
+	config := opt.options
+	config.Brokers = e.brokers
+	config.Dialer = e.dialer
+	if err = config.Validate(); err != nil {
+		return nil, err
+	}
+	log = log.With("subgroup", config.GroupID)
+	middlewares := opt.middlewares
+	if !opt.disableTracing {
+		middlewares = append(
+			[]mq.SubMiddleware[ConsumerMessage]{
+				mqotel.SubTracing[ConsumerMessage]("kafka", semconv.MessagingConsumerGroupName(config.GroupID)),
+			},
+			middlewares...,
+		)
+	}
+
+	x = &consumer{
+		sub:         kafka.NewReader(config),
+		cli:         e,
+		log:         logx.NewStd().With("subgroup", config.GroupID),
+		callback:    opt.callback,
+		middlewares: middlewares,
+		autoAck:     !opt.disableAutoAck,
+		retryNack:   opt.retryNack,
+		maxRetry:    opt.maxRetry,
+		mode:        opt.mode,
+		worker:      opt.worker,
+		hasher:      opt.hasher,
+		bufferSize:  opt.bufferSize,
+	}
+	return x, nil
+}
+
+func (e *Endpoint) Close() error {
+	if e.closed.CompareAndSwap(false, true) {
+		err := e.ResourceManager.Close()
+		if e.retrier != nil {
+			_ = e.retrier.Close()
+		}
+		if e.transport != nil {
+			e.transport.CloseIdleConnections()
+		}
+		return err
+	}
+	return nil
+}
+
+func (e *Endpoint) WithContext(ctx context.Context) context.Context {
+	return With(ctx, e)
+}
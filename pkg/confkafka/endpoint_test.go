@@ -0,0 +1,293 @@
+package confkafka_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	. "github.com/xoctopus/x/testx"
+	"github.com/xoctopus/x/testx/bdd"
+
+	"github.com/xoctopus/confx/hack"
+	. "github.com/xoctopus/confx/pkg/confkafka"
+	"github.com/xoctopus/confx/pkg/types"
+	"github.com/xoctopus/confx/pkg/types/liveness"
+	"github.com/xoctopus/confx/pkg/types/mq"
+)
+
+func TopicFor(t testing.TB) string {
+	return strings.ReplaceAll(t.Name(), "/", "_")
+}
+
+func TestEndpoint(t *testing.T) {
+	bdd.From(t).Given("EmptyEndpoint", func(t bdd.T) {
+		ep := &Endpoint{}
+		t.When("SetDefault", func(t bdd.T) {
+			ep.SetDefault()
+			t.Then(
+				"AddressIsDefaultLocalKafkaEndpoint",
+				bdd.Equal(ep.Address, "kafka://localhost:9092"),
+			)
+		})
+	})
+
+	bdd.From(t).Given("InvalidDSN", func(t bdd.T) {
+		ep := &Endpoint{
+			Endpoint: types.Endpoint[Option]{
+				Address: "kafka://localhost:9092/%zz",
+			},
+		}
+		t.When("Init", func(b bdd.T) {
+			err := ep.Init(hack.Context(t))
+			_, ok := errors.AsType[*url.Error](err)
+			t.Then("FailedToInitCausedByURL", bdd.BeTrue(ok))
+		})
+	})
+
+	bdd.From(t).Given("EmptyBrokerAddress", func(t bdd.T) {
+		ep := &Endpoint{
+			Endpoint: types.Endpoint[Option]{
+				Address: "kafka:///path",
+			},
+		}
+		t.When("Init", func(t bdd.T) {
+			ep.SetDefault()
+			err := ep.Init(hack.Context(t))
+			t.Then("FailedToInit", bdd.IsCodeError(err, ERROR__CLI_INIT_ERROR))
+		})
+	})
+
+	bdd.From(t).Given("UnreachableDSN", func(t bdd.T) {
+		var dsn = "kafka://localhost:9?dialTimeout=100ms"
+		t.When("TryToConnect", func(t bdd.T) {
+			ctx := hack.WithKafkaLost(hack.Context(t), t, dsn)
+			t.When("CheckLiveness", func(t bdd.T) {
+				err := Must(ctx).(*Endpoint).LivenessCheck(ctx).FailureReason()
+				t.Then("Failed", bdd.ErrorContains(err, "connection refused"))
+			})
+		})
+	})
+
+	dsn := "kafka://localhost:19092"
+	bdd.From(t).Given("ReachableDSN", func(t bdd.T) {
+		var (
+			ctx = hack.WithKafka(hack.Context(t), t, dsn)
+			ps  = Must(ctx)
+		)
+		t.Then("Reachable", bdd.NotBeNil(ps))
+	})
+
+	t.Run("ValidateConsumerMessage", func(t *testing.T) {
+		var (
+			mp      = NewProducerMessage(TopicFor(t), []byte(ulid.Make().String()))
+			termsig = make(chan struct{}, 1)
+		)
+		mp.SetPartitionKey("partition_key")
+		mp.SetExpiredAt(9000000000)
+
+		hack.RunKafkaPubSubTestSuite(
+			hack.Context(t), t, dsn,
+			[]ProducerMessage{mp},
+			termsig,
+			func(ctx context.Context, mc ConsumerMessage) error {
+				if !bytes.Equal(mp.Payload(), mc.Payload()) {
+					return nil
+				}
+				Expect(t, mc.Topic(), Equal(mp.Topic()))
+				Expect(t, mc.PartitionKey(), Equal(mp.PartitionKey()))
+				Expect(t, mc.PublishedAt().Second(), Equal(mp.PublishedAt().Second()))
+				Expect(t, mc.RetryCount(), Equal(uint32(0)))
+				Expect(t, mc.ProducedBy(), Equal(mp.Topic()))
+				Expect(t, mc.Underlying().Partition, Equal(int(mc.PartitionID())))
+				Expect(t, mc.Underlying().Offset, Equal(mc.Offset()))
+
+				v, _ := mc.ExtraValueOf(EXTRA_KEY__EXPIRED_AT)
+				Expect(t, v, Equal("9000000000"))
+				_, ok := mc.Extra()["none"]
+				Expect(t, ok, BeFalse())
+
+				termsig <- struct{}{}
+				return nil
+			},
+			time.Second*10,
+			true,
+			WithPubTopic(mp.Topic()),
+			WithPublisherName(mp.Topic()),
+			WithSyncPublish(),
+			WithSubTopic(mp.Topic()),
+		)
+	})
+
+	t.Run("Producing", func(t *testing.T) {
+		var (
+			topic = TopicFor(t)
+			ctx   = hack.WithKafka(hack.Context(t), t, dsn)
+		)
+
+		t.Run("InvalidTopic", func(t *testing.T) {
+			pub, err := Must(ctx).NewProducer(ctx, WithPubTopic(topic))
+			Expect(t, err, Succeed())
+			Expect(t, pub.Topic(), Equal(topic))
+			_, err = pub.Publish(ctx, "unmatched", nil)
+			Expect(t, err, IsCodeError(ERROR__PUB_INVALID_MESSAGE))
+		})
+		t.Run("PubAsyncWithCallback", func(t *testing.T) {
+			done := make(chan struct{})
+			pub, err := Must(ctx).NewProducer(
+				ctx,
+				WithPubTopic(topic),
+				WithPublishCallback(func(_ ProducerMessage, err error) {
+					Expect(t, err, Succeed())
+					close(done)
+				}),
+			)
+			Expect(t, err, Succeed())
+			_, err = pub.Publish(ctx, topic, nil)
+			Expect(t, err, Succeed())
+			select {
+			case <-done:
+			case <-time.After(time.Second * 5):
+				t.Fatal("callback timeout")
+			}
+		})
+		t.Run("PublisherClosed", func(t *testing.T) {
+			pub, err := Must(ctx).NewProducer(ctx, WithPubTopic(topic))
+			Expect(t, err, Succeed())
+			Expect(t, pub.Close(), Succeed())
+			_, err = pub.PublishWithKey(ctx, topic, "partition-key", nil)
+			Expect(t, err, IsCodeError(ERROR__PUB_CLOSED))
+		})
+		t.Run("ClientClosed", func(t *testing.T) {
+			pub, err := Must(ctx).NewProducer(ctx, WithPubTopic(topic))
+			Expect(t, err, Succeed())
+			Expect(t, Must(ctx).Close(), Succeed())
+			_, err = Must(ctx).NewProducer(ctx, WithPubTopic(topic))
+			Expect(t, err, IsCodeError(ERROR__CLI_CLOSED))
+			err = pub.PublishMessage(ctx, NewProducerMessage(topic, nil))
+			Expect(t, err, IsCodeError(ERROR__CLI_CLOSED))
+		})
+	})
+
+	t.Run("Consuming", func(t *testing.T) {
+		var (
+			topic = TopicFor(t)
+			ctx   = hack.WithKafka(hack.Context(t), t, dsn)
+			hdl   = func(context.Context, ConsumerMessage) error { return nil }
+		)
+
+		t.Run("Rerun", func(t *testing.T) {
+			sub, err := Must(ctx).NewConsumer(ctx, WithSubTopic(topic), WithSubWorkerSize(2), WithSubWorkerBufferSize(1))
+			Expect(t, err, Succeed())
+			go func() {
+				_ = sub.Run(ctx, hdl)
+			}()
+			time.Sleep(time.Second)
+			err = sub.Run(ctx, hdl)
+			Expect(t, err, IsCodeError(ERROR__SUB_BOOTED))
+		})
+		t.Run("ConsumerClosed", func(t *testing.T) {
+			sub, err := Must(ctx).NewConsumer(ctx, WithSubTopic(topic))
+			Expect(t, err, Succeed())
+			Expect(t, sub.Close(), Succeed())
+			Expect(t, sub.Run(ctx, hdl), IsCodeError(ERROR__SUB_CLOSED))
+		})
+		t.Run("ClientClosed", func(t *testing.T) {
+			sub, err := Must(ctx).NewConsumer(ctx, WithSubTopic(topic))
+			Expect(t, err, Succeed())
+			Expect(t, Must(ctx).Close(), Succeed())
+
+			_, err = Must(ctx).NewConsumer(ctx, WithSubTopic(topic))
+			Expect(t, err, IsCodeError(ERROR__CLI_CLOSED))
+
+			v := Must(ctx).(liveness.Checker).LivenessCheck(ctx)
+			Expect(t, v.FailureReason(), IsCodeError(ERROR__CLI_CLOSED))
+
+			Expect(t, sub.Run(ctx, hdl), IsCodeError(ERROR__CLI_CLOSED))
+		})
+	})
+
+	t.Run("ConsumerHandling", func(t *testing.T) {
+		var herr = errors.New("handle error")
+		t.Run("HandlerPanicked", func(t *testing.T) {
+			var (
+				topic   = TopicFor(t)
+				msg     = NewProducerMessage(topic, []byte(ulid.Make().String()))
+				termsig = make(chan struct{}, 1)
+				handler = func(_ context.Context, m ConsumerMessage) error {
+					if bytes.Equal(m.Payload(), msg.Payload()) {
+						termsig <- struct{}{}
+						panic(herr)
+					}
+					return nil
+				}
+			)
+
+			hack.RunKafkaPubSubTestSuite(
+				hack.Context(t), t, dsn,
+				[]ProducerMessage{msg},
+				termsig,
+				handler,
+				time.Second*10,
+				false,
+				WithPubTopic(topic),
+				WithSyncPublish(),
+				WithSubTopic(topic),
+				WithSubConsumingMode(mq.PartitionOrdered),
+				WithSubWorkerSize(1),
+				WithSubWorkerBufferSize(1),
+				WithSubCallback(func(_ mq.Acknowledger[ConsumerMessage], m ConsumerMessage, err error) {
+					if bytes.Equal(m.Payload(), msg.Payload()) {
+						Expect(t, err, IsError(herr))
+						Expect(t, err, IsCodeError(ERROR__SUB_HANDLER_PANICKED))
+					}
+				}),
+			)
+		})
+		t.Run("HandlerFailed", func(t *testing.T) {
+			var (
+				topic   = TopicFor(t)
+				msg     = NewProducerMessage(topic, []byte(ulid.Make().String()))
+				termsig = make(chan struct{}, 1)
+				handler = func(_ context.Context, m ConsumerMessage) error {
+					if bytes.Equal(m.Payload(), msg.Payload()) {
+						return herr
+					}
+					return nil
+				}
+			)
+
+			hack.RunKafkaPubSubTestSuite(
+				hack.Context(t), t, dsn,
+				[]ProducerMessage{msg},
+				termsig,
+				handler,
+				time.Second*10,
+				false,
+				WithPubTopic(topic),
+				WithSyncPublish(),
+				WithSubTopic(topic),
+				WithSubConsumingMode(mq.Concurrent),
+				WithSubWorkerSize(1),
+				WithSubWorkerBufferSize(1),
+				WithSubDisableAutoAck(),
+				WithSubEnableRetryNack(3),
+				WithSubCallback(func(a mq.Acknowledger[ConsumerMessage], m ConsumerMessage, err error) {
+					if bytes.Equal(m.Payload(), msg.Payload()) {
+						Expect(t, err, IsError(herr))
+					}
+					if m.RetryCount() > 1 {
+						_ = a.Ack(m)
+						termsig <- struct{}{}
+					} else {
+						_ = a.Nack(m)
+					}
+				}),
+			)
+		})
+	})
+}
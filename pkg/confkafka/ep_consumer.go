@@ -0,0 +1,343 @@
+package confkafka
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/xoctopus/logx"
+	"github.com/xoctopus/x/codex"
+
+	"github.com/xoctopus/confx/pkg/types/mq"
+)
+
+type consumer struct {
+	cli    *Endpoint
+	elem   *list.Element
+	closed atomic.Bool
+	booted atomic.Bool
+	sub    *kafka.Reader
+	log    logx.Logger
+
+	mode       mq.ConsumeHandleMode
+	worker     uint16
+	bufferSize uint16
+	tasks      []chan kafka.Message
+	wg         sync.WaitGroup
+
+	hasher   mq.Hasher
+	handler  mq.SubHandler[ConsumerMessage]
+	callback mq.SubCallback[ConsumerMessage]
+
+	middlewares []mq.SubMiddleware[ConsumerMessage]
+
+	// offsets tracks fetched offsets to commit contiguous settled ones
+	offsets offsets
+
+	cancel    context.CancelCauseFunc
+	autoAck   bool
+	retryNack bool
+	maxRetry  uint32
+}
+
+var _ mq.Consumer[ConsumerMessage] = (*consumer)(nil)
+
+func (s *consumer) process(ctx context.Context, wid uint16) error {
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	log := s.log.With("worker_id", wid)
+	log.Info("processing stated")
+	for {
+		select {
+		case <-ctx.Done():
+			err := errors.Join(ctx.Err(), context.Cause(ctx))
+			log.Error(fmt.Errorf("processing stopped caused by %w", err))
+			return err
+		case m := <-s.tasks[wid]:
+			logd := log.With("topic", m.Topic, "partition", m.Partition, "offset", m.Offset)
+			msg := NewConsumerMessage(m)
+			if err := s.handle(ctx, msg); err != nil {
+				logd.With("action", "handle").Error(err)
+			}
+			if s.autoAck || s.callback == nil {
+				if err := s.Ack(msg); err != nil {
+					logd.With("action", "ack").Error(err)
+				}
+			}
+		}
+	}
+}
+
+func (s *consumer) dispatch(ctx context.Context) error {
+	var (
+		count uint16
+		wid   uint16
+	)
+	for {
+		// block call until subscriber closed
+		msg, err := s.sub.FetchMessage(ctx)
+		if err != nil {
+			return errors.Join(err, context.Cause(ctx))
+		}
+		switch s.mode {
+		case mq.PartitionOrdered:
+			wid = s.hasher(string(msg.Key)) % s.worker
+		case mq.Concurrent:
+			count = (count + 1) % math.MaxUint16
+			wid = count % s.worker
+		default:
+			wid = 0
+		}
+		s.offsets.fetched(msg)
+		s.log.With("worker_id", wid, "order_key", string(msg.Key)).Info("dispatched")
+		s.tasks[wid] <- msg
+	}
+}
+
+// Run starts consuming messages and processing them.
+func (s *consumer) Run(ctx context.Context, h mq.SubHandler[ConsumerMessage]) error {
+	if !s.booted.CompareAndSwap(false, true) {
+		return codex.Errorf(ERROR__SUB_BOOTED, "reentered")
+	}
+	if s.cli.closed.Load() {
+		return codex.New(ERROR__CLI_CLOSED)
+	}
+	if s.closed.Load() {
+		return codex.New(ERROR__SUB_CLOSED)
+	}
+
+	s.tasks = make([]chan kafka.Message, s.worker)
+	for i := range s.tasks {
+		s.tasks[i] = make(chan kafka.Message, s.bufferSize)
+	}
+
+	s.handler = mq.ChainSub(h, s.middlewares...)
+	ctx, s.cancel = context.WithCancelCause(ctx)
+
+	defer func() { _ = s.Close() }()
+	for i := range s.worker {
+		go func() {
+			err := s.process(ctx, i)
+			s.log.With("worker_id", i).Error(fmt.Errorf("processing stopped caused by: %w", err))
+		}()
+	}
+
+	log := s.log.With("workers", s.worker)
+	log.Info("dispatching started")
+	err := s.dispatch(ctx)
+	log.Error(fmt.Errorf("dispatching stopped caused by: %w", err))
+	return err
+}
+
+// handle wrapped consumer handle task
+func (s *consumer) handle(ctx context.Context, msg ConsumerMessage) (err error) {
+	_, log := logx.Enter(
+		ctx,
+		"topic", msg.Topic(),
+		"pub_at", msg.PublishedAt(),
+		"latency", msg.Latency().Milliseconds(),
+	)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = codex.Errorf(ERROR__SUB_HANDLER_PANICKED, "cause: %v", r)
+			if x, ok := r.(error); ok {
+				err = codex.Wrap(ERROR__SUB_HANDLER_PANICKED, x)
+			}
+		}
+		if err != nil {
+			log.Error(err)
+		} else {
+			log.Info("handled")
+		}
+		if s.callback != nil {
+			s.callback(s, msg, err)
+		}
+		log.End()
+	}()
+	return s.handler(ctx, msg)
+}
+
+// Ack commits message offset. committing an offset of partition implicitly
+// acknowledges all messages before it in the same partition, so that message
+// is committed only after all messages fetched before it in its partition are
+// settled, which matters when messages are handled by multiple workers.
+func (s *consumer) Ack(m ConsumerMessage) error {
+	u, ok := s.offsets.settle(m.Underlying())
+	if !ok {
+		return nil
+	}
+	return s.sub.CommitMessages(context.Background(), u)
+}
+
+// Nack republishes message to its topic with increased retry count if retry
+// nack enabled. if reached max retry times the message is published to dead
+// letter topic. then the message is committed.
+//
+// without retry nack, kafka cannot redeliver a single message, the message is
+// settled and committed along with messages acknowledged after it.
+func (s *consumer) Nack(m ConsumerMessage) error {
+	if !s.retryNack {
+		// commits messages acknowledged after it only
+		if u, ok := s.offsets.settle(m.Underlying()); ok && u.Offset > m.Offset() {
+			return s.sub.CommitMessages(context.Background(), u)
+		}
+		return nil
+	}
+
+	u := m.Underlying()
+	retry := &producerMessage{
+		Message: kafka.Message{
+			Topic:   u.Topic,
+			Key:     u.Key,
+			Value:   u.Value,
+			Headers: slices.Clone(u.Headers),
+			Time:    u.Time,
+		},
+	}
+	count := m.RetryCount() + 1
+	if count > s.maxRetry {
+		retry.SetTopic(u.Topic + DLQ_SUFFIX)
+	}
+	retry.AddExtra(EXTRA_KEY__RETRY_COUNT, strconv.FormatUint(uint64(count), 10))
+
+	if err := s.cli.retrier.WriteMessages(context.Background(), retry.Message); err != nil {
+		return err
+	}
+	return s.Ack(m)
+}
+
+func (s *consumer) Elem() *list.Element {
+	return s.elem
+}
+
+func (s *consumer) SetElem(elem *list.Element) {
+	s.elem = elem
+}
+
+// Release stops consuming and closes reader. kafka has no unsubscription, the
+// consumer leaves its consumer group whether opt.Unsub is set or not.
+func (s *consumer) Release(appliers ...mq.ReleaseOptionFunc) error {
+	var (
+		err    error
+		opt    mq.ReleaseOption
+		closed bool
+	)
+
+	for _, applier := range appliers {
+		applier(&opt)
+	}
+
+	log := s.log.With("unsub", opt.Unsub)
+	defer func() {
+		if err != nil {
+			log.Warn(err)
+		}
+		if closed {
+			log.Info("consumer underlying closed")
+		}
+	}()
+
+	if s.closed.CompareAndSwap(false, true) {
+		cause := ERROR__SUB_CLOSED
+		if opt.Unsub {
+			cause = ERROR__SUB_UNSUBSCRIBED
+		}
+		if s.cancel != nil {
+			s.cancel(codex.New(cause))
+		}
+		s.wg.Wait()
+		if s.sub != nil {
+			closed = true
+			err = s.sub.Close()
+		}
+		for i := range s.tasks {
+			close(s.tasks[i])
+		}
+	}
+	return err
+}
+
+func (s *consumer) Unsubscribe() error {
+	return s.cli.ResourceManager.Unsubscribe(s)
+}
+
+func (s *consumer) Close() error {
+	return s.cli.ResourceManager.CloseConsumer(s)
+}
+
+type partition struct {
+	topic string
+	id    int
+}
+
+// pending holds offsets of a partition fetched but not committed
+type pending struct {
+	offsets []int64
+	settled map[int64]kafka.Message
+}
+
+// offsets tracks fetched messages of each partition. messages may be settled
+// out of order by workers, only the highest contiguous settled offset can be
+// committed to avoid losing messages in processing if crashed.
+type offsets struct {
+	mtx        sync.Mutex
+	partitions map[partition]*pending
+}
+
+func (o *offsets) fetched(m kafka.Message) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	if o.partitions == nil {
+		o.partitions = make(map[partition]*pending)
+	}
+	k := partition{topic: m.Topic, id: m.Partition}
+	p := o.partitions[k]
+	// refetched from committed offset after rebalancing
+	if p == nil || len(p.offsets) > 0 && m.Offset <= p.offsets[len(p.offsets)-1] {
+		p = &pending{settled: make(map[int64]kafka.Message)}
+		o.partitions[k] = p
+	}
+	p.offsets = append(p.offsets, m.Offset)
+}
+
+// settle marks m settled and returns message of the highest contiguous settled
+// offset in its partition. false is returned if there is nothing to commit.
+// message not tracked is returned directly.
+func (o *offsets) settle(m kafka.Message) (kafka.Message, bool) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	p := o.partitions[partition{topic: m.Topic, id: m.Partition}]
+	if p == nil {
+		return m, true
+	}
+	if _, ok := slices.BinarySearch(p.offsets, m.Offset); !ok {
+		return m, len(p.offsets) == 0 || m.Offset > p.offsets[len(p.offsets)-1]
+	}
+	p.settled[m.Offset] = m
+
+	var (
+		last      kafka.Message
+		committed bool
+	)
+	for len(p.offsets) > 0 {
+		x, ok := p.settled[p.offsets[0]]
+		if !ok {
+			break
+		}
+		delete(p.settled, p.offsets[0])
+		p.offsets = p.offsets[1:]
+		last, committed = x, true
+	}
+	return last, committed
+}
@@ -0,0 +1,53 @@
+package confkafka
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	. "github.com/xoctopus/x/testx"
+)
+
+func TestOffsets(t *testing.T) {
+	o := &offsets{}
+	message := func(partition int, offset int64) kafka.Message {
+		return kafka.Message{Topic: "topic", Partition: partition, Offset: offset}
+	}
+	for _, offset := range []int64{1, 2, 4, 5} {
+		o.fetched(message(0, offset))
+	}
+	o.fetched(message(1, 10))
+
+	// offset 2 is settled before 1
+	_, ok := o.settle(message(0, 2))
+	Expect(t, ok, BeFalse())
+	m, ok := o.settle(message(0, 1))
+	Expect(t, ok, BeTrue())
+	Expect(t, m.Offset, Equal(int64(2)))
+
+	// other partitions are tracked separately
+	m, ok = o.settle(message(1, 10))
+	Expect(t, ok, BeTrue())
+	Expect(t, m.Offset, Equal(int64(10)))
+
+	_, ok = o.settle(message(0, 5))
+	Expect(t, ok, BeFalse())
+	// settled twice
+	_, ok = o.settle(message(0, 2))
+	Expect(t, ok, BeFalse())
+	m, ok = o.settle(message(0, 4))
+	Expect(t, ok, BeTrue())
+	Expect(t, m.Offset, Equal(int64(5)))
+
+	// refetched from committed offset after rebalancing
+	o.fetched(message(0, 6))
+	o.fetched(message(0, 7))
+	o.fetched(message(0, 6))
+	m, ok = o.settle(message(0, 6))
+	Expect(t, ok, BeTrue())
+	Expect(t, m.Offset, Equal(int64(6)))
+
+	// untracked message
+	m, ok = o.settle(message(2, 3))
+	Expect(t, ok, BeTrue())
+	Expect(t, m.Offset, Equal(int64(3)))
+}
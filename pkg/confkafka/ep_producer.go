@@ -0,0 +1,122 @@
+package confkafka
+
+import (
+	"container/list"
+	"context"
+	"sync/atomic"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/xoctopus/logx"
+	"github.com/xoctopus/x/codex"
+
+	"github.com/xoctopus/confx/pkg/types/mq"
+)
+
+type producer struct {
+	cli    *Endpoint
+	elem   *list.Element
+	closed atomic.Bool
+
+	log      logx.Logger
+	pub      *kafka.Writer
+	topic    string
+	name     string
+	sync     bool
+	callback mq.AsyncPubCallback[ProducerMessage]
+
+	middlewares []mq.PubMiddleware[ProducerMessage]
+}
+
+func (p *producer) Topic() string {
+	return p.topic
+}
+
+func (p *producer) Publish(ctx context.Context, topic string, payload []byte) (ProducerMessage, error) {
+	msg := NewProducerMessage(topic, payload)
+	return msg, p.PublishMessage(ctx, msg)
+}
+
+func (p *producer) PublishWithKey(ctx context.Context, topic, key string, payload []byte) (ProducerMessage, error) {
+	msg := NewProducerMessage(topic, payload)
+	msg.SetPartitionKey(key)
+	return msg, p.PublishMessage(ctx, msg)
+}
+
+func (p *producer) PublishMessage(ctx context.Context, msg ProducerMessage) error {
+	return mq.ChainPub(p.send, p.middlewares...)(ctx, msg)
+}
+
+// send publishes msg without middlewares
+func (p *producer) send(ctx context.Context, msg ProducerMessage) (err error) {
+	_, log := logx.Enter(ctx)
+	defer func() {
+		if err != nil {
+			log.Error(err)
+		} else {
+			log.Info("published")
+		}
+		log.End()
+	}()
+
+	if topic := msg.Topic(); topic != p.topic {
+		return codex.Errorf(
+			ERROR__PUB_INVALID_MESSAGE,
+			"unexpected topic: expect `%s` but got `%s`",
+			p.topic, topic,
+		)
+	}
+
+	if p.cli.closed.Load() {
+		return codex.New(ERROR__CLI_CLOSED)
+	}
+
+	if p.closed.Load() {
+		return codex.New(ERROR__PUB_CLOSED)
+	}
+
+	msg.RefreshPublishedAt()
+	if len(p.name) > 0 {
+		msg.AddExtra(EXTRA_KEY__PRODUCER, p.name)
+	}
+	log = log.With("pub_at", msg.PublishedAt(), "partition_key", msg.PartitionKey())
+
+	raw := *msg.Underlying()
+	if !p.sync {
+		raw.WriterData = msg
+	}
+	// in async mode, WriteMessages returns immediately and the result is
+	// reported by completion
+	return p.pub.WriteMessages(ctx, raw)
+}
+
+func (p *producer) completion(messages []kafka.Message, err error) {
+	for i := range messages {
+		msg, ok := messages[i].WriterData.(ProducerMessage)
+		if !ok {
+			continue
+		}
+		p.log.With("pub_at", msg.PublishedAt(), "result", err).Info("callback called")
+		if p.callback != nil {
+			p.callback(msg, err)
+		}
+	}
+}
+
+func (p *producer) Elem() *list.Element {
+	return p.elem
+}
+
+func (p *producer) SetElem(elem *list.Element) {
+	p.elem = elem
+}
+
+func (p *producer) Release(_ ...mq.ReleaseOptionFunc) error {
+	if p.closed.CompareAndSwap(false, true) {
+		return p.pub.Close()
+	}
+	return nil
+}
+
+func (p *producer) Close() error {
+	return p.cli.CloseProducer(p)
+}
@@ -0,0 +1,12 @@
+package confkafka
+
+// this file defines keys for extended metadata and more mq-specific features for kafka
+
+const (
+	// EXTRA_KEY__EXPIRED_AT is header key for message expiration timestamp (epoch seconds).
+	EXTRA_KEY__EXPIRED_AT = "EXPIRED_AT"
+	// EXTRA_KEY__RETRY_COUNT is header key for redelivery times of a NACKed message.
+	EXTRA_KEY__RETRY_COUNT = "RETRY_COUNT"
+	// EXTRA_KEY__PRODUCER is header key for the client id of message producer.
+	EXTRA_KEY__PRODUCER = "PRODUCER"
+)
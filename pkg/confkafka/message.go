@@ -1,5 +1,235 @@
 package confkafka
 
 import (
-	_ "github.com/segmentio/kafka-go"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/xoctopus/x/misc/must"
+
+	"github.com/xoctopus/confx/pkg/types/mq"
 )
+
+type ProducerMessage interface {
+	mq.HasTopic
+	mq.CanSetTopic
+	mq.HasPayload
+	mq.CanSetPayload
+	mq.HasExtra
+	mq.CanAppendExtra
+	mq.HasExpiredAt
+	mq.CanSetExpiredAt
+	mq.HasPartitionKey
+	mq.CanSetPartitionKey
+	mq.HasPublishedAt
+	mq.CanRefreshPublishedAt
+	mq.HasUnderlying[*kafka.Message]
+}
+
+func NewProducerMessage(topic string, payload []byte) ProducerMessage {
+	m := &producerMessage{}
+	m.SetTopic(topic)
+	m.SetPayload(payload)
+	m.RefreshPublishedAt()
+	return m
+}
+
+type producerMessage struct {
+	kafka.Message
+}
+
+func (x *producerMessage) Topic() string {
+	return x.Message.Topic
+}
+
+func (x *producerMessage) SetTopic(topic string) {
+	x.Message.Topic = topic
+}
+
+func (x *producerMessage) Payload() []byte {
+	return x.Value
+}
+
+func (x *producerMessage) SetPayload(payload []byte) {
+	x.Value = payload
+}
+
+func (x *producerMessage) Extra() map[string]string {
+	return headers(x.Headers)
+}
+
+func (x *producerMessage) ExtraValueOf(k string) (string, bool) {
+	return headerValueOf(x.Headers, k)
+}
+
+func (x *producerMessage) AddExtra(k, v string) {
+	for i := range x.Headers {
+		if x.Headers[i].Key == k {
+			x.Headers[i].Value = []byte(v)
+			return
+		}
+	}
+	x.Headers = append(x.Headers, kafka.Header{Key: k, Value: []byte(v)})
+}
+
+func (x *producerMessage) ExpiredAt() int64 {
+	return expiredAt(x.Headers)
+}
+
+func (x *producerMessage) SetExpiredAt(expiredAt int64) {
+	must.BeTrueF(expiredAt > time.Now().Unix(), "invalid expired timestamp")
+	x.AddExtra(EXTRA_KEY__EXPIRED_AT, strconv.FormatInt(expiredAt, 10))
+}
+
+func (x *producerMessage) SetExpiredAfter(du time.Duration) {
+	x.AddExtra(EXTRA_KEY__EXPIRED_AT, strconv.FormatInt(time.Now().Add(du).Unix(), 10))
+}
+
+func (x *producerMessage) PartitionKey() string {
+	return string(x.Key)
+}
+
+func (x *producerMessage) SetPartitionKey(k string) {
+	x.Key = []byte(k)
+}
+
+func (x *producerMessage) PublishedAt() time.Time {
+	return x.Time
+}
+
+func (x *producerMessage) RefreshPublishedAt() {
+	x.Time = time.Now()
+}
+
+func (x *producerMessage) Underlying() *kafka.Message {
+	return &x.Message
+}
+
+type ConsumerMessage interface {
+	mq.HasTopic
+	mq.HasPayload
+	mq.HasExtra
+	mq.HasExpiredAt
+	mq.HasPartitionKey
+	mq.HasPartitionID
+	mq.HasOffset
+	mq.HasProducer
+	mq.HasPublishedAt
+	mq.HasConsumedAt
+	mq.CanRefreshConsumedAt
+	mq.HasLatency
+	mq.HasRetryCount
+	mq.HasUnderlying[kafka.Message]
+}
+
+func NewConsumerMessage(u kafka.Message) ConsumerMessage {
+	m := &consumerMessage{Message: u}
+	m.RefreshConsumedAt()
+	return m
+}
+
+type consumerMessage struct {
+	kafka.Message
+	consumedAt time.Time
+}
+
+func (x *consumerMessage) Topic() string {
+	return x.Message.Topic
+}
+
+func (x *consumerMessage) Payload() []byte {
+	return x.Value
+}
+
+func (x *consumerMessage) Extra() map[string]string {
+	return headers(x.Headers)
+}
+
+func (x *consumerMessage) ExtraValueOf(k string) (string, bool) {
+	return headerValueOf(x.Headers, k)
+}
+
+func (x *consumerMessage) ExpiredAt() int64 {
+	return expiredAt(x.Headers)
+}
+
+func (x *consumerMessage) PartitionKey() string {
+	return string(x.Key)
+}
+
+func (x *consumerMessage) PartitionID() int64 {
+	return int64(x.Partition)
+}
+
+func (x *consumerMessage) Offset() int64 {
+	return x.Message.Offset
+}
+
+func (x *consumerMessage) ProducedBy() string {
+	v, _ := x.ExtraValueOf(EXTRA_KEY__PRODUCER)
+	return v
+}
+
+func (x *consumerMessage) PublishedAt() time.Time {
+	return x.Time
+}
+
+func (x *consumerMessage) ConsumedAt() time.Time {
+	return x.consumedAt
+}
+
+func (x *consumerMessage) RefreshConsumedAt() {
+	x.consumedAt = time.Now()
+}
+
+func (x *consumerMessage) Latency() time.Duration {
+	t1, t2 := x.PublishedAt(), x.ConsumedAt()
+	if !t1.IsZero() && !t2.IsZero() && t1.Before(t2) {
+		return t2.Sub(t1)
+	}
+	return 0
+}
+
+func (x *consumerMessage) RetryCount() uint32 {
+	if val, ok := x.ExtraValueOf(EXTRA_KEY__RETRY_COUNT); ok {
+		if v, err := strconv.ParseUint(val, 10, 32); err == nil {
+			return uint32(v)
+		}
+	}
+	return 0
+}
+
+func (x *consumerMessage) Underlying() kafka.Message {
+	return x.Message
+}
+
+func headers(hs []kafka.Header) map[string]string {
+	if len(hs) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(hs))
+	for _, h := range hs {
+		m[h.Key] = string(h.Value)
+	}
+	return m
+}
+
+func headerValueOf(hs []kafka.Header, k string) (string, bool) {
+	for _, h := range hs {
+		if h.Key == k {
+			return string(h.Value), true
+		}
+	}
+	return "", false
+}
+
+func expiredAt(hs []kafka.Header) int64 {
+	expiredAt := int64(math.MaxInt64)
+	if val, ok := headerValueOf(hs, EXTRA_KEY__EXPIRED_AT); ok {
+		if v, err := strconv.ParseInt(val, 10, 64); err == nil {
+			expiredAt = v
+		}
+	}
+	return expiredAt
+}
@@ -0,0 +1,73 @@
+package confkafka_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	. "github.com/xoctopus/x/testx"
+
+	. "github.com/xoctopus/confx/pkg/confkafka"
+)
+
+func TestProducerMessage(t *testing.T) {
+	var (
+		topic = TopicFor(t)
+		body  = []byte(topic)
+	)
+
+	mp := NewProducerMessage(topic, body)
+	mp.SetPartitionKey("abc")
+	Expect(t, mp.ExpiredAt(), Equal(int64(math.MaxInt64)))
+
+	exp := time.Now().Unix() + 100
+	mp.SetExpiredAt(exp)
+	mp.SetExpiredAt(exp + 1)
+
+	Expect(t, mp.Topic(), Equal(topic))
+	Expect(t, mp.Payload(), Equal(body))
+	Expect(t, mp.PartitionKey(), Equal("abc"))
+	Expect(t, mp.ExpiredAt(), Equal(exp+1))
+	Expect(t, time.Since(mp.PublishedAt()) > 0, BeTrue())
+
+	Expect(t, len(mp.Extra()), Equal(1))
+	v, _ := mp.ExtraValueOf(EXTRA_KEY__EXPIRED_AT)
+	Expect(t, v, Equal(strconv.FormatInt(exp+1, 10)))
+
+	// modify through underlying
+	u := mp.Underlying()
+	u.Key = []byte("partition_key")
+	Expect(t, mp.PartitionKey(), Equal("partition_key"))
+}
+
+func TestConsumerMessage(t *testing.T) {
+	u := kafka.Message{
+		Topic:     TopicFor(t),
+		Partition: 2,
+		Offset:    100,
+		Key:       []byte("abc"),
+		Value:     []byte("payload"),
+		Headers: []kafka.Header{
+			{Key: EXTRA_KEY__RETRY_COUNT, Value: []byte("2")},
+			{Key: EXTRA_KEY__PRODUCER, Value: []byte("producer")},
+		},
+		Time: time.Now().Add(-time.Second),
+	}
+
+	mc := NewConsumerMessage(u)
+	Expect(t, mc.Topic(), Equal(u.Topic))
+	Expect(t, mc.Payload(), Equal(u.Value))
+	Expect(t, mc.PartitionKey(), Equal("abc"))
+	Expect(t, mc.PartitionID(), Equal(int64(2)))
+	Expect(t, mc.Offset(), Equal(int64(100)))
+	Expect(t, mc.RetryCount(), Equal(uint32(2)))
+	Expect(t, mc.ProducedBy(), Equal("producer"))
+	Expect(t, mc.ExpiredAt(), Equal(int64(math.MaxInt64)))
+	Expect(t, mc.Latency() >= time.Second, BeTrue())
+	Expect(t, len(mc.Extra()), Equal(2))
+	_, ok := mc.ExtraValueOf("none")
+	Expect(t, ok, BeFalse())
+	Expect(t, mc.Underlying().Offset, Equal(int64(100)))
+}
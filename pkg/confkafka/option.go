@@ -0,0 +1,486 @@
+package confkafka
+
+import (
+	"slices"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/xoctopus/x/misc/must"
+	"github.com/xoctopus/x/textx"
+
+	"github.com/xoctopus/confx/pkg/types"
+	"github.com/xoctopus/confx/pkg/types/mq"
+)
+
+// DLQ_SUFFIX is appended to the consuming topic as the dead letter topic of
+// messages reached max nack retry times
+const DLQ_SUFFIX = "_DLQ"
+
+// Option presents kafka client options and default pub/sub options. it can be
+// overridden by option applier when call Endpoint.NewProducer and
+// Endpoint.NewConsumer
+type Option struct {
+	// ClientID [Client] identifies the client to brokers and is attached to
+	// published messages as producer name
+	ClientID string `url:",default=confx"`
+	// DialTimeout [Client] connection establishment timeout
+	DialTimeout types.Duration `url:",default=3s"`
+	// IdleTimeout [Client] release the connection if it is not used for more
+	// than IdleTimeout
+	IdleTimeout types.Duration `url:",default=30s"`
+	// MetadataTTL [Client] the ttl of cached topic and partition metadata
+	MetadataTTL types.Duration `url:",default=6s"`
+
+	// BatchSize [PUB] max messages buffered before sending to a partition
+	BatchSize int `url:",default=100"`
+	// BatchTimeout [PUB] max time of an incomplete batch will be buffered
+	BatchTimeout types.Duration `url:",default=10ms"`
+	// WriteTimeout [PUB] specifies the timeout for a batch from sent to
+	// acknowledged by the broker
+	WriteTimeout types.Duration `url:",default=10s"`
+	// MaxAttempts [PUB] max attempts of delivering a batch
+	MaxAttempts int `url:",default=10"`
+	// RequiredAcks [PUB] number of acknowledges from partition replicas
+	// required before receiving a response. -1 means all in-sync replicas.
+	RequiredAcks int `url:",default=-1"`
+	// DisableCompress [PUB] specifies if disable message compression, if it is
+	// enabled use LZ4 compress type
+	DisableCompress bool `url:",default=false"`
+	// DisableAutoTopicCreation [PUB] if disabled, publishing to a nonexistent
+	// topic will be failed.
+	DisableAutoTopicCreation bool `url:",default=false"`
+
+	// StartFromLatest [SUB] if enabled, a consumer group without committed
+	// offset starts consuming from the latest message, otherwise the earliest.
+	StartFromLatest bool `url:",default=false"`
+	// CommitInterval [SUB] the interval of committing offsets to broker. if it
+	// is 0, offsets are committed synchronously when message acked.
+	CommitInterval types.Duration `url:",default=0s"`
+	// MaxWait [SUB] max time for waiting new data when fetching batches
+	MaxWait types.Duration `url:",default=1s"`
+	// EnableRetryNack [SUB] if enabled, NACKed message will be republished to
+	// its topic max MaxNackRetry times. if reached MaxNackRetry times, the
+	// message is published to the dead letter topic `<topic>_DLQ`
+	EnableRetryNack bool `url:",default=true"`
+	// MaxNackRetry [SUB] max retry times for nack message
+	MaxNackRetry uint32 `url:",default=3"`
+	// WorkerSize defines the concurrency level for message consumption.
+	// Behavior based on ConsumeMode:
+	// eg:
+	//	- mq.GlobalOrdered: Forced to 1 to ensure strict sequential processing.
+	//	- mq.PartitionOrdered: Messages are dispatched to specific workers based on
+	//	  a hash of the partition key, ensuring order within the same key.
+	//	- mq.Concurrent: messages are distributed across all workers (e.g., round-robin)
+	//	  to maximize throughput.
+	WorkerSize uint16 `url:",default=16"`
+	// WorkerBufferSize [SUB] will prefetch message from broker for improves
+	// consumption throughput and reducing wait
+	WorkerBufferSize uint16 `url:",default=64"`
+
+	// defaultPubOption default publisher option
+	defaultPubOption *PubOption
+	// defaultSubOption default subscriber option
+	defaultSubOption *SubOption
+}
+
+func (o *Option) SetDefault() {
+	must.NoErrorV(textx.SetDefault(o))
+
+	if o.defaultPubOption == nil {
+		o.defaultPubOption = &PubOption{}
+	}
+	if !o.defaultPubOption._initialized {
+		compression := kafka.Lz4
+		if o.DisableCompress {
+			compression = 0
+		}
+		o.defaultPubOption = &PubOption{
+			name:            o.ClientID,
+			balancer:        &kafka.Hash{},
+			batchSize:       o.BatchSize,
+			batchTimeout:    time.Duration(o.BatchTimeout),
+			writeTimeout:    time.Duration(o.WriteTimeout),
+			maxAttempts:     o.MaxAttempts,
+			requiredAcks:    kafka.RequiredAcks(o.RequiredAcks),
+			compression:     compression,
+			autoCreateTopic: !o.DisableAutoTopicCreation,
+		}
+		o.defaultPubOption._initialized = true
+	}
+	if o.defaultSubOption == nil {
+		o.defaultSubOption = &SubOption{}
+	}
+	if !o.defaultSubOption._initialized {
+		offset := kafka.FirstOffset
+		if o.StartFromLatest {
+			offset = kafka.LastOffset
+		}
+		o.defaultSubOption = &SubOption{
+			options: kafka.ReaderConfig{
+				MaxWait:        time.Duration(o.MaxWait),
+				CommitInterval: time.Duration(o.CommitInterval),
+				StartOffset:    offset,
+			},
+			retryNack:  o.EnableRetryNack,
+			maxRetry:   o.MaxNackRetry,
+			worker:     o.WorkerSize,
+			hasher:     mq.CRC,
+			bufferSize: o.WorkerBufferSize,
+		}
+		o.defaultSubOption._initialized = true
+	}
+}
+
+func (o *Option) PubOption(appliers ...mq.OptionApplier) *PubOption {
+	opt := *o.defaultPubOption
+	for _, applier := range appliers {
+		applier.Apply(&opt)
+	}
+	must.BeTrueF(opt.topic != "", "producer topic is required")
+	if opt.balancer == nil {
+		opt.balancer = &kafka.Hash{}
+	}
+	return &opt
+}
+
+func (o *Option) SubOption(appliers ...mq.OptionApplier) *SubOption {
+	opt := *o.defaultSubOption
+	for _, applier := range appliers {
+		applier.Apply(&opt)
+	}
+
+	if len(opt.options.GroupTopics) > 0 {
+		topics := make([]string, 0, len(opt.options.GroupTopics))
+		for _, v := range opt.options.GroupTopics {
+			if len(v) > 0 {
+				topics = append(topics, v)
+			}
+		}
+		opt.options.GroupTopics = topics
+	}
+
+	must.BeTrueF(
+		len(opt.options.Topic) > 0 || len(opt.options.GroupTopics) > 0,
+		"consumer topic is required",
+	)
+	must.BeTrueF(
+		len(opt.options.GroupID) > 0,
+		"consumer group id is required",
+	)
+
+	if opt.worker == 0 {
+		opt.worker = 16
+	}
+	if opt.mode == mq.GlobalOrdered {
+		opt.worker = 1
+	}
+	if opt.bufferSize == 0 {
+		opt.bufferSize = 16
+	}
+	if opt.hasher == nil {
+		opt.hasher = mq.CRC
+	}
+
+	return &opt
+}
+
+type PubOption struct {
+	_initialized bool
+	// callback when async send mode enabled. callback will be called when message
+	// sent completed
+	callback mq.AsyncPubCallback[ProducerMessage]
+	// middlewares wrap publishing, the first is the outermost
+	middlewares []mq.PubMiddleware[ProducerMessage]
+	// disableTracing if trace propagation middleware is not wrapped
+	disableTracing bool
+	// sync decides if writer works in async mode
+	sync bool
+	// topic producer topic
+	topic string
+	// name producer name attached to message header
+	name string
+	// balancer decides which partition message is routed to
+	balancer        kafka.Balancer
+	batchSize       int
+	batchTimeout    time.Duration
+	writeTimeout    time.Duration
+	maxAttempts     int
+	requiredAcks    kafka.RequiredAcks
+	compression     kafka.Compression
+	autoCreateTopic bool
+}
+
+// Writer returns kafka writer without broker address and transport
+func (o *PubOption) Writer() *kafka.Writer {
+	return &kafka.Writer{
+		Balancer:               o.balancer,
+		MaxAttempts:            o.maxAttempts,
+		BatchSize:              o.batchSize,
+		BatchTimeout:           o.batchTimeout,
+		WriteTimeout:           o.writeTimeout,
+		RequiredAcks:           o.requiredAcks,
+		Async:                  !o.sync,
+		Compression:            o.compression,
+		AllowAutoTopicCreation: o.autoCreateTopic,
+	}
+}
+
+func (o *PubOption) Topic() string { return o.topic }
+
+func (*PubOption) OptionScheme() string { return "kafka" }
+
+func (o *PubOption) AddPubMiddleware(middlewares ...mq.PubMiddleware[ProducerMessage]) {
+	o.middlewares = append(slices.Clip(o.middlewares), middlewares...)
+}
+
+func (o *PubOption) DisableTracing() { o.disableTracing = true }
+
+func WithPublishCallback(f mq.AsyncPubCallback[ProducerMessage]) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*PubOption); ok {
+			x.callback = f
+		}
+	})
+}
+
+func WithSyncPublish() mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*PubOption); ok {
+			x.sync = true
+		}
+	})
+}
+
+func WithPubTopic(topic string) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*PubOption); ok {
+			x.topic = topic
+		}
+	})
+}
+
+func WithPublisherName(name string) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*PubOption); ok {
+			x.name = name
+		}
+	})
+}
+
+// WithPubBalancer set partition balancer. default is kafka.Hash which routes
+// messages with the same partition key to the same partition.
+func WithPubBalancer(b kafka.Balancer) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*PubOption); ok {
+			x.balancer = b
+		}
+	})
+}
+
+func WithPubBatchSize(n int) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*PubOption); ok {
+			x.batchSize = n
+		}
+	})
+}
+
+func WithPubBatchTimeout(d time.Duration) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*PubOption); ok {
+			x.batchTimeout = d
+		}
+	})
+}
+
+func WithPubWriteTimeout(d time.Duration) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*PubOption); ok {
+			x.writeTimeout = d
+		}
+	})
+}
+
+func WithPubRequiredAcks(acks kafka.RequiredAcks) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*PubOption); ok {
+			x.requiredAcks = acks
+		}
+	})
+}
+
+func WithPubCompression(c kafka.Compression) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*PubOption); ok {
+			x.compression = c
+		}
+	})
+}
+
+type SubOption struct {
+	_initialized bool
+	// disableAutoAck disable auto ack. if this option is set true, message ack
+	// should be handled by callback.
+	disableAutoAck bool
+	// callback it is called when message handled
+	callback mq.SubCallback[ConsumerMessage]
+	// middlewares wrap handler, the first is the outermost
+	middlewares []mq.SubMiddleware[ConsumerMessage]
+	// disableTracing if trace propagation middleware is not wrapped
+	disableTracing bool
+	// retryNack if enabled NACKed message will be republished
+	retryNack bool
+	// maxRetry max retry times for nack message
+	maxRetry uint32
+	// worker specifies the consumer concurrency level. the default is defined
+	// in Option.WorkerSize (16 by default).
+	worker uint16
+	// bufferSize
+	bufferSize uint16
+	// hasher helps to hash message partition key
+	hasher mq.Hasher
+	// mode consumer handling mode
+	mode mq.ConsumeHandleMode
+	// options kafka reader config. Brokers and Dialer are overwritten by Endpoint
+	options kafka.ReaderConfig
+}
+
+func (*SubOption) OptionScheme() string { return "kafka" }
+
+func (o *SubOption) AddSubMiddleware(middlewares ...mq.SubMiddleware[ConsumerMessage]) {
+	o.middlewares = append(slices.Clip(o.middlewares), middlewares...)
+}
+
+func (o *SubOption) DisableTracing() { o.disableTracing = true }
+
+func (o *SubOption) Options() kafka.ReaderConfig {
+	return o.options
+}
+
+// WithSubDisableAutoAck disables auto ack. if this option is set, message ack
+// should be handled by callback.
+func WithSubDisableAutoAck() mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.disableAutoAck = true
+		}
+	})
+}
+
+// WithSubCallback set subscriber's callback when message is handled.
+func WithSubCallback(f mq.SubCallback[ConsumerMessage]) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.callback = f
+		}
+	})
+}
+
+// WithSubTopic set consuming topics. the first topic is used as consumer group
+// id if it is not set.
+func WithSubTopic(topics ...string) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			if len(topics) > 0 {
+				if x.options.GroupID == "" {
+					x.options.GroupID = topics[0]
+				}
+				if len(topics) == 1 {
+					x.options.Topic = topics[0]
+					x.options.GroupTopics = nil
+				}
+				if len(topics) > 1 {
+					x.options.Topic = ""
+					x.options.GroupTopics = topics
+				}
+			}
+		}
+	})
+}
+
+func WithSubGroupName(name string) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.options.GroupID = name
+		}
+	})
+}
+
+// WithSubStartOffset set start offset when consumer group has no committed
+// offset. it must be kafka.FirstOffset or kafka.LastOffset
+func WithSubStartOffset(offset int64) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.options.StartOffset = offset
+		}
+	})
+}
+
+func WithSubCommitInterval(d time.Duration) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.options.CommitInterval = d
+		}
+	})
+}
+
+func WithSubEnableRetryNack(maxRetry uint32) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.retryNack = true
+			x.maxRetry = maxRetry
+		}
+	})
+}
+
+func WithSubWorkerSize(n uint16) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.worker = n
+		}
+	})
+}
+
+func WithSubWorkerBufferSize(n uint16) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.bufferSize = n
+		}
+	})
+}
+
+func WithSubOrderedKeyHasher(h mq.Hasher) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.hasher = h
+		}
+	})
+}
+
+func WithSubConsumingMode(mode mq.ConsumeHandleMode) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.mode = mode
+		}
+	})
+}
+
+func WithKafkaReaderConfig(config kafka.ReaderConfig) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if o, ok := opt.(*SubOption); ok {
+			o.options = config
+		}
+	})
+}
+
+type (
+	Producer = mq.Producer[ProducerMessage]
+	Consumer = mq.Consumer[ConsumerMessage]
+	Observer = mq.Observer[ConsumerMessage]
+	PubSub   = mq.PubSub[Producer, Consumer]
+)
+
+var (
+	WithSubMiddleware = mq.WithSubMiddleware[ConsumerMessage]
+	WithPubMiddleware = mq.WithPubMiddleware[ProducerMessage]
+)
@@ -0,0 +1,89 @@
+package confkafka_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	. "github.com/xoctopus/x/testx"
+
+	. "github.com/xoctopus/confx/pkg/confkafka"
+	"github.com/xoctopus/confx/pkg/types/mq"
+)
+
+func TestKafkaOption(t *testing.T) {
+	opt := &Option{
+		StartFromLatest: true,
+		DisableCompress: true,
+	}
+	opt.SetDefault()
+
+	t.Run("OptionScheme", func(t *testing.T) {
+		Expect(t, opt.PubOption(WithPubTopic("x")).OptionScheme(), Equal("kafka"))
+		Expect(t, opt.SubOption(WithSubTopic("x")).OptionScheme(), Equal("kafka"))
+	})
+
+	t.Run("RequiredTopic", func(t *testing.T) {
+		ExpectPanic[error](t, func() { opt.PubOption() })
+		ExpectPanic[error](t, func() { opt.SubOption() })
+		ExpectPanic[error](t, func() { opt.SubOption(WithKafkaReaderConfig(kafka.ReaderConfig{Topic: "x"})) })
+	})
+
+	topic := TopicFor(t)
+	po := opt.PubOption(
+		WithPubTopic(topic),
+		WithPublishCallback(func(ProducerMessage, error) {}),
+		WithPublisherName(topic),
+		WithPubBalancer(nil),
+		WithPubBatchSize(1),
+		WithPubBatchTimeout(time.Millisecond),
+		WithPubWriteTimeout(time.Second),
+		WithPubRequiredAcks(kafka.RequireOne),
+		WithPubCompression(kafka.Snappy),
+	)
+	Expect(t, po.Topic(), Equal(topic))
+	w := po.Writer()
+	Expect(t, w.Async, BeTrue())
+	Expect(t, w.BatchSize, Equal(1))
+	Expect(t, w.BatchTimeout, Equal(time.Millisecond))
+	Expect(t, w.WriteTimeout, Equal(time.Second))
+	Expect(t, w.RequiredAcks, Equal(kafka.RequireOne))
+	Expect(t, w.Compression, Equal(kafka.Snappy))
+	Expect(t, w.AllowAutoTopicCreation, BeTrue())
+	Expect(t, w.Balancer, NotBeNil[kafka.Balancer]())
+
+	w = opt.PubOption(WithPubTopic(topic), WithSyncPublish()).Writer()
+	Expect(t, w.Async, BeFalse())
+	Expect(t, w.RequiredAcks, Equal(kafka.RequireAll))
+	Expect(t, w.Compression, Equal(kafka.Compression(0)))
+
+	so := opt.SubOption(
+		WithKafkaReaderConfig(kafka.ReaderConfig{MaxWait: time.Second}),
+		WithSubConsumingMode(mq.PartitionOrdered),
+		WithSubDisableAutoAck(),
+		WithSubCallback(func(mq.Acknowledger[ConsumerMessage], ConsumerMessage, error) {}),
+		WithSubTopic(topic),
+		WithSubCommitInterval(time.Second),
+		WithSubEnableRetryNack(10),
+		WithSubWorkerSize(0),
+		WithSubWorkerBufferSize(0),
+		WithSubOrderedKeyHasher(nil),
+	).Options()
+	Expect(t, so.Topic, Equal(topic))
+	Expect(t, so.GroupID, Equal(topic))
+	Expect(t, so.CommitInterval, Equal(time.Second))
+	Expect(t, so.MaxWait, Equal(time.Second))
+
+	so = opt.SubOption(
+		WithSubTopic(topic, "", topic+"_2"),
+		WithSubGroupName("group"),
+		WithSubStartOffset(kafka.FirstOffset),
+	).Options()
+	Expect(t, so.Topic, Equal(""))
+	Expect(t, so.GroupTopics, Equal([]string{topic, topic + "_2"}))
+	Expect(t, so.GroupID, Equal("group"))
+	Expect(t, so.StartOffset, Equal(kafka.FirstOffset))
+
+	so = opt.SubOption(WithSubTopic(topic)).Options()
+	Expect(t, so.StartOffset, Equal(kafka.LastOffset))
+}
@@ -42,6 +42,14 @@ func (c *LoggerConfig) Close() error {
 	return stop()
 }
 
+// Reload applies Level and Format at runtime. The output, which is decided by
+// Mode, OutputDIR, FileName and Rolling, keeps unchanged until restarted.
+func (c *LoggerConfig) Reload(_ context.Context, _ []string) error {
+	logx.SetLogFormat(c.Format)
+	logx.SetLogLevel(c.Level)
+	return nil
+}
+
 func (c *LoggerConfig) WithContext(ctx context.Context) context.Context {
 	logx.SetLogFormat(c.Format)
 	logx.SetLogLevel(c.Level)
@@ -2,6 +2,7 @@ package conflogx_test
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -107,3 +108,12 @@ func TestLoggerConfig_WithContext(t *testing.T) {
 		Expect(t, string(data), ContainsSubString("before close"))
 	})
 }
+
+func TestLoggerConfig_Reload(t *testing.T) {
+	cfg := &conflogx.LoggerConfig{Level: logx.LogLevelInfo, Format: logx.LogFormatJSON}
+	ctx := cfg.WithContext(context.Background())
+
+	cfg.Level = logx.LogLevelError
+	Expect(t, cfg.Reload(ctx, []string{"Level"}), Succeed())
+	logx.From(ctx).Error(errors.New("reloaded"))
+}
@@ -3,6 +3,7 @@ package metric
 import (
 	"context"
 
+	"go.opentelemetry.io/otel/attribute"
 	otelapimetric "go.opentelemetry.io/otel/metric"
 
 	"github.com/xoctopus/confx/internal/otel/providers"
@@ -114,3 +115,58 @@ func (i *float64Instrument) Record(ctx context.Context, incr float64, options ..
 		c.Record(ctx, incr, options...)
 	}
 }
+
+// NewInt64ObservableGauge returns a gauge observed by callbacks registered by
+// Observe when metrics are collected
+func NewInt64ObservableGauge(name string, appliers ...OptionFunc) *Int64ObservableGauge {
+	return &Int64ObservableGauge{option: NewOption(name, appliers...)}
+}
+
+type Int64ObservableGauge struct {
+	*option
+}
+
+func (g *Int64ObservableGauge) instrument(meter otelapimetric.Meter) (otelapimetric.Int64ObservableGauge, error) {
+	return meter.Int64ObservableGauge(g.Name, otelapimetric.WithUnit(g.Unit), otelapimetric.WithDescription(g.Description))
+}
+
+// Observer observes values of gauges in callback
+type Observer interface {
+	ObserveInt64(g *Int64ObservableGauge, v int64, attrs ...attribute.KeyValue)
+}
+
+// Observe registers callback observes gauges to meter provider in ctx. the
+// registration should be unregistered when the observed target closed.
+func Observe(ctx context.Context, callback func(context.Context, Observer), gauges ...*Int64ObservableGauge) (otelapimetric.Registration, error) {
+	meter := providers.MeterFrom(ctx)
+
+	instruments := make(map[*Int64ObservableGauge]otelapimetric.Int64ObservableGauge, len(gauges))
+	observables := make([]otelapimetric.Observable, 0, len(gauges))
+	for _, g := range gauges {
+		i, err := g.instrument(meter)
+		if err != nil {
+			return nil, err
+		}
+		instruments[g] = i
+		observables = append(observables, i)
+	}
+
+	return meter.RegisterCallback(
+		func(ctx context.Context, o otelapimetric.Observer) error {
+			callback(ctx, &observer{Observer: o, instruments: instruments})
+			return nil
+		},
+		observables...,
+	)
+}
+
+type observer struct {
+	otelapimetric.Observer
+	instruments map[*Int64ObservableGauge]otelapimetric.Int64ObservableGauge
+}
+
+func (o *observer) ObserveInt64(g *Int64ObservableGauge, v int64, attrs ...attribute.KeyValue) {
+	if i, ok := o.instruments[g]; ok {
+		o.Observer.ObserveInt64(i, v, otelapimetric.WithAttributes(attrs...))
+	}
+}
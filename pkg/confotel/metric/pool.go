@@ -0,0 +1,94 @@
+package metric
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelapimetric "go.opentelemetry.io/otel/metric"
+)
+
+// attribute keys of connection pool
+const (
+	// AttrPoolName identifies pool, eg: database name or redis address
+	AttrPoolName = attribute.Key("db.client.connection.pool.name")
+	// AttrPoolRole distinguishes main and readonly pools of an endpoint
+	AttrPoolRole = attribute.Key("db.client.connection.pool.role")
+	// AttrServerAddress address of pool connected to, without auth info
+	AttrServerAddress = attribute.Key("server.address")
+)
+
+// roles of connection pool
+const (
+	POOL_ROLE_MAIN     = "main"
+	POOL_ROLE_READONLY = "readonly"
+)
+
+// gauges of connection pool statistics
+var (
+	PoolConnectionsOpen = NewInt64ObservableGauge(
+		"db.client.connections.open",
+		WithUnit("{connection}"),
+		WithDescription("number of established connections both in use and idle"),
+	)
+	PoolConnectionsInUse = NewInt64ObservableGauge(
+		"db.client.connections.in_use",
+		WithUnit("{connection}"),
+		WithDescription("number of connections currently in use"),
+	)
+	PoolConnectionsIdle = NewInt64ObservableGauge(
+		"db.client.connections.idle",
+		WithUnit("{connection}"),
+		WithDescription("number of idle connections"),
+	)
+	PoolWaitCount = NewInt64ObservableGauge(
+		"db.client.connections.wait_count",
+		WithUnit("{wait}"),
+		WithDescription("total number of connections waited for"),
+	)
+	PoolWaitDuration = NewInt64ObservableGauge(
+		"db.client.connections.wait_duration",
+		WithUnit("ms"),
+		WithDescription("total time blocked waiting for a new connection"),
+	)
+	PoolConnectionsClosedMaxLifetime = NewInt64ObservableGauge(
+		"db.client.connections.closed_max_lifetime",
+		WithUnit("{connection}"),
+		WithDescription("total number of connections closed due to max lifetime or staleness"),
+	)
+)
+
+// PoolStats presents statistics of a connection pool
+type PoolStats struct {
+	Open              int64
+	InUse             int64
+	Idle              int64
+	WaitCount         int64
+	WaitDuration      time.Duration
+	ClosedMaxLifetime int64
+}
+
+// ObservePool registers gauges of pool statistics to meter provider in ctx.
+// stats is called when metrics collected, attrs label the pool, such as
+// AttrPoolName and AttrPoolRole. it takes no effect if ctx carries no meter
+// provider. the registration should be unregistered when pool closed.
+func ObservePool(ctx context.Context, stats func() PoolStats, attrs ...attribute.KeyValue) (otelapimetric.Registration, error) {
+	return Observe(
+		ctx,
+		func(_ context.Context, o Observer) {
+			s := stats()
+			o.ObserveInt64(PoolConnectionsOpen, s.Open, attrs...)
+			o.ObserveInt64(PoolConnectionsInUse, s.InUse, attrs...)
+			o.ObserveInt64(PoolConnectionsIdle, s.Idle, attrs...)
+			o.ObserveInt64(PoolWaitCount, s.WaitCount, attrs...)
+			o.ObserveInt64(PoolWaitDuration, s.WaitDuration.Milliseconds(), attrs...)
+			o.ObserveInt64(PoolConnectionsClosedMaxLifetime, s.ClosedMaxLifetime, attrs...)
+		},
+		PoolConnectionsOpen,
+		PoolConnectionsInUse,
+		PoolConnectionsIdle,
+		PoolWaitCount,
+		PoolWaitDuration,
+		PoolConnectionsClosedMaxLifetime,
+	)
+}
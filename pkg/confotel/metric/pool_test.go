@@ -0,0 +1,73 @@
+package metric_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/xoctopus/x/testx"
+	otelsdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/xoctopus/confx/internal/otel/providers"
+	. "github.com/xoctopus/confx/pkg/confotel/metric"
+)
+
+func collect(t *testing.T, r otelsdkmetric.Reader) map[string]map[string]int64 {
+	t.Helper()
+	rm := metricdata.ResourceMetrics{}
+	Expect(t, r.Collect(context.Background(), &rm), Succeed())
+
+	values := map[string]map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			values[m.Name] = map[string]int64{}
+			for _, dp := range m.Data.(metricdata.Gauge[int64]).DataPoints {
+				role, _ := dp.Attributes.Value(AttrPoolRole)
+				values[m.Name][role.AsString()] = dp.Value
+			}
+		}
+	}
+	return values
+}
+
+func TestObservePool(t *testing.T) {
+	reader := otelsdkmetric.NewManualReader()
+	ctx := providers.WithMetricProvider(
+		context.Background(),
+		otelsdkmetric.NewMeterProvider(otelsdkmetric.WithReader(reader)),
+	)
+
+	main, err := ObservePool(
+		ctx,
+		func() PoolStats {
+			return PoolStats{Open: 3, InUse: 1, Idle: 2, WaitCount: 4, WaitDuration: 2 * time.Second, ClosedMaxLifetime: 5}
+		},
+		AttrPoolName.String("db"), AttrPoolRole.String(POOL_ROLE_MAIN),
+	)
+	Expect(t, err, Succeed())
+	ro, err := ObservePool(
+		ctx,
+		func() PoolStats { return PoolStats{Open: 1, Idle: 1} },
+		AttrPoolName.String("db"), AttrPoolRole.String(POOL_ROLE_READONLY),
+	)
+	Expect(t, err, Succeed())
+
+	values := collect(t, reader)
+	Expect(t, values["db.client.connections.open"], Equal(map[string]int64{"main": 3, "readonly": 1}))
+	Expect(t, values["db.client.connections.in_use"]["main"], Equal(int64(1)))
+	Expect(t, values["db.client.connections.wait_duration"]["main"], Equal(int64(2000)))
+	Expect(t, values["db.client.connections.closed_max_lifetime"]["main"], Equal(int64(5)))
+
+	// unregistered pool is not reported
+	Expect(t, ro.Unregister(), Succeed())
+	values = collect(t, reader)
+	Expect(t, values["db.client.connections.idle"], Equal(map[string]int64{"main": 2}))
+	Expect(t, main.Unregister(), Succeed())
+
+	t.Run("WithoutProvider", func(t *testing.T) {
+		r, err := ObservePool(context.Background(), func() PoolStats { return PoolStats{} })
+		Expect(t, err, Succeed())
+		Expect(t, r.Unregister(), Succeed())
+	})
+}
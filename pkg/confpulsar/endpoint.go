@@ -15,10 +15,12 @@ import (
 	"github.com/oklog/ulid/v2"
 	"github.com/xoctopus/logx"
 	"github.com/xoctopus/x/codex"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
 
 	"github.com/xoctopus/confx/pkg/types"
 	"github.com/xoctopus/confx/pkg/types/liveness"
 	"github.com/xoctopus/confx/pkg/types/mq"
+	"github.com/xoctopus/confx/pkg/types/mq/mqotel"
 )
 
 // Endpoint pulsar component endpoint
@@ -47,7 +49,7 @@ func (e *Endpoint) SetDefault() {
 }
 
 func (e *Endpoint) Init(ctx context.Context) (err error) {
-	if err = e.Endpoint.Init(); err != nil {
+	if err = e.Endpoint.InitByContext(ctx); err != nil {
 		return err
 	}
 
@@ -173,13 +175,21 @@ func (e *Endpoint) NewProducer(ctx context.Context, options ...mq.OptionApplier)
 	if err != nil {
 		return
 	}
+	middlewares := opt.middlewares
+	if !opt.disableTracing {
+		middlewares = append(
+			[]mq.PubMiddleware[ProducerMessage]{mqotel.PubTracing[ProducerMessage]("pulsar")},
+			middlewares...,
+		)
+	}
 
 	x = &producer{
-		cli:      e,
-		pub:      p,
-		log:      logx.NewStd().With("producer", p.Name(), "topic", p.Topic()),
-		sync:     opt.sync,
-		callback: opt.callback,
+		cli:         e,
+		pub:         p,
+		log:         logx.NewStd().With("producer", p.Name(), "topic", p.Topic()),
+		sync:        opt.sync,
+		callback:    opt.callback,
+		middlewares: middlewares,
 	}
 	return x, nil
 }
@@ -211,17 +221,27 @@ func (e *Endpoint) NewConsumer(ctx context.Context, options ...mq.OptionApplier)
 		return nil, err
 	}
 	log = log.With("consumer", c.Name(), "subgroup", c.Subscription())
+	middlewares := opt.middlewares
+	if !opt.disableTracing {
+		middlewares = append(
+			[]mq.SubMiddleware[ConsumerMessage]{
+				mqotel.SubTracing[ConsumerMessage]("pulsar", semconv.MessagingDestinationSubscriptionName(c.Subscription())),
+			},
+			middlewares...,
+		)
+	}
 
 	x = &consumer{
-		sub:        c,
-		cli:        e,
-		log:        logx.NewStd().With("consumer", c.Name(), "subgroup", c.Subscription()),
-		callback:   opt.callback,
-		autoAck:    !opt.disableAutoAck,
-		mode:       opt.mode,
-		worker:     opt.worker,
-		hasher:     opt.hasher,
-		bufferSize: opt.bufferSize,
+		sub:         c,
+		cli:         e,
+		log:         logx.NewStd().With("consumer", c.Name(), "subgroup", c.Subscription()),
+		callback:    opt.callback,
+		middlewares: middlewares,
+		autoAck:     !opt.disableAutoAck,
+		mode:        opt.mode,
+		worker:      opt.worker,
+		hasher:      opt.hasher,
+		bufferSize:  opt.bufferSize,
 	}
 	return x, nil
 }
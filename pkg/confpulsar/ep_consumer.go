@@ -34,6 +34,8 @@ type consumer struct {
 	handler  mq.SubHandler[ConsumerMessage]
 	callback mq.SubCallback[ConsumerMessage]
 
+	middlewares []mq.SubMiddleware[ConsumerMessage]
+
 	cancel  context.CancelCauseFunc
 	autoAck bool
 }
@@ -109,7 +111,7 @@ func (s *consumer) Run(ctx context.Context, h mq.SubHandler[ConsumerMessage]) er
 		s.tasks[i] = make(chan pulsar.Message, s.bufferSize)
 	}
 
-	s.handler = h
+	s.handler = mq.ChainSub(h, s.middlewares...)
 	ctx, s.cancel = context.WithCancelCause(ctx)
 
 	defer func() { _ = s.Close() }()
@@ -21,6 +21,8 @@ type producer struct {
 	pub      pulsar.Producer
 	sync     bool
 	callback mq.AsyncPubCallback[ProducerMessage]
+
+	middlewares []mq.PubMiddleware[ProducerMessage]
 }
 
 func (p *producer) Topic() string {
@@ -38,7 +40,12 @@ func (p *producer) PublishWithKey(ctx context.Context, topic, key string, payloa
 	return msg, p.PublishMessage(ctx, msg)
 }
 
-func (p *producer) PublishMessage(ctx context.Context, msg ProducerMessage) (err error) {
+func (p *producer) PublishMessage(ctx context.Context, msg ProducerMessage) error {
+	return mq.ChainPub(p.send, p.middlewares...)(ctx, msg)
+}
+
+// send publishes msg without middlewares
+func (p *producer) send(ctx context.Context, msg ProducerMessage) (err error) {
 	_, log := logx.Enter(ctx)
 	defer func() {
 		if err != nil {
@@ -145,6 +145,7 @@ type ConsumerMessage interface {
 	mq.HasPartitionKey
 	mq.HasOrderingKey
 	mq.HasPartitionID
+	mq.HasMessageID
 	mq.HasProducer
 	mq.HasPublishedAt
 	mq.HasConsumedAt
@@ -196,6 +197,10 @@ func (x *consumerMessage) PartitionID() int64 {
 	return int64(x.ID().PartitionIdx())
 }
 
+func (x *consumerMessage) MessageID() string {
+	return x.ID().String()
+}
+
 func (x *consumerMessage) ProducedBy() string {
 	return x.ProducerName()
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -266,6 +267,10 @@ type PubOption struct {
 	// callback when async send mode enabled. callback will be called when message
 	// sent completed
 	callback mq.AsyncPubCallback[ProducerMessage]
+	// middlewares wrap publishing, the first is the outermost
+	middlewares []mq.PubMiddleware[ProducerMessage]
+	// disableTracing if trace propagation middleware is not wrapped
+	disableTracing bool
 	// sync decides use Send or SendAsync in pulsar client
 	sync bool
 	// options pulsar producer option
@@ -278,6 +283,12 @@ func (o *PubOption) Options() pulsar.ProducerOptions {
 
 func (*PubOption) OptionScheme() string { return "pulsar" }
 
+func (o *PubOption) AddPubMiddleware(middlewares ...mq.PubMiddleware[ProducerMessage]) {
+	o.middlewares = append(slices.Clip(o.middlewares), middlewares...)
+}
+
+func (o *PubOption) DisableTracing() { o.disableTracing = true }
+
 func WithPublishCallback(f mq.AsyncPubCallback[ProducerMessage]) mq.OptionApplier {
 	return mq.OptionApplyFunc(func(opt mq.Option) {
 		if x, ok := opt.(*PubOption); ok {
@@ -375,6 +386,10 @@ type SubOption struct {
 	disableAutoAck bool
 	// callback it is called when message handled
 	callback mq.SubCallback[ConsumerMessage]
+	// middlewares wrap handler, the first is the outermost
+	middlewares []mq.SubMiddleware[ConsumerMessage]
+	// disableTracing if trace propagation middleware is not wrapped
+	disableTracing bool
 	// handler process mq.Message
 	handler mq.SubHandler[ConsumerMessage]
 	// worker specifies the consumer concurrency level. the default is defined
@@ -392,6 +407,12 @@ type SubOption struct {
 
 func (*SubOption) OptionScheme() string { return "pulsar" }
 
+func (o *SubOption) AddSubMiddleware(middlewares ...mq.SubMiddleware[ConsumerMessage]) {
+	o.middlewares = append(slices.Clip(o.middlewares), middlewares...)
+}
+
+func (o *SubOption) DisableTracing() { o.disableTracing = true }
+
 func (o *SubOption) Options() pulsar.ConsumerOptions {
 	return o.options
 }
@@ -533,3 +554,8 @@ type (
 	Observer = mq.Observer[ConsumerMessage]
 	PubSub   = mq.PubSub[Producer, Consumer]
 )
+
+var (
+	WithSubMiddleware = mq.WithSubMiddleware[ConsumerMessage]
+	WithPubMiddleware = mq.WithPubMiddleware[ProducerMessage]
+)
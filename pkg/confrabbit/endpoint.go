@@ -10,10 +10,12 @@ import (
 	"github.com/wagslane/go-rabbitmq"
 	"github.com/xoctopus/logx"
 	"github.com/xoctopus/x/codex"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
 
 	"github.com/xoctopus/confx/pkg/types"
 	"github.com/xoctopus/confx/pkg/types/liveness"
 	"github.com/xoctopus/confx/pkg/types/mq"
+	"github.com/xoctopus/confx/pkg/types/mq/mqotel"
 )
 
 type Endpoint struct {
@@ -42,7 +44,7 @@ func (e *Endpoint) SetDefault() {
 }
 
 func (e *Endpoint) Init(ctx context.Context) error {
-	if err := e.Endpoint.Init(); err != nil {
+	if err := e.Endpoint.InitByContext(ctx); err != nil {
 		return err
 	}
 
@@ -190,16 +192,24 @@ func (e *Endpoint) NewProducer(ctx context.Context, options ...mq.OptionApplier)
 	if err != nil {
 		return
 	}
+	middlewares := opt.middlewares
+	if !opt.disableTracing {
+		middlewares = append(
+			[]mq.PubMiddleware[ProducerMessage]{mqotel.PubTracing[ProducerMessage]("rabbitmq")},
+			middlewares...,
+		)
+	}
 
 	x = &producer{
-		cli:      e,
-		pub:      p,
-		log:      logx.NewStd().With("topic", opt.topic),
-		topic:    opt.topic,
-		exchange: opt.exchangeName,
-		timeout:  opt.timeout,
-		sync:     opt.sync,
-		callback: opt.callback,
+		cli:         e,
+		pub:         p,
+		log:         logx.NewStd().With("topic", opt.topic),
+		topic:       opt.topic,
+		exchange:    opt.exchangeName,
+		timeout:     opt.timeout,
+		sync:        opt.sync,
+		callback:    opt.callback,
+		middlewares: middlewares,
 	}
 	return x, nil
 }
@@ -229,17 +239,27 @@ func (e *Endpoint) NewConsumer(ctx context.Context, options ...mq.OptionApplier)
 	if err != nil {
 		return nil, err
 	}
+	middlewares := opt.middlewares
+	if !opt.disableTracing {
+		middlewares = append(
+			[]mq.SubMiddleware[ConsumerMessage]{
+				mqotel.SubTracing[ConsumerMessage]("rabbitmq", semconv.MessagingDestinationSubscriptionName(opt.queue)),
+			},
+			middlewares...,
+		)
+	}
 
 	x = &consumer{
-		sub:        c,
-		cli:        e,
-		log:        logx.NewStd().With("queue", opt.queue),
-		callback:   opt.callback,
-		autoAck:    !opt.disableAutoAck,
-		mode:       opt.mode,
-		worker:     opt.worker,
-		hasher:     opt.hasher,
-		bufferSize: opt.bufferSize,
+		sub:         c,
+		cli:         e,
+		log:         logx.NewStd().With("queue", opt.queue),
+		callback:    opt.callback,
+		middlewares: middlewares,
+		autoAck:     !opt.disableAutoAck,
+		mode:        opt.mode,
+		worker:      opt.worker,
+		hasher:      opt.hasher,
+		bufferSize:  opt.bufferSize,
 	}
 
 	return x, nil
@@ -25,6 +25,8 @@ type producer struct {
 	timeout  time.Duration
 	sync     bool
 	callback mq.AsyncPubCallback[ProducerMessage]
+
+	middlewares []mq.PubMiddleware[ProducerMessage]
 }
 
 func (p *producer) Topic() string {
@@ -42,7 +44,12 @@ func (p *producer) PublishWithKey(ctx context.Context, topic, key string, payloa
 	return msg, p.PublishMessage(ctx, msg)
 }
 
-func (p *producer) PublishMessage(ctx context.Context, msg ProducerMessage) (err error) {
+func (p *producer) PublishMessage(ctx context.Context, msg ProducerMessage) error {
+	return mq.ChainPub(p.send, p.middlewares...)(ctx, msg)
+}
+
+// send publishes msg without middlewares
+func (p *producer) send(ctx context.Context, msg ProducerMessage) (err error) {
 	if p.timeout > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, p.timeout)
@@ -146,6 +146,7 @@ type ConsumerMessage interface {
 	mq.HasPayload
 	mq.HasExtra
 	mq.HasPartitionKey
+	mq.HasMessageID
 	mq.HasPublishedAt
 	mq.HasConsumedAt
 	mq.CanRefreshConsumedAt
@@ -203,6 +204,10 @@ func (x *consumerMessage) PartitionKey() string {
 	return v
 }
 
+func (x *consumerMessage) MessageID() string {
+	return x.MessageId
+}
+
 func (x *consumerMessage) PublishedAt() time.Time {
 	return x.Timestamp
 }
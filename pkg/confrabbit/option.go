@@ -1,6 +1,7 @@
 package confrabbit
 
 import (
+	"slices"
 	"time"
 
 	"github.com/wagslane/go-rabbitmq"
@@ -144,9 +145,11 @@ func WithRabbitPublisherOptions(opts ...func(*rabbitmq.PublisherOptions)) mq.Opt
 }
 
 type PubOption struct {
-	topic    string
-	sync     bool
-	callback mq.AsyncPubCallback[ProducerMessage]
+	topic          string
+	sync           bool
+	callback       mq.AsyncPubCallback[ProducerMessage]
+	middlewares    []mq.PubMiddleware[ProducerMessage]
+	disableTracing bool
 
 	exchangeName string
 	exchangeKind string
@@ -158,6 +161,12 @@ type PubOption struct {
 
 func (*PubOption) OptionScheme() string { return "rabbitmq" }
 
+func (o *PubOption) AddPubMiddleware(middlewares ...mq.PubMiddleware[ProducerMessage]) {
+	o.middlewares = append(slices.Clip(o.middlewares), middlewares...)
+}
+
+func (o *PubOption) DisableTracing() { o.disableTracing = true }
+
 func WithSubQueue(queue string) mq.OptionApplier {
 	return mq.OptionApplyFunc(func(opt mq.Option) {
 		if x, ok := opt.(*SubOption); ok {
@@ -255,8 +264,21 @@ type SubOption struct {
 	hasher         mq.Hasher
 	bufferSize     uint16
 	callback       mq.SubCallback[ConsumerMessage]
+	middlewares    []mq.SubMiddleware[ConsumerMessage]
+	disableTracing bool
 	disableAutoAck bool
 	options        []func(options *rabbitmq.ConsumerOptions)
 }
 
 func (*SubOption) OptionScheme() string { return "rabbitmq" }
+
+func (o *SubOption) AddSubMiddleware(middlewares ...mq.SubMiddleware[ConsumerMessage]) {
+	o.middlewares = append(slices.Clip(o.middlewares), middlewares...)
+}
+
+func (o *SubOption) DisableTracing() { o.disableTracing = true }
+
+var (
+	WithSubMiddleware = mq.WithSubMiddleware[ConsumerMessage]
+	WithPubMiddleware = mq.WithPubMiddleware[ProducerMessage]
+)
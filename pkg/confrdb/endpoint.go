@@ -5,8 +5,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"text/tabwriter"
+	"time"
 
 	"github.com/xoctopus/sqlx/pkg/builder"
 	"github.com/xoctopus/sqlx/pkg/frag"
@@ -14,7 +18,11 @@ import (
 	"github.com/xoctopus/sqlx/pkg/migrator"
 	"github.com/xoctopus/sqlx/pkg/session"
 	"github.com/xoctopus/x/flagx"
+	"github.com/xoctopus/x/misc/must"
+	otelapimetric "go.opentelemetry.io/otel/metric"
 
+	"github.com/xoctopus/confx/pkg/confotel/metric"
+	"github.com/xoctopus/confx/pkg/confrdb/migration"
 	"github.com/xoctopus/confx/pkg/confrdb/option"
 	"github.com/xoctopus/confx/pkg/types"
 	"github.com/xoctopus/confx/pkg/types/liveness"
@@ -31,16 +39,25 @@ import (
 //	a serial of database files in a sqlite process
 type endpoint[A any] struct {
 	types.Endpoint[Option[A]]
+	// Readonly endpoint, it is regarded as the first of Replicas
 	Readonly types.Endpoint[Option[A]]
+	// Replicas readonly endpoints. queries of readonly session are balanced
+	// across healthy replicas
+	Replicas []Replica[A]
+	// ReplicaOption balancing and health checking of replicas
+	ReplicaOption ReplicaOption
 
 	// database endpoint string
 	database string
 	// name logic database name
 	name string
 
-	catalog builder.Catalog
-	db      session.Adaptor
-	ro      session.Adaptor
+	catalog    builder.Catalog
+	migrations []*migration.Migration
+	db         session.Adaptor
+	ro         *replicas
+	// registrations of pool statistics gauges
+	registrations []otelapimetric.Registration
 }
 
 type (
@@ -52,6 +69,10 @@ type (
 
 func (d *endpoint[A]) SetDefault() {
 	d.Option.SetDefault()
+	d.ReplicaOption.SetDefault()
+	for i := range d.Replicas {
+		d.Replicas[i].Option.SetDefault()
+	}
 }
 
 // ApplyCatalog should do before endpoint initialization
@@ -67,6 +88,18 @@ func (d *endpoint[A]) ApplyCatalog(name string, catalogs ...builder.Catalog) {
 	session.Register(d.catalog)
 }
 
+// ApplyMigrations registers versioned migrations loaded from sql files in dir of
+// fsys and defined in go. it should do before endpoint initialization. see
+// migration.Load for naming of sql files.
+func (d *endpoint[A]) ApplyMigrations(fsys fs.FS, dir string, migrations ...*migration.Migration) {
+	if fsys != nil {
+		loaded, err := migration.Load(fsys, dir)
+		must.NoErrorF(err, "failed to load migrations from %s", dir)
+		d.migrations = append(d.migrations, loaded...)
+	}
+	d.migrations = append(d.migrations, migrations...)
+}
+
 func (d *endpoint[A]) Init(ctx context.Context) error {
 	if d.db != nil {
 		return nil
@@ -76,7 +109,7 @@ func (d *endpoint[A]) Init(ctx context.Context) error {
 		x.Overwrite()
 	}
 
-	if err := d.Endpoint.Init(); err != nil {
+	if err := d.Endpoint.InitByContext(ctx); err != nil {
 		return fmt.Errorf("failed to init main endpoint: %w", err)
 	}
 
@@ -86,6 +119,10 @@ func (d *endpoint[A]) Init(ctx context.Context) error {
 		}
 	}
 
+	if err := patchParams(&d.Endpoint); err != nil {
+		return err
+	}
+
 	main := d.Endpoint
 	d.database = d.Endpoint.Key()
 	db, err := session.Open(ctx, main.String())
@@ -93,39 +130,103 @@ func (d *endpoint[A]) Init(ctx context.Context) error {
 		return err
 	}
 
-	d.db = db
-	d.Option.Apply(d.db.D())
+	d.Option.Apply(db.D())
+	if err = initConn(ctx, &d.Option, db); err != nil {
+		return errors.Join(err, db.Close())
+	}
+	d.db = instrument(db, &d.Option, main.Scheme(), d.DatabaseName(), metric.POOL_ROLE_MAIN)
 
+	if err = d.initReplicas(ctx); err != nil {
+		return errors.Join(err, d.release())
+	}
+	if err = d.observe(ctx); err != nil {
+		return errors.Join(fmt.Errorf("failed to observe pool stats: %w", err), d.release())
+	}
+	if err = d.LivenessCheck(ctx).FailureReason(); err != nil {
+		return errors.Join(err, d.release())
+	}
+	return nil
+}
+
+// initReplicas opens readonly endpoints and starts checking of replicas.
+// Readonly endpoint reuses options of main endpoint, and each of Replicas is
+// applied with its own options.
+func (d *endpoint[A]) initReplicas(ctx context.Context) error {
+	replicas := d.Replicas
 	if !d.Readonly.IsZero() {
-		if !d.Readonly.IsZero() {
-			if err = d.Readonly.Init(); err != nil {
-				return fmt.Errorf("failed to init readonly endpoint: %w", err)
+		ro := Replica[A]{Endpoint: d.Readonly}
+		ro.Option = d.Option
+		replicas = append([]Replica[A]{ro}, replicas...)
+	}
+	if len(replicas) == 0 {
+		return nil
+	}
+
+	prober, _ := any(&d.Option.AdaptorOption).(option.LagProber)
+	d.ro = newReplicas(d.ReplicaOption, prober, d.db)
+
+	for i := range replicas {
+		ro := replicas[i].Endpoint
+		// reuse main auth, which is already resolved and decrypted
+		if ro.Auth.IsZero() && !hasUserinfo(ro.Address) {
+			ro.Auth = types.Userinfo{
+				Username: d.Endpoint.Auth.Username,
+				Password: d.Endpoint.Auth.Password,
 			}
 		}
-		// readonly endpoint
-		ro := d.Readonly
-		// reuse main configurations
-		if ro.Auth.IsZero() {
-			ro.Auth = main.Auth
+		if err := ro.InitByContext(ctx); err != nil {
+			return fmt.Errorf("failed to init readonly endpoint: %w", err)
 		}
 		ro.AddOption("_ro", "true")
-		db, err = session.Open(ctx, ro.String())
+		if err := patchParams(&ro); err != nil {
+			return err
+		}
+		db, err := session.Open(ctx, ro.String())
 		if err != nil {
 			return err
 		}
-		d.ro = db
-		d.Option.Apply(d.ro.D())
+		ro.Option.Apply(db.D())
+		if err = initConn(ctx, &ro.Option, db); err != nil {
+			return errors.Join(err, db.Close())
+		}
+		d.ro.add(
+			ro.Key(), db,
+			instrument(db, &d.Option, ro.Scheme(), d.DatabaseName(), metric.POOL_ROLE_READONLY),
+			replicas[i].Weight,
+		)
 	}
-	return d.LivenessCheck(ctx).FailureReason()
+	d.ro.start(ctx)
+	return nil
+}
+
+// patchParams adds DSN parameters of adaptor option to endpoint
+func patchParams[A any](ep *types.Endpoint[Option[A]]) error {
+	if x, ok := any(&ep.Option.AdaptorOption).(option.ParamsPatcher); ok {
+		params, err := x.Params()
+		if err != nil {
+			return fmt.Errorf("failed to patch params for adaptor: %w", err)
+		}
+		for k, vs := range params {
+			ep.AddOption(k, vs...)
+		}
+	}
+	return nil
+}
+
+// initConn applies connection scoped settings of adaptor option
+func initConn[A any](ctx context.Context, o *Option[A], db session.Adaptor) error {
+	if x, ok := any(&o.AdaptorOption).(option.ConnInitializer); ok {
+		if err := x.InitConn(ctx, db.D()); err != nil {
+			return fmt.Errorf("failed to init connection: %w", err)
+		}
+	}
+	return nil
 }
 
 func (d *endpoint[A]) LivenessCheck(ctx context.Context) (v liveness.Result) {
 	v = liveness.NewLivenessData()
 
 	db := d.db
-	if d.ro != nil {
-		db = d.ro
-	}
 	if db == nil {
 		v.End(errors.New("store session: lost connection"))
 		return
@@ -137,11 +238,19 @@ func (d *endpoint[A]) LivenessCheck(ctx context.Context) (v liveness.Result) {
 
 func (d *endpoint[A]) Session() session.Session {
 	if d.ro != nil {
-		return session.NewReadonly(d.db, d.ro, d.name)
+		return session.NewReadonly(d.db, d.ro.adaptor(), d.name)
 	}
 	return session.New(d.db, d.name)
 }
 
+// ReplicaStates returns states of readonly replicas
+func (d *endpoint[A]) ReplicaStates() []ReplicaState {
+	if d.ro == nil {
+		return nil
+	}
+	return d.ro.states()
+}
+
 func (d *endpoint[A]) WithContext(ctx context.Context) context.Context {
 	s := d.Session()
 	ctx = session.With(ctx, s)
@@ -191,19 +300,112 @@ func (d *endpoint[A]) Run(ctx context.Context) error {
 			return err
 		}
 	}
+
+	if o.AutoMigration && len(d.migrations) > 0 {
+		if o.DryRun {
+			return d.MigratePlan(ctx, os.Stdout)
+		}
+		return d.MigrateUp(ctx, os.Stdout)
+	}
 	return nil
 }
 
+// Migrator returns migrator of versioned migrations registered by
+// ApplyMigrations
+func (d *endpoint[A]) Migrator() (*migration.Migrator, error) {
+	if d.db == nil {
+		return nil, errors.New("migrator: endpoint is not initialized")
+	}
+	return migration.New(
+		d.db.D(), d.migrations,
+		migration.WithTable(d.Option.MigrationTable),
+		migration.WithDialect(d.Endpoint.Scheme()),
+	)
+}
+
+func (d *endpoint[A]) MigratePlan(ctx context.Context, w io.Writer) error {
+	m, err := d.Migrator()
+	if err != nil {
+		return err
+	}
+	plan, err := m.Plan(ctx)
+	if err != nil {
+		return err
+	}
+	for _, x := range plan {
+		_, _ = fmt.Fprintf(w, "[%s] pending %d_%s\n", d.DatabaseName(), x.Version, x.Name)
+	}
+	return nil
+}
+
+func (d *endpoint[A]) MigrateUp(ctx context.Context, w io.Writer) error {
+	m, err := d.Migrator()
+	if err != nil {
+		return err
+	}
+	done, err := m.Up(ctx)
+	for _, x := range done {
+		_, _ = fmt.Fprintf(w, "[%s] applied %d_%s\n", d.DatabaseName(), x.Version, x.Name)
+	}
+	return err
+}
+
+func (d *endpoint[A]) MigrateDown(ctx context.Context, w io.Writer, steps int) error {
+	m, err := d.Migrator()
+	if err != nil {
+		return err
+	}
+	done, err := m.Down(ctx, steps)
+	for _, x := range done {
+		_, _ = fmt.Fprintf(w, "[%s] rolled back %d_%s\n", d.DatabaseName(), x.Version, x.Name)
+	}
+	return err
+}
+
+func (d *endpoint[A]) MigrateStatus(ctx context.Context, w io.Writer) error {
+	m, err := d.Migrator()
+	if err != nil {
+		return err
+	}
+	states, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "DATABASE\tVERSION\tNAME\tSTATUS\tAPPLIED AT\n")
+	for _, s := range states {
+		status, at := "pending", "-"
+		if s.Applied {
+			status, at = "applied", s.AppliedAt.Format(time.RFC3339)
+		}
+		if s.Modified {
+			status += ",modified"
+		}
+		if s.Missing {
+			status += ",missing"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", d.DatabaseName(), s.Version, s.Name, status, at)
+	}
+	return tw.Flush()
+}
+
+// Close unregisters pool observers, stops checking of replicas and closes all
+// pools. it continues on failure and returns the joined errors.
 func (d *endpoint[A]) Close() error {
+	errs := []error{d.unobserve()}
 	if d.db != nil {
-		if err := d.db.Close(); err != nil {
-			return err
-		}
+		errs = append(errs, d.db.Close())
 	}
 	if d.ro != nil {
-		if err := d.ro.Close(); err != nil {
-			return err
-		}
+		errs = append(errs, d.ro.close())
 	}
-	return nil
+	return errors.Join(errs...)
+}
+
+// release closes resources opened by a failed Init, so that it can be retried
+func (d *endpoint[A]) release() error {
+	err := d.Close()
+	d.db, d.ro = nil, nil
+	return err
 }
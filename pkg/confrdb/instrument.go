@@ -0,0 +1,204 @@
+package confrdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xoctopus/logx"
+	"github.com/xoctopus/sqlx/pkg/frag"
+	"github.com/xoctopus/sqlx/pkg/session"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	otelapimetric "go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/xoctopus/confx/internal/otel/providers"
+	"github.com/xoctopus/confx/pkg/confotel/metric"
+)
+
+// ScopeName is the instrumentation scope of confrdb tracer
+const ScopeName = "github.com/xoctopus/confx/pkg/confrdb"
+
+// operations of instrumented adaptor
+const (
+	OPERATION_QUERY = "query"
+	OPERATION_EXEC  = "exec"
+	OPERATION_TX    = "tx"
+)
+
+var operationDuration = metric.NewFloat64Histogram(
+	"db.client.operation.duration",
+	metric.WithUnit("s"),
+	metric.WithDescription("duration of database client operations"),
+)
+
+// instrumented wraps adaptor. each query, exec and transaction becomes a span
+// and is measured by operationDuration when tracing enabled, statements cost
+// more than slow are logged with args.
+type instrumented struct {
+	session.Adaptor
+
+	name    string
+	attrs   []attribute.KeyValue
+	tracing bool
+	slow    time.Duration
+	// dquoted if double-quoted strings are literals, otherwise identifiers
+	dquoted bool
+}
+
+func instrument[A any](db session.Adaptor, o *Option[A], scheme, name, role string) session.Adaptor {
+	if !o.EnableTracing && o.SlowQueryThreshold <= 0 {
+		return db
+	}
+	return &instrumented{
+		Adaptor: db,
+		name:    name,
+		attrs: []attribute.KeyValue{
+			system(scheme),
+			semconv.DBNamespace(name),
+			metric.AttrPoolRole.String(role),
+		},
+		tracing: o.EnableTracing,
+		slow:    time.Duration(o.SlowQueryThreshold),
+		dquoted: strings.HasPrefix(scheme, "mysql"),
+	}
+}
+
+func (i *instrumented) Query(ctx context.Context, f frag.Fragment) (rows *sql.Rows, err error) {
+	ctx, end := i.start(ctx, OPERATION_QUERY, f)
+	defer func() { end(err) }()
+
+	return i.Adaptor.Query(ctx, f)
+}
+
+func (i *instrumented) Exec(ctx context.Context, f frag.Fragment) (res sql.Result, err error) {
+	ctx, end := i.start(ctx, OPERATION_EXEC, f)
+	defer func() { end(err) }()
+
+	return i.Adaptor.Exec(ctx, f)
+}
+
+func (i *instrumented) Tx(ctx context.Context, exec func(context.Context) error) (err error) {
+	ctx, end := i.start(ctx, OPERATION_TX, nil)
+	defer func() { end(err) }()
+
+	return i.Adaptor.Tx(ctx, exec)
+}
+
+// start starts span of operation and returns function ends it with err
+func (i *instrumented) start(ctx context.Context, operation string, f frag.Fragment) (context.Context, func(error)) {
+	var (
+		started   = time.Now()
+		query     string
+		args      []any
+		span      trace.Span
+		operating = append(slices.Clip(i.attrs), semconv.DBOperationName(operation))
+	)
+	if i.tracing {
+		// statement is rendered only when it is recorded
+		if f != nil {
+			query, args = statement(ctx, f)
+		}
+		attrs := operating
+		if query != "" {
+			attrs = append(attrs, semconv.DBQueryText(sanitize(query, i.dquoted)))
+		}
+		ctx, span = tracer(ctx).Start(
+			ctx, operation+" "+i.name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attrs...),
+		)
+	}
+
+	return ctx, func(err error) {
+		cost := time.Since(started)
+
+		if i.tracing {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				operating = append(operating, semconv.ErrorType(err))
+			}
+			span.End()
+			operationDuration.Record(ctx, cost.Seconds(), otelapimetric.WithAttributes(operating...))
+		}
+
+		if i.slow > 0 && cost >= i.slow && operation != OPERATION_TX {
+			if f != nil && !i.tracing {
+				query, args = statement(ctx, f)
+			}
+			logx.From(ctx).With(
+				"db", i.name,
+				"cost", cost.String(),
+				"statement", query,
+				"args", args,
+			).Warn(fmt.Errorf("slow %s: cost %s exceeds %s", operation, cost, i.slow))
+		}
+	}
+}
+
+func tracer(ctx context.Context) trace.Tracer {
+	tp, ok := providers.TracerProviderFrom(ctx)
+	if !ok || tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(ScopeName)
+}
+
+func system(scheme string) attribute.KeyValue {
+	switch {
+	case strings.HasPrefix(scheme, "mysql"):
+		return semconv.DBSystemNameMySQL
+	case strings.HasPrefix(scheme, "postgres"):
+		return semconv.DBSystemNamePostgreSQL
+	case strings.HasPrefix(scheme, "sqlite"):
+		return semconv.DBSystemNameSQLite
+	default:
+		return semconv.DBSystemNameKey.String(scheme)
+	}
+}
+
+// statement renders f to sql and args
+func statement(ctx context.Context, f frag.Fragment) (string, []any) {
+	x, ok := f.(interface {
+		Frag(context.Context) iter.Seq2[string, []any]
+	})
+	if !ok || f.IsNil() {
+		return "", nil
+	}
+
+	b, args := strings.Builder{}, make([]any, 0)
+	for q, a := range x.Frag(ctx) {
+		b.WriteString(q)
+		args = append(args, a...)
+	}
+	return b.String(), args
+}
+
+var (
+	literals = regexp.MustCompile(`'(?:[^']|'')*'|"(?:[^"]|"")*"|[\w$]*\d[\w.]*`)
+	spaces   = regexp.MustCompile(`\s+`)
+)
+
+// sanitize replaces string and numeric literals in query with `?` to keep
+// sensitive values out of spans. double-quoted strings are masked only when
+// dquoted, they are string literals in mysql but quoted identifiers in postgres
+// and sqlite. values bound as args are never recorded.
+func sanitize(query string, dquoted bool) string {
+	query = literals.ReplaceAllStringFunc(query, func(s string) string {
+		// keep identifiers and placeholders, eg: t_user2, $1, "user"
+		if s[0] == '\'' || s[0] == '"' && dquoted || s[0] >= '0' && s[0] <= '9' {
+			return "?"
+		}
+		return s
+	})
+	return strings.TrimSpace(spaces.ReplaceAllString(query, " "))
+}
@@ -0,0 +1,188 @@
+package confrdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xoctopus/logx"
+	. "github.com/xoctopus/x/testx"
+	"go.opentelemetry.io/otel/codes"
+	otelsdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	otelsdktracer "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+
+	"github.com/xoctopus/sqlx/pkg/frag"
+	"github.com/xoctopus/sqlx/pkg/session"
+
+	"github.com/xoctopus/confx/internal/otel/providers"
+	"github.com/xoctopus/confx/pkg/confotel/metric"
+	"github.com/xoctopus/confx/pkg/types"
+)
+
+func (fakeAdaptor) Tx(ctx context.Context, exec func(context.Context) error) error {
+	return exec(ctx)
+}
+
+func TestSanitize(t *testing.T) {
+	for _, c := range []struct {
+		query   string
+		dquoted bool
+		expect  string
+	}{
+		{"SELECT * FROM t_user2 WHERE id = 1", false, "SELECT * FROM t_user2 WHERE id = ?"},
+		{"SELECT * FROM t_user WHERE name = 'it''s'  AND age > 1.5", false, "SELECT * FROM t_user WHERE name = ? AND age > ?"},
+		{"UPDATE t_user SET name = $1\n\tWHERE id = $2", false, "UPDATE t_user SET name = $1 WHERE id = $2"},
+		{`SELECT * FROM t_user WHERE name = "say ""hi"""`, true, "SELECT * FROM t_user WHERE name = ?"},
+		{`SELECT "user"."id" FROM "user" WHERE "user"."id" = 1`, false, `SELECT "user"."id" FROM "user" WHERE "user"."id" = ?`},
+		{`SELECT "t_2fa"."id" FROM "t_2fa"`, false, `SELECT "t_2fa"."id" FROM "t_2fa"`},
+	} {
+		Expect(t, sanitize(c.query, c.dquoted), Equal(c.expect))
+	}
+
+	o := &Option[any]{EnableTracing: true}
+	for scheme, dquoted := range map[string]bool{"mysql": true, "postgres": false, "sqlite": false} {
+		i := instrument(fakeAdaptor{}, o, scheme, "test", metric.POOL_ROLE_MAIN)
+		Expect(t, i.(*instrumented).dquoted, Equal(dquoted))
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	db := fakeAdaptor{}
+
+	o := &Option[any]{}
+	Expect(t, instrument(db, o, "sqlite", "test", metric.POOL_ROLE_MAIN), Equal[session.Adaptor](db))
+
+	o.EnableTracing = true
+	o.SlowQueryThreshold = types.Duration(-1)
+	i := instrument(db, o, "sqlite", "test", metric.POOL_ROLE_MAIN)
+
+	recorder := tracetest.NewSpanRecorder()
+	ctx := providers.WithTracerProvider(
+		context.Background(),
+		otelsdktracer.NewTracerProvider(otelsdktracer.WithSpanProcessor(recorder)),
+	)
+
+	Expect(t, i.Tx(ctx, func(context.Context) error { return nil }), Succeed())
+	err := i.Tx(ctx, func(context.Context) error { return errors.New("rollback") })
+	Expect(t, err, Failed())
+
+	spans := recorder.Ended()
+	Expect(t, len(spans), Equal(2))
+	Expect(t, spans[0].Name(), Equal("tx test"))
+	Expect(t, spans[0].Status().Code, Equal(codes.Unset))
+	Expect(t, spans[1].Status().Code, Equal(codes.Error))
+}
+
+// counted counts rendering of fragment
+type counted struct {
+	frag.Fragment
+	rendered *int
+}
+
+func (f counted) Frag(ctx context.Context) iter.Seq2[string, []any] {
+	*f.rendered++
+	return f.Fragment.(interface {
+		Frag(context.Context) iter.Seq2[string, []any]
+	}).Frag(ctx)
+}
+
+// warner records warnings with fields
+type warner struct {
+	logx.Logger
+	kvs      []any
+	warnings *[]string
+}
+
+func (w warner) With(kvs ...any) logx.Logger {
+	return warner{Logger: w.Logger, kvs: append(slices.Clip(w.kvs), kvs...), warnings: w.warnings}
+}
+
+func (w warner) Warn(err error) {
+	*w.warnings = append(*w.warnings, fmt.Sprint(append([]any{err.Error()}, w.kvs...)...))
+}
+
+func TestInstrument_Statement(t *testing.T) {
+	var (
+		db       = open(t, "instrument")
+		recorder = tracetest.NewSpanRecorder()
+		reader   = otelsdkmetric.NewManualReader()
+		warnings = make([]string, 0)
+		ctx      = context.Background()
+	)
+	ctx = providers.WithTracerProvider(ctx, otelsdktracer.NewTracerProvider(otelsdktracer.WithSpanProcessor(recorder)))
+	ctx = providers.WithMetricProvider(ctx, otelsdkmetric.NewMeterProvider(otelsdkmetric.WithReader(reader)))
+	ctx = logx.With(ctx, warner{Logger: logx.From(ctx), warnings: &warnings})
+
+	t.Run("Tracing", func(t *testing.T) {
+		i := instrument(db, &Option[any]{EnableTracing: true}, "sqlite", "test", metric.POOL_ROLE_MAIN)
+
+		_, err := i.Exec(ctx, frag.Query("INSERT INTO t_user (id, name) VALUES (1, 'secret')"))
+		Expect(t, err, Succeed())
+		rows, err := i.Query(ctx, frag.Query("SELECT name FROM t_user WHERE id = ?", 1))
+		Expect(t, err, Succeed())
+		Expect(t, rows.Close(), Succeed())
+
+		spans := recorder.Ended()
+		Expect(t, len(spans), Equal(2))
+		Expect(t, spans[0].Name(), Equal("exec test"))
+		Expect(t, spans[1].Name(), Equal("query test"))
+
+		texts := make([]string, 0, 2)
+		for _, span := range spans {
+			for _, kv := range span.Attributes() {
+				if kv.Key == semconv.DBQueryTextKey {
+					texts = append(texts, kv.Value.AsString())
+				}
+			}
+		}
+		Expect(t, texts, Equal([]string{
+			"INSERT INTO t_user (id, name) VALUES (?, ?)",
+			"SELECT name FROM t_user WHERE id = ?",
+		}))
+
+		rm := metricdata.ResourceMetrics{}
+		Expect(t, reader.Collect(ctx, &rm), Succeed())
+		counts := map[string]uint64{}
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				if m.Name != "db.client.operation.duration" {
+					continue
+				}
+				for _, dp := range m.Data.(metricdata.Histogram[float64]).DataPoints {
+					operation, _ := dp.Attributes.Value(semconv.DBOperationNameKey)
+					counts[operation.AsString()] += dp.Count
+				}
+			}
+		}
+		Expect(t, counts, Equal(map[string]uint64{OPERATION_EXEC: 1, OPERATION_QUERY: 1}))
+		Expect(t, len(warnings), Equal(0))
+	})
+
+	t.Run("SlowLog", func(t *testing.T) {
+		rendered := 0
+		f := counted{Fragment: frag.Query("UPDATE t_user SET name = ? WHERE id = ?", "a", 1), rendered: &rendered}
+
+		// statement is rendered by adaptor only if neither traced nor slow
+		i := instrument(db, &Option[any]{SlowQueryThreshold: types.Duration(time.Hour)}, "sqlite", "test", metric.POOL_ROLE_MAIN)
+		_, err := i.Exec(ctx, f)
+		Expect(t, err, Succeed())
+		Expect(t, rendered, Equal(1))
+		Expect(t, len(warnings), Equal(0))
+
+		i = instrument(db, &Option[any]{SlowQueryThreshold: types.Duration(time.Nanosecond)}, "sqlite", "test", metric.POOL_ROLE_MAIN)
+		_, err = i.Exec(ctx, f)
+		Expect(t, err, Succeed())
+		Expect(t, rendered, Equal(3))
+		Expect(t, len(warnings), Equal(1))
+		Expect(t, strings.Contains(warnings[0], "slow exec"), BeTrue())
+		Expect(t, strings.Contains(warnings[0], "UPDATE t_user SET name = ? WHERE id = ?"), BeTrue())
+	})
+}
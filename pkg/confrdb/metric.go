@@ -0,0 +1,62 @@
+package confrdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/xoctopus/confx/pkg/confotel/metric"
+)
+
+func poolStats(db *sql.DB) func() metric.PoolStats {
+	return func() metric.PoolStats {
+		s := db.Stats()
+		return metric.PoolStats{
+			Open:              int64(s.OpenConnections),
+			InUse:             int64(s.InUse),
+			Idle:              int64(s.Idle),
+			WaitCount:         s.WaitCount,
+			WaitDuration:      s.WaitDuration,
+			ClosedMaxLifetime: s.MaxLifetimeClosed,
+		}
+	}
+}
+
+// observe registers gauges of pool statistics of main and readonly replicas to
+// meter provider in ctx.
+func (d *endpoint[A]) observe(ctx context.Context) error {
+	register := func(db *sql.DB, role, addr string) error {
+		r, err := metric.ObservePool(
+			ctx, poolStats(db),
+			metric.AttrPoolName.String(d.DatabaseName()),
+			metric.AttrPoolRole.String(role),
+			metric.AttrServerAddress.String(addr),
+		)
+		if err != nil {
+			return err
+		}
+		d.registrations = append(d.registrations, r)
+		return nil
+	}
+
+	if err := register(d.db.D(), metric.POOL_ROLE_MAIN, d.Endpoint.Key()); err != nil {
+		return err
+	}
+	if d.ro != nil {
+		for _, r := range d.ro.list {
+			if err := register(r.db.D(), metric.POOL_ROLE_READONLY, r.addr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *endpoint[A]) unobserve() error {
+	errs := make([]error, 0, len(d.registrations))
+	for _, r := range d.registrations {
+		errs = append(errs, r.Unregister())
+	}
+	d.registrations = nil
+	return errors.Join(errs...)
+}
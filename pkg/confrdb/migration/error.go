@@ -0,0 +1,14 @@
+package migration
+
+// Error presents error codes of versioned migration
+// +genx:code
+type Error int8
+
+const (
+	ERROR_UNDEFINED          Error = iota
+	ERROR__INVALID_MIGRATION       // invalid migration
+	ERROR__DUPLICATE_VERSION       // duplicate migration version
+	ERROR__CHECKSUM_MISMATCH       // applied migration is modified
+	ERROR__MIGRATION_LOCKED        // migration is locked by another process
+	ERROR__IRREVERSIBLE            // migration is irreversible
+)
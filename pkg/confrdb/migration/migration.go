@@ -0,0 +1,138 @@
+package migration
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/xoctopus/x/codex"
+)
+
+// Func is a migration step implemented in go. it runs in the transaction which
+// records the version.
+type Func func(ctx context.Context, tx *sql.Tx) error
+
+// Migration is a versioned step of schema or data changes. a step is either sql
+// script or go function, sql script is executed as a whole, so driver should
+// support multiple statements (eg: MySQL with multiStatements=true).
+type Migration struct {
+	Version int64
+	Name    string
+
+	UpSQL   string
+	DownSQL string
+	Up      Func
+	Down    Func
+}
+
+// Checksum identifies content of up step. applied migration with different
+// checksum is regarded as modified. go function is identified by name only.
+func (m *Migration) Checksum() string {
+	content := m.UpSQL
+	if m.Up != nil {
+		content = "go:" + m.Name
+	}
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Reversible reports if m can be rolled back
+func (m *Migration) Reversible() bool {
+	return m.Down != nil || strings.TrimSpace(m.DownSQL) != ""
+}
+
+func (m *Migration) up(ctx context.Context, tx *sql.Tx) error {
+	if m.Up != nil {
+		return m.Up(ctx, tx)
+	}
+	_, err := tx.ExecContext(ctx, m.UpSQL)
+	return err
+}
+
+func (m *Migration) down(ctx context.Context, tx *sql.Tx) error {
+	if m.Down != nil {
+		return m.Down(ctx, tx)
+	}
+	_, err := tx.ExecContext(ctx, m.DownSQL)
+	return err
+}
+
+func (m *Migration) validate() error {
+	if m.Version <= 0 {
+		return codex.Errorf(ERROR__INVALID_MIGRATION, "migration: version of %q should be positive", m.Name)
+	}
+	if m.Up == nil && strings.TrimSpace(m.UpSQL) == "" {
+		return codex.Errorf(ERROR__INVALID_MIGRATION, "migration: %d has no up step", m.Version)
+	}
+	if m.Up != nil && m.UpSQL != "" || m.Down != nil && m.DownSQL != "" {
+		return codex.Errorf(ERROR__INVALID_MIGRATION, "migration: %d has both sql and go step", m.Version)
+	}
+	return nil
+}
+
+var filename = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Load reads migrations from sql files in dir of fsys. files are named like
+// {version}_{name}.up.sql and {version}_{name}.down.sql, eg:
+// 20260101000000_create_user.up.sql. other files are ignored. migrations are
+// sorted by version.
+func Load(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := map[int64]*Migration{}
+	for _, entry := range entries {
+		matches := filename.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, codex.Wrap(ERROR__INVALID_MIGRATION, err)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := migrations[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			migrations[version] = m
+		}
+		if m.Name != matches[2] {
+			return nil, codex.Errorf(ERROR__DUPLICATE_VERSION, "migration: %s and %s", m.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			m.UpSQL = string(content)
+		} else {
+			m.DownSQL = string(content)
+		}
+	}
+
+	list := make([]*Migration, 0, len(migrations))
+	for _, m := range migrations {
+		if err = m.validate(); err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	sortByVersion(list)
+	return list, nil
+}
+
+func sortByVersion(migrations []*Migration) {
+	slices.SortFunc(migrations, func(a, b *Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+}
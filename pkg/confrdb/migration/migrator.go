@@ -0,0 +1,399 @@
+package migration
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xoctopus/x/codex"
+)
+
+const (
+	// DefaultTable records applied versions
+	DefaultTable = "schema_migrations"
+	// DefaultLockTTL is the duration after which a lock row is regarded as
+	// stale, eg: left by a crashed process
+	DefaultLockTTL = 10 * time.Minute
+)
+
+// lockRetryInterval is interval of retrying when migration is locked by others
+var lockRetryInterval = 500 * time.Millisecond
+
+type Option func(*Migrator)
+
+// WithTable sets table name records applied versions. lock table is named with
+// suffix `_lock`.
+func WithTable(table string) Option {
+	return func(m *Migrator) {
+		if table != "" {
+			m.table = table
+		}
+	}
+}
+
+// WithDialect sets sql dialect by driver name or url scheme. postgres uses
+// numbered placeholders, others use `?`. sqlite is used by default.
+func WithDialect(dialect string) Option {
+	return func(m *Migrator) {
+		m.dialect = dialect
+		m.numbered = strings.HasPrefix(dialect, "postgres") || dialect == "pgx"
+	}
+}
+
+// WithLockTTL sets the duration after which a lock row is regarded as stale and
+// taken over. it should be longer than the longest migration. default is
+// DefaultLockTTL.
+func WithLockTTL(ttl time.Duration) Option {
+	return func(m *Migrator) {
+		if ttl > 0 {
+			m.ttl = ttl
+		}
+	}
+}
+
+// New creates a Migrator applies migrations to db. migrations may be loaded by
+// Load or defined in go.
+func New(db *sql.DB, migrations []*Migration, appliers ...Option) (*Migrator, error) {
+	m := &Migrator{db: db, table: DefaultTable, ttl: DefaultLockTTL}
+	for _, applier := range appliers {
+		applier(m)
+	}
+
+	host, _ := os.Hostname()
+	m.owner = host + ":" + strconv.Itoa(os.Getpid())
+
+	m.migrations = append(m.migrations, migrations...)
+	sortByVersion(m.migrations)
+	for i, x := range m.migrations {
+		if err := x.validate(); err != nil {
+			return nil, err
+		}
+		if i > 0 && m.migrations[i-1].Version == x.Version {
+			return nil, codex.Errorf(ERROR__DUPLICATE_VERSION, "migration: %d", x.Version)
+		}
+	}
+	return m, nil
+}
+
+// Migrator applies and rolls back versioned migrations. applied versions are
+// recorded with checksums, and executions are serialized by a lock row.
+// Status and Plan are readonly, tables are created when applying or rolling
+// back.
+type Migrator struct {
+	db         *sql.DB
+	table      string
+	dialect    string
+	numbered   bool
+	ttl        time.Duration
+	owner      string
+	migrations []*Migration
+}
+
+// Status presents state of a migration
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Modified reports checksum of applied migration mismatched
+	Modified bool
+	// Missing reports applied migration is not found in sources
+	Missing bool
+}
+
+type record struct {
+	version   int64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Status returns states of migrations ordered by version
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]Status, 0, len(m.migrations))
+	for _, x := range m.migrations {
+		s := Status{Version: x.Version, Name: x.Name}
+		if r, ok := applied[x.Version]; ok {
+			s.Applied, s.AppliedAt = true, r.appliedAt
+			s.Modified = r.checksum != x.Checksum()
+			delete(applied, x.Version)
+		}
+		states = append(states, s)
+	}
+	for _, r := range applied {
+		states = append(states, Status{
+			Version:   r.version,
+			Name:      r.name,
+			Applied:   true,
+			AppliedAt: r.appliedAt,
+			Missing:   true,
+		})
+	}
+	slices.SortFunc(states, func(a, b Status) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return states, nil
+}
+
+// Plan returns pending migrations. ERROR__CHECKSUM_MISMATCH is returned if any
+// applied migration is modified.
+func (m *Migrator) Plan(ctx context.Context) ([]*Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.pending(applied)
+}
+
+// Up applies pending migrations in order and returns applied ones. each
+// migration runs in its own transaction with its version record.
+func (m *Migrator) Up(ctx context.Context) (done []*Migration, err error) {
+	err = m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		pending, err := m.pending(applied)
+		if err != nil {
+			return err
+		}
+		for _, x := range pending {
+			err = m.tx(ctx, func(tx *sql.Tx) error {
+				if err := x.up(ctx, tx); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, m.bind(
+					"INSERT INTO "+m.table+" (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)"),
+					x.Version, x.Name, x.Checksum(), time.Now().UnixMilli(),
+				)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration: failed to apply %d_%s: %w", x.Version, x.Name, err)
+			}
+			done = append(done, x)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the latest steps applied migrations and returns rolled back
+// ones. ERROR__IRREVERSIBLE is returned if any of them has no down step.
+func (m *Migrator) Down(ctx context.Context, steps int) (done []*Migration, err error) {
+	err = m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		rollbacks := make([]*Migration, 0, steps)
+		for i := len(m.migrations) - 1; i >= 0 && len(rollbacks) < steps; i-- {
+			x := m.migrations[i]
+			if _, ok := applied[x.Version]; !ok {
+				continue
+			}
+			if !x.Reversible() {
+				return codex.Errorf(ERROR__IRREVERSIBLE, "migration: %d_%s", x.Version, x.Name)
+			}
+			rollbacks = append(rollbacks, x)
+		}
+
+		for _, x := range rollbacks {
+			err = m.tx(ctx, func(tx *sql.Tx) error {
+				if err := x.down(ctx, tx); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, m.bind("DELETE FROM "+m.table+" WHERE version = ?"), x.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration: failed to roll back %d_%s: %w", x.Version, x.Name, err)
+			}
+			done = append(done, x)
+		}
+		return nil
+	})
+	return done, err
+}
+
+func (m *Migrator) pending(applied map[int64]record) ([]*Migration, error) {
+	pending := make([]*Migration, 0, len(m.migrations))
+	for _, x := range m.migrations {
+		r, ok := applied[x.Version]
+		if !ok {
+			pending = append(pending, x)
+			continue
+		}
+		if r.checksum != x.Checksum() {
+			return nil, codex.Errorf(ERROR__CHECKSUM_MISMATCH, "migration: %d_%s", x.Version, x.Name)
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) init(ctx context.Context) error {
+	for _, q := range []string{
+		"CREATE TABLE IF NOT EXISTS " + m.table + " (" +
+			"version BIGINT NOT NULL PRIMARY KEY, " +
+			"name VARCHAR(255) NOT NULL, " +
+			"checksum VARCHAR(64) NOT NULL, " +
+			"applied_at BIGINT NOT NULL)",
+		"CREATE TABLE IF NOT EXISTS " + m.table + "_lock (" +
+			"id INT NOT NULL PRIMARY KEY, " +
+			"owner VARCHAR(255) NOT NULL, " +
+			"locked_at BIGINT NOT NULL)",
+	} {
+		if _, err := m.db.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exists reports if table of applied versions exists
+func (m *Migrator) exists(ctx context.Context) (bool, error) {
+	var q string
+	switch {
+	case m.numbered, strings.HasPrefix(m.dialect, "duckdb"):
+		q = "SELECT COUNT(1) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?"
+	case strings.HasPrefix(m.dialect, "mysql"):
+		q = "SELECT COUNT(1) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	default:
+		q = "SELECT COUNT(1) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	}
+	n := 0
+	if err := m.db.QueryRowContext(ctx, m.bind(q), m.table).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// applied returns applied versions. all migrations are pending if the table
+// is not created.
+func (m *Migrator) applied(ctx context.Context) (map[int64]record, error) {
+	exists, err := m.exists(ctx)
+	if err != nil || !exists {
+		return map[int64]record{}, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM "+m.table)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	applied := map[int64]record{}
+	for rows.Next() {
+		r, ms := record{}, int64(0)
+		if err = rows.Scan(&r.version, &r.name, &r.checksum, &ms); err != nil {
+			return nil, err
+		}
+		r.appliedAt = time.UnixMilli(ms)
+		applied[r.version] = r
+	}
+	return applied, rows.Err()
+}
+
+// locked runs f holding the lock row. if the lock is held by others, it waits
+// until the lock released or ctx done. a lock row older than lock ttl, which
+// may be left by a crashed process, is taken over.
+func (m *Migrator) locked(ctx context.Context, f func() error) error {
+	if err := m.init(ctx); err != nil {
+		return err
+	}
+
+	for {
+		acquired, err := m.lock(ctx)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return m.holder(context.WithoutCancel(ctx), context.Cause(ctx))
+		case <-time.After(lockRetryInterval):
+		}
+	}
+
+	defer func() {
+		_, _ = m.db.ExecContext(
+			context.WithoutCancel(ctx),
+			m.bind("DELETE FROM "+m.table+"_lock WHERE id = 1 AND owner = ?"),
+			m.owner,
+		)
+	}()
+	return f()
+}
+
+// lock tries to insert the lock row after removing the stale one
+func (m *Migrator) lock(ctx context.Context) (bool, error) {
+	now := time.Now()
+	_, err := m.db.ExecContext(ctx, m.bind(
+		"DELETE FROM "+m.table+"_lock WHERE id = 1 AND locked_at < ?"),
+		now.Add(-m.ttl).UnixMilli(),
+	)
+	if err != nil {
+		return false, err
+	}
+	_, err = m.db.ExecContext(ctx, m.bind(
+		"INSERT INTO "+m.table+"_lock (id, owner, locked_at) VALUES (1, ?, ?)"),
+		m.owner, now.UnixMilli(),
+	)
+	return err == nil, nil
+}
+
+// holder returns ERROR__MIGRATION_LOCKED with the holder of lock
+func (m *Migrator) holder(ctx context.Context, cause error) error {
+	owner, at := "", int64(0)
+	row := m.db.QueryRowContext(ctx, "SELECT owner, locked_at FROM "+m.table+"_lock WHERE id = 1")
+	if err := row.Scan(&owner, &at); err != nil {
+		return errors.Join(codex.Wrap(ERROR__MIGRATION_LOCKED, cause), err)
+	}
+	return codex.Wrapf(
+		ERROR__MIGRATION_LOCKED, cause, "migration: locked by %s at %s",
+		owner, time.UnixMilli(at).Format(time.RFC3339),
+	)
+}
+
+func (m *Migrator) tx(ctx context.Context, f func(*sql.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = f(tx); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	return tx.Commit()
+}
+
+// bind replaces `?` placeholders with numbered ones for postgres
+func (m *Migrator) bind(q string) string {
+	if !m.numbered {
+		return q
+	}
+	b, n := strings.Builder{}, 0
+	for _, c := range q {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
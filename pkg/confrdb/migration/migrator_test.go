@@ -0,0 +1,177 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	. "github.com/xoctopus/x/testx"
+	_ "modernc.org/sqlite"
+
+	. "github.com/xoctopus/confx/pkg/confrdb/migration"
+)
+
+var migrations = fstest.MapFS{
+	"sql/001_create_user.up.sql":   {Data: []byte("CREATE TABLE t_user (id INTEGER PRIMARY KEY, name TEXT);")},
+	"sql/001_create_user.down.sql": {Data: []byte("DROP TABLE t_user;")},
+	"sql/002_seed_user.up.sql":     {Data: []byte("INSERT INTO t_user (id, name) VALUES (1, 'a'); INSERT INTO t_user (id, name) VALUES (2, 'b');")},
+	"sql/002_seed_user.down.sql":   {Data: []byte("DELETE FROM t_user;")},
+	"sql/README.md":                {Data: []byte("ignored")},
+}
+
+func open(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	Expect(t, err, Succeed())
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func count(t *testing.T, db *sql.DB) (n int) {
+	Expect(t, db.QueryRow("SELECT COUNT(1) FROM t_user").Scan(&n), Succeed())
+	return n
+}
+
+func TestLoad(t *testing.T) {
+	list, err := Load(migrations, "sql")
+	Expect(t, err, Succeed())
+	Expect(t, len(list), Equal(2))
+	Expect(t, list[0].Version, Equal(int64(1)))
+	Expect(t, list[1].Name, Equal("seed_user"))
+	Expect(t, list[1].Reversible(), BeTrue())
+
+	_, err = Load(fstest.MapFS{
+		"001_a.up.sql": {Data: []byte("SELECT 1")},
+		"001_b.up.sql": {Data: []byte("SELECT 1")},
+	}, ".")
+	Expect(t, err, IsCodeError(ERROR__DUPLICATE_VERSION))
+
+	_, err = Load(fstest.MapFS{"001_a.down.sql": {Data: []byte("SELECT 1")}}, ".")
+	Expect(t, err, IsCodeError(ERROR__INVALID_MIGRATION))
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db := open(t)
+
+	list, err := Load(migrations, "sql")
+	Expect(t, err, Succeed())
+	seeded := &Migration{
+		Version: 3,
+		Name:    "rename_user",
+		Up: func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "UPDATE t_user SET name = 'c' WHERE id = 2")
+			return err
+		},
+	}
+	m, err := New(db, append(list, seeded))
+	Expect(t, err, Succeed())
+
+	plan, err := m.Plan(ctx)
+	Expect(t, err, Succeed())
+	Expect(t, len(plan), Equal(3))
+
+	states, err := m.Status(ctx)
+	Expect(t, err, Succeed())
+	Expect(t, len(states), Equal(3))
+	Expect(t, states[0].Applied, BeFalse())
+
+	// plan and status do not create tables
+	tables := 0
+	Expect(t, db.QueryRow("SELECT COUNT(1) FROM sqlite_master WHERE type = 'table'").Scan(&tables), Succeed())
+	Expect(t, tables, Equal(0))
+
+	done, err := m.Up(ctx)
+	Expect(t, err, Succeed())
+	Expect(t, len(done), Equal(3))
+	Expect(t, count(t, db), Equal(2))
+
+	// applied migrations are skipped
+	done, err = m.Up(ctx)
+	Expect(t, err, Succeed())
+	Expect(t, len(done), Equal(0))
+
+	states, err = m.Status(ctx)
+	Expect(t, err, Succeed())
+	Expect(t, len(states), Equal(3))
+	Expect(t, states[2].Applied, BeTrue())
+	Expect(t, states[2].Modified, BeFalse())
+
+	t.Run("Irreversible", func(t *testing.T) {
+		_, err := m.Down(ctx, 1)
+		Expect(t, err, IsCodeError(ERROR__IRREVERSIBLE))
+	})
+
+	t.Run("Modified", func(t *testing.T) {
+		modified := *list[1]
+		modified.UpSQL += "\n-- modified"
+		m2, err := New(db, []*Migration{list[0], &modified})
+		Expect(t, err, Succeed())
+
+		_, err = m2.Plan(ctx)
+		Expect(t, err, IsCodeError(ERROR__CHECKSUM_MISMATCH))
+
+		states, err := m2.Status(ctx)
+		Expect(t, err, Succeed())
+		Expect(t, states[1].Modified, BeTrue())
+		Expect(t, states[2].Missing, BeTrue())
+	})
+
+	t.Run("Locked", func(t *testing.T) {
+		_, err := db.Exec("INSERT INTO schema_migrations_lock (id, owner, locked_at) VALUES (1, 'other', ?)", time.Now().UnixMilli())
+		Expect(t, err, Succeed())
+
+		t.Run("Timeout", func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			defer cancel()
+			_, err = m.Up(ctx)
+			Expect(t, err, IsCodeError(ERROR__MIGRATION_LOCKED))
+		})
+
+		t.Run("WaitReleased", func(t *testing.T) {
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				_, _ = db.Exec("DELETE FROM schema_migrations_lock")
+			}()
+			_, err = m.Up(ctx)
+			Expect(t, err, Succeed())
+		})
+
+		t.Run("Stale", func(t *testing.T) {
+			_, err = db.Exec("INSERT INTO schema_migrations_lock (id, owner, locked_at) VALUES (1, 'other', ?)", time.Now().Add(-time.Minute).UnixMilli())
+			Expect(t, err, Succeed())
+
+			m2, err := New(db, list, WithLockTTL(time.Second))
+			Expect(t, err, Succeed())
+			_, err = m2.Up(ctx)
+			Expect(t, err, Succeed())
+
+			n := 0
+			Expect(t, db.QueryRow("SELECT COUNT(1) FROM schema_migrations_lock").Scan(&n), Succeed())
+			Expect(t, n, Equal(0))
+		})
+	})
+
+	t.Run("Down", func(t *testing.T) {
+		m2, err := New(db, list, WithDialect("sqlite"))
+		Expect(t, err, Succeed())
+
+		done, err := m2.Down(ctx, 1)
+		Expect(t, err, Succeed())
+		Expect(t, len(done), Equal(1))
+		Expect(t, done[0].Version, Equal(int64(2)))
+		Expect(t, count(t, db), Equal(0))
+
+		plan, err := m2.Plan(ctx)
+		Expect(t, err, Succeed())
+		Expect(t, len(plan), Equal(1))
+	})
+
+	t.Run("Duplicated", func(t *testing.T) {
+		_, err := New(db, []*Migration{list[0], list[0]})
+		Expect(t, err, IsCodeError(ERROR__DUPLICATE_VERSION))
+	})
+}
@@ -20,6 +20,17 @@ type Option[A any] struct {
 	CreateTableOnly bool `url:"-"`
 	// EnableModelMeta if enable building model meta
 	EnableModelMeta bool `url:"-,default=true"`
+	// MigrationTable records applied versions of versioned migrations. versioned
+	// migrations are applied after catalog migration when AutoMigration enabled,
+	// and only planned when DryRun.
+	MigrationTable string `url:"-,default=schema_migrations"`
+
+	// EnableTracing if enable tracing of statements and transactions. spans and
+	// latency histograms are reported to providers injected by confotel.
+	EnableTracing bool `url:"-"`
+	// SlowQueryThreshold statements cost more than threshold are logged with
+	// args. 0 means slow query logging is disabled.
+	SlowQueryThreshold types.Duration `url:"-"`
 
 	// MaxOpenConns the upper limit on open connections. this should be tuned
 	// based on both application concurrency and database server capacity.
@@ -56,17 +67,12 @@ func (o *Option[A]) SetDefault() {
 	// 	if len(o.Name) == 0 {
 	// 		o.Name = "public"
 	// 	}
-	// case option.SQLite:
-	// 	if len(o.Name) == 0 {
-	// 		o.Name = "main"
-	// 	}
-	// 	// _joural=WAL may set max open to runtime.NumCPU
-	// 	o.MaxOpenConns = 1
-	// 	o.MaxIdleConns = 1
-	// 	o.ConnMaxLifetime = 0
-	// 	o.ConnMaxIdleTime = 0
-	// 	sqlite = true
 	case option.SQLite, option.DuckDB:
+		// file-based database allows a single writer. connections are kept open
+		// to retain connection scoped settings and in-memory database.
+		if o.MaxOpenConns == 0 {
+			o.MaxOpenConns = 1
+		}
 		filebased = true
 	}
 
@@ -1,6 +1,12 @@
 package option
 
-import "crypto/tls"
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"net/url"
+	"time"
+)
 
 type TLSConfigPatcher interface {
 	WithTLS(*tls.Config) error
@@ -9,3 +15,22 @@ type TLSConfigPatcher interface {
 type Overwriter interface {
 	Overwrite()
 }
+
+// ConnInitializer is implemented by adaptor options which apply settings after
+// database opened, eg: SET statements of duckdb
+type ConnInitializer interface {
+	InitConn(context.Context, *sql.DB) error
+}
+
+// ParamsPatcher is implemented by adaptor options which pass connection scoped
+// settings as DSN parameters, so that they are applied to each connection of
+// pool, eg: PRAGMAs of sqlite
+type ParamsPatcher interface {
+	Params() (url.Values, error)
+}
+
+// LagProber is implemented by adaptor options which can probe replication lag
+// of a replica
+type LagProber interface {
+	ReplicationLag(context.Context, *sql.DB) (time.Duration, error)
+}
@@ -1,8 +1,62 @@
 package option
 
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/xoctopus/x/misc/must"
+	"github.com/xoctopus/x/textx"
+)
+
+var accessModes = []string{"AUTOMATIC", "READ_ONLY", "READ_WRITE"}
+
+// DuckDB presents options of duckdb database. AccessMode is passed as DSN
+// parameter, others are applied by SET statements after opened. the duckdb
+// driver is not imported by confrdb and should be registered by application.
+//
+// duckdb allows a single writer process, connections are limited to 1 by
+// default and kept open, so that an in-memory database is retained.
 type DuckDB struct {
+	// AccessMode AUTOMATIC|READ_ONLY|READ_WRITE
+	AccessMode string `url:"access_mode,default=AUTOMATIC"`
+	// Threads number of threads used by duckdb, 0 means number of cpu cores
+	Threads int `url:",default=0"`
+	// MemoryLimit max memory of duckdb, eg: 1GB. empty means 80% of ram
+	MemoryLimit string `url:",default="`
 }
 
 func (o *DuckDB) SetDefault() {
-	panic("not implemented")
+	must.NoErrorV(textx.SetDefault(o))
+
+	if o.AccessMode == "" {
+		o.AccessMode = "AUTOMATIC"
+	}
+	o.AccessMode = strings.ToUpper(o.AccessMode)
+}
+
+// Settings returns SET statements of options
+func (o *DuckDB) Settings() []string {
+	var settings []string
+	if o.Threads > 0 {
+		settings = append(settings, fmt.Sprintf("SET threads = %d", o.Threads))
+	}
+	if o.MemoryLimit != "" {
+		settings = append(settings, fmt.Sprintf("SET memory_limit = '%s'", strings.ReplaceAll(o.MemoryLimit, "'", "")))
+	}
+	return settings
+}
+
+func (o *DuckDB) InitConn(ctx context.Context, db *sql.DB) error {
+	if !slices.Contains(accessModes, o.AccessMode) {
+		return fmt.Errorf("duckdb: invalid access mode %q", o.AccessMode)
+	}
+	for _, s := range o.Settings() {
+		if _, err := db.ExecContext(ctx, s); err != nil {
+			return fmt.Errorf("duckdb: %s: %w", s, err)
+		}
+	}
+	return nil
 }
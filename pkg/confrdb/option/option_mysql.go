@@ -1,7 +1,12 @@
 package option
 
 import (
+	"context"
 	"crypto/tls"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/xoctopus/x/misc/must"
@@ -34,3 +39,48 @@ func (o *MySQL) SetDefault() {
 func (o *MySQL) WithTLS(c *tls.Config) error {
 	return mysql.RegisterTLSConfig(o.TLS, c)
 }
+
+// ReplicationLag returns Seconds_Behind_Source of replica status. lag is 0 if
+// database is not a replica.
+func (o *MySQL) ReplicationLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		// before 8.0.22
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS")
+		if err != nil {
+			return 0, err
+		}
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err = rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+
+	for i, c := range columns {
+		if c != "Seconds_Behind_Source" && c != "Seconds_Behind_Master" {
+			continue
+		}
+		if !values[i].Valid {
+			return 0, errors.New("replication is not running")
+		}
+		seconds, err := strconv.ParseInt(values[i].String, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return 0, errors.New("missing Seconds_Behind_Source in replica status")
+}
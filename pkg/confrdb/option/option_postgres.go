@@ -1,12 +1,30 @@
 package option
 
 import (
+	"context"
+	"database/sql"
+	"time"
+
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
+// Postgres presents options of postgres database. it has no adaptor specific
+// option yet, parameters such as sslmode are passed by endpoint address.
 type Postgres struct {
 }
 
-func (o *Postgres) SetDefault() {
-	panic("not implemented")
+func (o *Postgres) SetDefault() {}
+
+// ReplicationLag returns the replay delay of a standby. lag is 0 if standby has
+// replayed all received wal or database is not in recovery.
+func (o *Postgres) ReplicationLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	seconds := float64(0)
+	err := db.QueryRowContext(ctx, `SELECT CASE
+	WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END`).Scan(&seconds)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
 }
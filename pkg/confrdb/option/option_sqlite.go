@@ -1,12 +1,102 @@
 package option
 
 import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xoctopus/x/misc/must"
+	"github.com/xoctopus/x/textx"
 	_ "modernc.org/sqlite"
+
+	"github.com/xoctopus/confx/pkg/types"
+)
+
+var (
+	journalModes      = []string{"DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"}
+	synchronousLevels = []string{"OFF", "NORMAL", "FULL", "EXTRA"}
 )
 
+// SQLite presents options of sqlite database. Mode and Cache are passed as
+// sqlite URI parameters, others are passed as `_pragma` parameters, which are
+// applied by driver to each connection of pool.
+//
+// sqlite allows a single writer, connections are limited to 1 by default and
+// kept open, so that an in-memory database is retained.
 type SQLite struct {
+	// JournalMode DELETE|TRUNCATE|PERSIST|MEMORY|WAL|OFF. WAL allows readers
+	// not blocked by writer.
+	JournalMode string `url:",default=WAL"`
+	// BusyTimeout duration of waiting when database is locked
+	BusyTimeout types.Duration `url:",default=5s"`
+	// DisableForeignKeys disables foreign key constraints, which are enabled by
+	// default
+	DisableForeignKeys bool `url:""`
+	// Synchronous OFF|NORMAL|FULL|EXTRA. NORMAL is safe with WAL
+	Synchronous string `url:",default=NORMAL"`
+	// CacheSize page cache size. positive value is number of pages and
+	// negative value is size in KiB
+	CacheSize int `url:",default=-2000"`
+	// Mode ro|rw|rwc|memory. memory opens an in-memory database
+	Mode string `url:"mode,default=rwc"`
+	// Cache private|shared. shared cache makes an in-memory database shared by
+	// connections of the same name
+	Cache string `url:"cache,default=private"`
 }
 
 func (o *SQLite) SetDefault() {
-	panic("not implemented")
+	must.NoErrorV(textx.SetDefault(o))
+
+	if o.JournalMode == "" {
+		o.JournalMode = "WAL"
+	}
+	if o.BusyTimeout == 0 {
+		o.BusyTimeout = types.Duration(5 * time.Second)
+	}
+	if o.Synchronous == "" {
+		o.Synchronous = "NORMAL"
+	}
+	if o.CacheSize == 0 {
+		o.CacheSize = -2000
+	}
+	if o.Mode == "" {
+		o.Mode = "rwc"
+	}
+	if o.Cache == "" {
+		o.Cache = "private"
+	}
+	o.JournalMode = strings.ToUpper(o.JournalMode)
+	o.Synchronous = strings.ToUpper(o.Synchronous)
+}
+
+// InMemory reports if database is in memory
+func (o *SQLite) InMemory() bool {
+	return o.Mode == "memory"
+}
+
+// Pragmas returns PRAGMAs of options as `name(value)`
+func (o *SQLite) Pragmas() []string {
+	fk := "ON"
+	if o.DisableForeignKeys {
+		fk = "OFF"
+	}
+	return []string{
+		fmt.Sprintf("busy_timeout(%d)", time.Duration(o.BusyTimeout).Milliseconds()),
+		fmt.Sprintf("journal_mode(%s)", o.JournalMode),
+		fmt.Sprintf("synchronous(%s)", o.Synchronous),
+		fmt.Sprintf("foreign_keys(%s)", fk),
+		fmt.Sprintf("cache_size(%d)", o.CacheSize),
+	}
+}
+
+func (o *SQLite) Params() (url.Values, error) {
+	if !slices.Contains(journalModes, o.JournalMode) {
+		return nil, fmt.Errorf("sqlite: invalid journal mode %q", o.JournalMode)
+	}
+	if !slices.Contains(synchronousLevels, o.Synchronous) {
+		return nil, fmt.Errorf("sqlite: invalid synchronous level %q", o.Synchronous)
+	}
+	return url.Values{"_pragma": o.Pragmas()}, nil
 }
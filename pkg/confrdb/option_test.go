@@ -1,6 +1,9 @@
 package confrdb_test
 
 import (
+	"context"
+	"database/sql"
+	"path/filepath"
 	"testing"
 
 	. "github.com/xoctopus/x/testx"
@@ -8,6 +11,7 @@ import (
 
 	"github.com/xoctopus/confx/pkg/confrdb"
 	"github.com/xoctopus/confx/pkg/confrdb/option"
+	"github.com/xoctopus/confx/pkg/types"
 )
 
 func TestOption(t *testing.T) {
@@ -23,3 +27,93 @@ func TestOption(t *testing.T) {
 
 	Expect(t, uv1.Encode(), Equal(uv2.Encode()))
 }
+
+func TestOption_FileBased(t *testing.T) {
+	t.Run("SQLite", func(t *testing.T) {
+		o := &confrdb.Option[option.SQLite]{}
+		o.SetDefault()
+		Expect(t, o.MaxOpenConns, Equal(1))
+		Expect(t, o.MaxIdleConns, Equal(1))
+		Expect(t, o.ConnMaxLifetime, Equal(types.Duration(0)))
+		Expect(t, o.ConnMaxIdleTime, Equal(types.Duration(0)))
+
+		x := o.AdaptorOption
+		Expect(t, x.JournalMode, Equal("WAL"))
+		Expect(t, x.Synchronous, Equal("NORMAL"))
+		Expect(t, x.InMemory(), BeFalse())
+		Expect(t, x.Pragmas(), Equal([]string{
+			"busy_timeout(5000)",
+			"journal_mode(WAL)",
+			"synchronous(NORMAL)",
+			"foreign_keys(ON)",
+			"cache_size(-2000)",
+		}))
+
+		params, err := x.Params()
+		Expect(t, err, Succeed())
+		o.MaxOpenConns = 2
+		db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db")+"?"+params.Encode())
+		Expect(t, err, Succeed())
+		defer db.Close()
+		o.Apply(db)
+
+		// pragmas are applied to each connection of pool
+		conns := make([]*sql.Conn, 0, 2)
+		for range 2 {
+			conn, err := db.Conn(context.Background())
+			Expect(t, err, Succeed())
+			defer conn.Close()
+			conns = append(conns, conn)
+		}
+		for _, conn := range conns {
+			mode, fk := "", 0
+			Expect(t, conn.QueryRowContext(context.Background(), "PRAGMA journal_mode").Scan(&mode), Succeed())
+			Expect(t, conn.QueryRowContext(context.Background(), "PRAGMA foreign_keys").Scan(&fk), Succeed())
+			Expect(t, mode, Equal("wal"))
+			Expect(t, fk, Equal(1))
+		}
+
+		x.JournalMode = "INVALID;"
+		_, err = x.Params()
+		Expect(t, err, Failed())
+	})
+
+	t.Run("SQLiteDisableForeignKeys", func(t *testing.T) {
+		o := &confrdb.Option[option.SQLite]{}
+		o.AdaptorOption.DisableForeignKeys = true
+		o.SetDefault()
+		Expect(t, o.AdaptorOption.Pragmas()[3], Equal("foreign_keys(OFF)"))
+
+		params, err := o.AdaptorOption.Params()
+		Expect(t, err, Succeed())
+		db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db")+"?"+params.Encode())
+		Expect(t, err, Succeed())
+		defer db.Close()
+
+		fk := 1
+		Expect(t, db.QueryRow("PRAGMA foreign_keys").Scan(&fk), Succeed())
+		Expect(t, fk, Equal(0))
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		o := &confrdb.Option[option.Postgres]{}
+		o.SetDefault()
+		_, err := textx.MarshalURL(o)
+		Expect(t, err, Succeed())
+	})
+
+	t.Run("DuckDB", func(t *testing.T) {
+		o := &confrdb.Option[option.DuckDB]{}
+		o.MaxOpenConns = 4
+		o.AdaptorOption.Threads = 2
+		o.AdaptorOption.MemoryLimit = "1GB"
+		o.SetDefault()
+		Expect(t, o.MaxOpenConns, Equal(4))
+		Expect(t, o.MaxIdleConns, Equal(4))
+		Expect(t, o.AdaptorOption.AccessMode, Equal("AUTOMATIC"))
+		Expect(t, o.AdaptorOption.Settings(), Equal([]string{
+			"SET threads = 2",
+			"SET memory_limit = '1GB'",
+		}))
+	})
+}
@@ -0,0 +1,65 @@
+package outbox
+
+import (
+	"github.com/xoctopus/sqlx/pkg/builder"
+	"github.com/xoctopus/sqlx/pkg/types"
+)
+
+// Catalog includes outbox tables. it should be applied to endpoint with
+// business catalogs, so that messages can be enqueued in business transactions
+//
+//	ep.ApplyCatalog("name", models.Catalog, outbox.Catalog)
+var Catalog = builder.NewCatalog()
+
+// Status presents publishing status of outbox message
+type Status uint8
+
+const (
+	// Pending message is waiting for publishing or retrying
+	Pending Status = iota + 1
+	// Published message is published to producer
+	Published
+	// Dead message reached max publishing attempts
+	Dead
+)
+
+// Message outbox message enqueued in business transaction
+// +genx:model
+// @model TableName=t_outbox_message
+// @model Register=Catalog
+// @model pk=ID
+// @model idx=i_status;Status
+// @model idx=i_partition_key;PartitionKey
+// @model idx=i_created_at;CreatedAt
+type Message struct {
+	types.Serial
+
+	MessageMeta
+	MessageState
+
+	types.CreationModificationTime
+}
+
+type MessageMeta struct {
+	// Topic publishing topic
+	Topic string `db:"topic,width=255" json:"topic"`
+	// PartitionKey messages with the same partition key are published in order
+	PartitionKey string `db:"partition_key,width=255,default=''" json:"partitionKey"`
+	// Payload message payload
+	Payload []byte `db:"payload" json:"payload"`
+	// Extra json encoded message extra
+	Extra []byte `db:"extra" json:"extra"`
+}
+
+type MessageState struct {
+	// Status publishing status
+	Status Status `db:"status" json:"status"`
+	// Attempts publishing attempts
+	Attempts uint32 `db:"attempts,default=0" json:"attempts"`
+	// NextAttemptAt unix milliseconds of next publishing attempt
+	NextAttemptAt int64 `db:"next_attempt_at,default=0" json:"nextAttemptAt"`
+	// PublishedAt unix milliseconds when message published
+	PublishedAt int64 `db:"published_at,default=0" json:"publishedAt"`
+	// LastError error of the last publishing attempt
+	LastError string `db:"last_error,width=1024,default=''" json:"lastError"`
+}
@@ -0,0 +1,76 @@
+// Package outbox implements transactional outbox backed by confrdb.
+//
+// Messages are enqueued to the outbox table in the same transaction with
+// business changes, and Relay publishes pending messages to an mq.Producer
+// after the transaction committed. So that a crash between database writing
+// and publishing neither loses nor publishes phantom messages, and consumers
+// should be idempotent because a message may be published more than once.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/xoctopus/sqlx/pkg/session"
+
+	"github.com/xoctopus/confx/pkg/types/mq"
+)
+
+// Publishable presents message can be enqueued to outbox. ProducerMessage of
+// mq drivers satisfies it.
+type Publishable interface {
+	mq.HasTopic
+	mq.HasPayload
+	mq.HasExtra
+	mq.HasPartitionKey
+}
+
+// NewMessage converts m to pending outbox Message
+func NewMessage[M Publishable](m M) (*Message, error) {
+	x := &Message{
+		MessageMeta: MessageMeta{
+			Topic:        m.Topic(),
+			PartitionKey: m.PartitionKey(),
+			Payload:      m.Payload(),
+		},
+		MessageState: MessageState{
+			Status: Pending,
+		},
+	}
+	if extra := m.Extra(); len(extra) > 0 {
+		data, err := json.Marshal(extra)
+		if err != nil {
+			return nil, err
+		}
+		x.Extra = data
+	}
+	return x, nil
+}
+
+// Enqueue inserts messages to outbox. ctx should carry session of the endpoint
+// which Catalog applied to. If ctx is in a transaction, messages are committed
+// or rolled back with business changes, eg:
+//
+//	session.MustFor(ctx, models.TOrder).Adaptor().Tx(ctx, func(ctx context.Context) error {
+//		if err := order.Create(ctx); err != nil {
+//			return err
+//		}
+//		return outbox.Enqueue(ctx, confkafka.NewProducerMessage("orders", payload))
+//	})
+func Enqueue[M Publishable](ctx context.Context, messages ...M) error {
+	return session.MustFor(ctx, TMessage).Adaptor().Tx(
+		ctx,
+		func(ctx context.Context) error {
+			for _, m := range messages {
+				x, err := NewMessage(m)
+				if err != nil {
+					return err
+				}
+				if err = x.Create(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
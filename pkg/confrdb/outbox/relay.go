@@ -0,0 +1,265 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/xoctopus/logx"
+	"github.com/xoctopus/sqlx/pkg/builder"
+	"github.com/xoctopus/sqlx/pkg/session"
+	"github.com/xoctopus/x/misc/must"
+	"github.com/xoctopus/x/textx"
+
+	"github.com/xoctopus/confx/pkg/types"
+	"github.com/xoctopus/confx/pkg/types/mq"
+)
+
+// RelayMessage presents producer message can be restored from outbox Message
+type RelayMessage interface {
+	mq.CanAppendExtra
+	mq.CanSetPartitionKey
+}
+
+// NewRelay creates Relay publishes outbox messages by p. f creates producer
+// message by topic and payload, it is usually NewProducerMessage of driver.
+func NewRelay[PM RelayMessage](p mq.Producer[PM], f func(topic string, payload []byte) PM) *Relay[PM] {
+	r := &Relay[PM]{producer: p, convert: f}
+	r.SetDefault()
+	return r
+}
+
+// Relay polls pending outbox messages in enqueued order and publishes them to
+// producer. Messages with the same partition key are published in order, a
+// message failed to publish blocks the following messages with the same key
+// until it is published or dead. Relays of an outbox can be run concurrently,
+// messages are claimed before publishing.
+type Relay[PM RelayMessage] struct {
+	// Interval polling interval when no message published
+	Interval types.Duration `url:",default=1s"`
+	// BatchSize max pending messages fetched for each polling
+	BatchSize uint16 `url:",default=100"`
+	// MaxAttempts message failed to publish MaxAttempts times is marked as Dead
+	MaxAttempts uint32 `url:",default=10"`
+	// Backoff delay of the first retrying, it is doubled for each attempt
+	Backoff types.Duration `url:",default=1s"`
+	// MaxBackoff max delay of retrying
+	MaxBackoff types.Duration `url:",default=5m"`
+	// ClaimLease duration a message is leased to the relay claimed it. it should
+	// be longer than publishing a message
+	ClaimLease types.Duration `url:",default=30s"`
+	// DeletePublished if enabled published messages are deleted from outbox,
+	// otherwise they are marked as Published
+	DeletePublished bool `url:",default=false"`
+
+	producer mq.Producer[PM]
+	convert  func(string, []byte) PM
+}
+
+var _ types.Runnable = (*Relay[RelayMessage])(nil)
+
+func (r *Relay[PM]) SetDefault() {
+	must.NoErrorV(textx.SetDefault(r))
+
+	if r.Interval <= 0 {
+		r.Interval = types.Duration(time.Second)
+	}
+	if r.BatchSize == 0 {
+		r.BatchSize = 100
+	}
+	if r.MaxAttempts == 0 {
+		r.MaxAttempts = 10
+	}
+	if r.Backoff <= 0 {
+		r.Backoff = types.Duration(time.Second)
+	}
+	if r.MaxBackoff < r.Backoff {
+		r.MaxBackoff = max(r.Backoff, types.Duration(5*time.Minute))
+	}
+	if r.ClaimLease <= 0 {
+		r.ClaimLease = types.Duration(30 * time.Second)
+	}
+}
+
+// Run polls and publishes outbox messages until ctx done. ctx should carry
+// session of the endpoint which Catalog applied to.
+func (r *Relay[PM]) Run(ctx context.Context) error {
+	must.BeTrueF(r.producer != nil && r.convert != nil, "relay producer is required")
+
+	log := logx.From(ctx).With("component", "outbox_relay")
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+
+		n, err := r.Poll(ctx)
+		if err != nil {
+			log.Error(err)
+		}
+		if n > 0 && err == nil {
+			timer.Reset(0)
+		} else {
+			timer.Reset(time.Duration(r.Interval))
+		}
+	}
+}
+
+// Poll publishes a batch of due messages and returns count of published.
+// messages of partitions blocked by a message waiting for retrying are skipped
+// to keep them in order. each message is claimed before publishing, so that
+// relays polling concurrently never publish the same message.
+func (r *Relay[PM]) Poll(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	pending, err := (&Message{}).List(
+		ctx,
+		builder.And(
+			TMessage.Status.AsCond(builder.Eq(Pending)),
+			TMessage.NextAttemptAt.AsCond(builder.Lte(now.UnixMilli())),
+		),
+		builder.Additions{
+			builder.OrderBy(builder.AscOrder(TMessage.ID)),
+			builder.Limit(int64(r.BatchSize)),
+		},
+	)
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+
+	waiting, err := (&Message{}).List(
+		ctx,
+		builder.And(
+			TMessage.Status.AsCond(builder.Eq(Pending)),
+			TMessage.NextAttemptAt.AsCond(builder.Gt(now.UnixMilli())),
+			TMessage.PartitionKey.AsCond(builder.Neq("")),
+		),
+		nil,
+		TMessage.PartitionKey,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		blocked   = make(map[string]struct{})
+		published = 0
+	)
+	for i := range waiting {
+		blocked[waiting[i].PartitionKey] = struct{}{}
+	}
+	for i := range pending {
+		m := &pending[i]
+		if _, ok := blocked[m.PartitionKey]; ok && m.PartitionKey != "" {
+			continue
+		}
+
+		claimed, err := r.claim(ctx, m)
+		if err != nil {
+			return published, err
+		}
+		if !claimed {
+			blocked[m.PartitionKey] = struct{}{}
+			continue
+		}
+
+		if err = r.publish(ctx, m); err != nil {
+			if err = r.retry(ctx, m, err); err != nil {
+				return published, err
+			}
+			if m.Status == Pending {
+				blocked[m.PartitionKey] = struct{}{}
+			}
+			continue
+		}
+		if err = r.published(ctx, m); err != nil {
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
+
+// claim leases m to the relay by postponing its next attempt by ClaimLease. it
+// reports false if m is claimed by others. a message claimed by a crashed relay
+// is published again after the lease expired.
+func (r *Relay[PM]) claim(ctx context.Context, m *Message) (bool, error) {
+	attempt := m.NextAttemptAt
+	m.NextAttemptAt = time.Now().Add(time.Duration(r.ClaimLease)).UnixMilli()
+
+	res, err := session.MustFor(ctx, TMessage).Adaptor().Exec(
+		ctx,
+		builder.Update(TMessage).
+			Set(TMessage.AssignmentFor(m, TMessage.NextAttemptAt)).
+			Where(
+				builder.And(
+					TMessage.ID.AsCond(builder.Eq(m.ID)),
+					TMessage.Status.AsCond(builder.Eq(Pending)),
+					TMessage.NextAttemptAt.AsCond(builder.Eq(attempt)),
+				),
+				builder.Comment("Relay.claim"),
+			),
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *Relay[PM]) publish(ctx context.Context, m *Message) error {
+	x := r.convert(m.Topic, m.Payload)
+	if m.PartitionKey != "" {
+		x.SetPartitionKey(m.PartitionKey)
+	}
+	if len(m.Extra) > 0 {
+		extra := make(map[string]string)
+		if err := json.Unmarshal(m.Extra, &extra); err != nil {
+			return err
+		}
+		for k, v := range extra {
+			x.AddExtra(k, v)
+		}
+	}
+	return r.producer.PublishMessage(ctx, x)
+}
+
+func (r *Relay[PM]) published(ctx context.Context, m *Message) error {
+	if r.DeletePublished {
+		return m.DeleteByID(ctx)
+	}
+	m.Attempts++
+	m.Status = Published
+	m.PublishedAt = time.Now().UnixMilli()
+	m.LastError = ""
+	return m.UpdateByID(ctx, TMessage.Status, TMessage.Attempts, TMessage.PublishedAt, TMessage.LastError)
+}
+
+// retry records cause and schedules next attempt with exponential backoff, or
+// marks m as Dead if reached MaxAttempts
+func (r *Relay[PM]) retry(ctx context.Context, m *Message, cause error) error {
+	m.Attempts++
+	m.LastError = cause.Error()
+	if len(m.LastError) > 1024 {
+		m.LastError = m.LastError[:1024]
+	}
+	if m.Attempts >= r.MaxAttempts {
+		m.Status = Dead
+	} else {
+		m.NextAttemptAt = time.Now().Add(r.backoff(m.Attempts)).UnixMilli()
+	}
+	logx.From(ctx).With("id", m.ID, "topic", m.Topic, "attempts", m.Attempts, "status", m.Status).Warn(cause)
+	return m.UpdateByID(ctx, TMessage.Status, TMessage.Attempts, TMessage.NextAttemptAt, TMessage.LastError)
+}
+
+func (r *Relay[PM]) backoff(attempts uint32) time.Duration {
+	d := time.Duration(r.Backoff)
+	for i := uint32(1); i < attempts && d < time.Duration(r.MaxBackoff); i++ {
+		d *= 2
+	}
+	return min(d, time.Duration(r.MaxBackoff))
+}
@@ -0,0 +1,147 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/xoctopus/sqlx/pkg/builder"
+	"github.com/xoctopus/sqlx/pkg/session"
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/hack"
+	. "github.com/xoctopus/confx/pkg/confrdb/outbox"
+	"github.com/xoctopus/confx/pkg/types"
+	"github.com/xoctopus/confx/pkg/types/mq"
+	"github.com/xoctopus/confx/pkg/types/mq/memq"
+)
+
+func TestOutbox(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Minute)
+	defer cancel()
+
+	ep, err := hack.WithMySQL(ctx, t, "mysql://root@localhost:13306/test", Catalog)
+	Expect(t, err, Succeed())
+	Expect(t, ep.Run(ctx), Succeed())
+	ctx = ep.WithContext(ctx)
+
+	b := memq.NewBroker()
+	defer b.Close()
+
+	t.Run("Enqueue", func(t *testing.T) {
+		m := memq.NewProducerMessage("orders", []byte("created"))
+		m.SetPartitionKey("order_1")
+		m.AddExtra("tenant", "t1")
+		Expect(t, Enqueue(ctx, m), Succeed())
+
+		err = session.MustFor(ctx, TMessage).Adaptor().Tx(ctx, func(ctx context.Context) error {
+			if err := Enqueue(ctx, memq.NewProducerMessage("orders", []byte("rollback"))); err != nil {
+				return err
+			}
+			return errors.New("rollback")
+		})
+		Expect(t, err, Failed())
+
+		count, err := (&Message{}).Count(ctx, TMessage.Topic.AsCond(builder.Eq("orders")))
+		Expect(t, err, Succeed())
+		Expect(t, count, Equal(int64(1)))
+	})
+
+	t.Run("Relay", func(t *testing.T) {
+		p, err := b.NewProducer(ctx, memq.WithPubTopic("relayed"))
+		Expect(t, err, Succeed())
+
+		for i := range 6 {
+			m := memq.NewProducerMessage("relayed", fmt.Appendf(nil, "%d", i))
+			m.SetPartitionKey(fmt.Sprintf("key%d", i%2))
+			Expect(t, Enqueue(ctx, m), Succeed())
+		}
+
+		r := NewRelay(p, memq.NewProducerMessage)
+		r.DeletePublished = true
+		n, err := r.Poll(ctx)
+		Expect(t, err, Succeed())
+		Expect(t, n, Equal(6))
+
+		messages := b.Messages("relayed")
+		Expect(t, messages, HaveLen[[]memq.ConsumerMessage](6))
+		for i, m := range messages {
+			Expect(t, m.Payload(), Equal(fmt.Appendf(nil, "%d", i)))
+			Expect(t, m.PartitionKey(), Equal(fmt.Sprintf("key%d", i%2)))
+		}
+
+		count, err := (&Message{}).Count(ctx, TMessage.Topic.AsCond(builder.Eq("relayed")))
+		Expect(t, err, Succeed())
+		Expect(t, count, Equal(int64(0)))
+	})
+
+	t.Run("RetryAndDead", func(t *testing.T) {
+		failed := errors.New("broker unavailable")
+		p, err := b.NewProducer(ctx, memq.WithPubTopic("failed"), memq.WithPubMiddleware(
+			func(mq.PubHandler[memq.ProducerMessage]) mq.PubHandler[memq.ProducerMessage] {
+				return func(context.Context, memq.ProducerMessage) error { return failed }
+			},
+		))
+		Expect(t, err, Succeed())
+
+		first := memq.NewProducerMessage("failed", []byte("first"))
+		first.SetPartitionKey("key")
+		second := memq.NewProducerMessage("failed", []byte("second"))
+		second.SetPartitionKey("key")
+		Expect(t, Enqueue(ctx, first, second), Succeed())
+
+		r := NewRelay(p, memq.NewProducerMessage)
+		r.MaxAttempts = 2
+		r.Backoff = types.Duration(time.Millisecond)
+
+		n, err := r.Poll(ctx)
+		Expect(t, err, Succeed())
+		Expect(t, n, Equal(0))
+
+		pending, err := (&Message{}).List(ctx, TMessage.Topic.AsCond(builder.Eq("failed")), nil)
+		Expect(t, err, Succeed())
+		Expect(t, pending, HaveLen[[]Message](2))
+		// the second message is blocked by the first one with the same key
+		Expect(t, pending[0].Attempts, Equal(uint32(1)))
+		Expect(t, pending[0].LastError, Equal(failed.Error()))
+		Expect(t, pending[1].Attempts, Equal(uint32(0)))
+
+		time.Sleep(5 * time.Millisecond)
+		_, err = r.Poll(ctx)
+		Expect(t, err, Succeed())
+
+		dead, err := (&Message{}).Count(ctx, TMessage.Status.AsCond(builder.Eq(Dead)))
+		Expect(t, err, Succeed())
+		Expect(t, dead, Equal(int64(1)))
+	})
+	t.Run("Claimed", func(t *testing.T) {
+		p, err := b.NewProducer(ctx, memq.WithPubTopic("claimed"))
+		Expect(t, err, Succeed())
+
+		for _, key := range []string{"key", "key", ""} {
+			m := memq.NewProducerMessage("claimed", []byte(key))
+			m.SetPartitionKey(key)
+			Expect(t, Enqueue(ctx, m), Succeed())
+		}
+
+		// the first message is claimed by another relay
+		pending, err := (&Message{}).List(ctx, TMessage.Topic.AsCond(builder.Eq("claimed")), nil)
+		Expect(t, err, Succeed())
+		pending[0].NextAttemptAt = time.Now().Add(time.Minute).UnixMilli()
+		Expect(t, pending[0].UpdateByID(ctx, TMessage.NextAttemptAt), Succeed())
+
+		r := NewRelay(p, memq.NewProducerMessage)
+		r.DeletePublished = true
+		n, err := r.Poll(ctx)
+		Expect(t, err, Succeed())
+		// message of the claimed partition is blocked
+		Expect(t, n, Equal(1))
+		Expect(t, b.Messages("claimed"), HaveLen[[]memq.ConsumerMessage](1))
+
+		count, err := (&Message{}).Count(ctx, TMessage.Topic.AsCond(builder.Eq("claimed")))
+		Expect(t, err, Succeed())
+		Expect(t, count, Equal(int64(2)))
+	})
+}
@@ -0,0 +1,307 @@
+package confrdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xoctopus/logx"
+	"github.com/xoctopus/sqlx/pkg/frag"
+	"github.com/xoctopus/sqlx/pkg/helper"
+	"github.com/xoctopus/sqlx/pkg/session"
+	"github.com/xoctopus/x/misc/must"
+	"github.com/xoctopus/x/textx"
+
+	"github.com/xoctopus/confx/pkg/confrdb/option"
+	"github.com/xoctopus/confx/pkg/types"
+)
+
+// balancing policies of replicas
+const (
+	BalancerRoundRobin = "round_robin"
+	BalancerLeastConn  = "least_conn"
+	BalancerWeighted   = "weighted"
+)
+
+// Replica is a readonly endpoint of database. Option of replica presents its
+// own pool and connection settings, and tracing follows the main endpoint.
+type Replica[A any] struct {
+	types.Endpoint[Option[A]]
+	// Weight of replica when balanced by weighted, default is 1
+	Weight int
+}
+
+// ReplicaOption presents balancing and health checking of replicas
+type ReplicaOption struct {
+	// Balancer round_robin|least_conn|weighted. least_conn picks the replica
+	// with the least connections in use.
+	Balancer string `url:",default=round_robin"`
+	// CheckInterval interval of checking liveness and replication lag
+	CheckInterval types.Duration `url:",default=5s"`
+	// CheckTimeout timeout of each checking
+	CheckTimeout types.Duration `url:",default=1s"`
+	// FailureThreshold a replica is ejected after failed checking in a row, and
+	// readmitted once checked successfully
+	FailureThreshold int `url:",default=2"`
+	// MaxLag replica with replication lag greater than MaxLag is regarded as
+	// failed. 0 means lag is not probed.
+	MaxLag types.Duration `url:",default=0s"`
+}
+
+func (o *ReplicaOption) SetDefault() {
+	must.NoErrorV(textx.SetDefault(o))
+
+	if o.Balancer == "" {
+		o.Balancer = BalancerRoundRobin
+	}
+	if o.CheckInterval <= 0 {
+		o.CheckInterval = types.Duration(5 * time.Second)
+	}
+	if o.CheckTimeout <= 0 {
+		o.CheckTimeout = types.Duration(time.Second)
+	}
+	if o.FailureThreshold <= 0 {
+		o.FailureThreshold = 2
+	}
+}
+
+// ReplicaState presents state of a replica
+type ReplicaState struct {
+	Address string
+	Weight  int
+	Healthy bool
+	Lag     time.Duration
+	InUse   int
+}
+
+type replica struct {
+	addr string
+	// db is used for checking and statistics
+	db session.Adaptor
+	// a is the adaptor serving readonly queries
+	a       session.Adaptor
+	weight  int
+	healthy atomic.Bool
+	lag     atomic.Int64
+	// failures is continuous failures of checking, accessed by checker only
+	failures int
+	// current is current weight of smooth weighted round-robin, guarded by
+	// replicas.mtx
+	current int
+}
+
+func (r *replica) inUse() int {
+	if d := r.db.D(); d != nil {
+		return d.Stats().InUse
+	}
+	return 0
+}
+
+func newReplicas(o ReplicaOption, prober option.LagProber, fallback session.Adaptor) *replicas {
+	return &replicas{option: o, prober: prober, fallback: fallback}
+}
+
+// replicas balances readonly queries across healthy replicas. replicas are
+// checked periodically, a replica failed in a row is ejected until it is
+// checked successfully. a replica failed in the initial checking is ejected
+// immediately. if no replica is healthy, the main adaptor is used.
+type replicas struct {
+	option   ReplicaOption
+	prober   option.LagProber
+	fallback session.Adaptor
+	list     []*replica
+
+	cursor atomic.Uint64
+	mtx    sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (p *replicas) add(addr string, db, a session.Adaptor, weight int) {
+	r := &replica{addr: addr, db: db, a: a, weight: max(weight, 1)}
+	r.healthy.Store(true)
+	p.list = append(p.list, r)
+}
+
+// pick returns adaptor of healthy replica by balancer
+func (p *replicas) pick() session.Adaptor {
+	healthy := make([]*replica, 0, len(p.list))
+	for _, r := range p.list {
+		if r.healthy.Load() {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return p.fallback
+	}
+
+	switch p.option.Balancer {
+	case BalancerLeastConn:
+		offset := int(p.cursor.Add(1) % uint64(len(healthy)))
+		picked := healthy[offset]
+		for i := 1; i < len(healthy); i++ {
+			r := healthy[(offset+i)%len(healthy)]
+			if r.inUse() < picked.inUse() {
+				picked = r
+			}
+		}
+		return picked.a
+	case BalancerWeighted:
+		p.mtx.Lock()
+		defer p.mtx.Unlock()
+
+		total, picked := 0, (*replica)(nil)
+		for _, r := range healthy {
+			r.current += r.weight
+			total += r.weight
+			if picked == nil || r.current > picked.current {
+				picked = r
+			}
+		}
+		picked.current -= total
+		return picked.a
+	default:
+		return healthy[p.cursor.Add(1)%uint64(len(healthy))].a
+	}
+}
+
+// start checks replicas once and keeps checking in background until close.
+// replicas unreachable when starting are ejected at once.
+func (p *replicas) start(ctx context.Context) {
+	p.check(ctx, 1)
+
+	ctx, p.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(time.Duration(p.option.CheckInterval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.check(ctx, p.option.FailureThreshold)
+			}
+		}
+	}()
+}
+
+// check probes replicas, a replica failed threshold times in a row is ejected
+func (p *replicas) check(ctx context.Context, threshold int) {
+	for _, r := range p.list {
+		log := logx.From(ctx).With("replica", r.addr)
+
+		err := p.probe(ctx, r)
+		if err == nil {
+			r.failures = 0
+			if !r.healthy.Swap(true) {
+				log.Info("replica readmitted")
+			}
+			continue
+		}
+		r.failures++
+		if r.failures >= threshold && r.healthy.Swap(false) {
+			log.Warn(fmt.Errorf("replica ejected: %w", err))
+		}
+	}
+}
+
+func (p *replicas) probe(ctx context.Context, r *replica) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(p.option.CheckTimeout))
+	defer cancel()
+
+	if err := helper.QueryAndScan(ctx, r.db, frag.Query("SELECT 1"), nil); err != nil {
+		return err
+	}
+	if p.prober == nil || p.option.MaxLag <= 0 {
+		return nil
+	}
+	lag, err := p.prober.ReplicationLag(ctx, r.db.D())
+	if err != nil {
+		return err
+	}
+	r.lag.Store(int64(lag))
+	if lag > time.Duration(p.option.MaxLag) {
+		return fmt.Errorf("replication lag %s exceeds %s", lag, p.option.MaxLag)
+	}
+	return nil
+}
+
+func (p *replicas) states() []ReplicaState {
+	states := make([]ReplicaState, 0, len(p.list))
+	for _, r := range p.list {
+		states = append(states, ReplicaState{
+			Address: r.addr,
+			Weight:  r.weight,
+			Healthy: r.healthy.Load(),
+			Lag:     time.Duration(r.lag.Load()),
+			InUse:   r.inUse(),
+		})
+	}
+	return states
+}
+
+func (p *replicas) close() error {
+	if p.cancel != nil {
+		p.cancel()
+		<-p.done
+		p.cancel = nil
+	}
+	errs := make([]error, 0, len(p.list))
+	for _, r := range p.list {
+		errs = append(errs, r.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// adaptor returns readonly adaptor balanced across replicas
+func (p *replicas) adaptor() session.Adaptor {
+	return &balanced{replicas: p}
+}
+
+// balanced is the readonly adaptor routes each call to a replica picked by
+// balancer. it is used as the readonly adaptor of session.NewReadonly, which
+// keeps writes on the main adaptor.
+type balanced struct {
+	replicas *replicas
+}
+
+func (b *balanced) Exec(ctx context.Context, f frag.Fragment) (sql.Result, error) {
+	return b.replicas.pick().Exec(ctx, f)
+}
+
+func (b *balanced) Query(ctx context.Context, f frag.Fragment) (*sql.Rows, error) {
+	return b.replicas.pick().Query(ctx, f)
+}
+
+func (b *balanced) Tx(ctx context.Context, exec func(context.Context) error) error {
+	return b.replicas.pick().Tx(ctx, exec)
+}
+
+// D returns *sql.DB of fallback adaptor. replica is picked per operation, a
+// stable handle is returned instead of a random replica's.
+func (b *balanced) D() *sql.DB {
+	return b.replicas.fallback.D()
+}
+
+func (b *balanced) Dialect() string {
+	return b.replicas.fallback.Dialect()
+}
+
+// Close does nothing, replicas are closed by endpoint
+func (b *balanced) Close() error {
+	return nil
+}
+
+// hasUserinfo reports if address carries username or password
+func hasUserinfo(address string) bool {
+	u, err := url.Parse(address)
+	return err == nil && u.User != nil
+}
@@ -0,0 +1,183 @@
+package confrdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/xoctopus/sqlx/pkg/frag"
+	"github.com/xoctopus/sqlx/pkg/session"
+	. "github.com/xoctopus/x/testx"
+	otelapimetric "go.opentelemetry.io/otel/metric"
+	_ "modernc.org/sqlite"
+)
+
+type fakeAdaptor struct {
+	session.Adaptor
+	name string
+}
+
+func (fakeAdaptor) D() *sql.DB { return nil }
+
+func newFakeReplicas(balancer string, weights ...int) *replicas {
+	p := newReplicas(ReplicaOption{Balancer: balancer}, nil, fakeAdaptor{name: "main"})
+	for i, w := range weights {
+		name := string(rune('a' + i))
+		p.add(name, fakeAdaptor{}, fakeAdaptor{name: name}, w)
+	}
+	return p
+}
+
+func picked(p *replicas, n int) map[string]int {
+	m := map[string]int{}
+	for range n {
+		m[p.pick().(fakeAdaptor).name]++
+	}
+	return m
+}
+
+// adaptor is a session.Adaptor over sqlite database for testing
+type adaptor struct {
+	db *sql.DB
+}
+
+func (a *adaptor) Exec(ctx context.Context, f frag.Fragment) (sql.Result, error) {
+	q, args := statement(ctx, f)
+	return a.db.ExecContext(ctx, q, args...)
+}
+
+func (a *adaptor) Query(ctx context.Context, f frag.Fragment) (*sql.Rows, error) {
+	q, args := statement(ctx, f)
+	return a.db.QueryContext(ctx, q, args...)
+}
+
+func (a *adaptor) Tx(ctx context.Context, exec func(context.Context) error) error {
+	return exec(ctx)
+}
+
+func (a *adaptor) D() *sql.DB { return a.db }
+
+func (a *adaptor) Close() error { return a.db.Close() }
+
+func (a *adaptor) Dialect() string { return "sqlite" }
+
+func open(t *testing.T, name string) *adaptor {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), name+".db"))
+	Expect(t, err, Succeed())
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = db.Exec("CREATE TABLE t_user (id INTEGER PRIMARY KEY, name TEXT)")
+	Expect(t, err, Succeed())
+	return &adaptor{db: db}
+}
+
+func selectNames(t *testing.T, s session.Session) []string {
+	rows, err := s.Adaptor().Query(context.Background(), frag.Query("SELECT name FROM t_user ORDER BY id"))
+	Expect(t, err, Succeed())
+	defer rows.Close()
+
+	list := make([]string, 0)
+	for rows.Next() {
+		name := ""
+		Expect(t, rows.Scan(&name), Succeed())
+		list = append(list, name)
+	}
+	return list
+}
+
+func TestEndpoint_Replicas(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		main    = open(t, "main")
+		replica = open(t, "replica")
+		down    = open(t, "down")
+	)
+	_, err := replica.db.Exec("INSERT INTO t_user (id, name) VALUES (1, 'replica')")
+	Expect(t, err, Succeed())
+	Expect(t, down.db.Close(), Succeed())
+
+	d := &EndpointSQLite{name: "test"}
+	d.ReplicaOption.SetDefault()
+	d.db = main
+	d.ro = newReplicas(d.ReplicaOption, nil, main)
+	d.ro.add("replica", replica, replica, 1)
+	d.ro.add("down", down, down, 1)
+	d.ro.start(ctx)
+
+	// replica unreachable when starting is ejected at once
+	states := d.ro.states()
+	Expect(t, states[0].Healthy, BeTrue())
+	Expect(t, states[1].Healthy, BeFalse())
+
+	s := d.Session()
+	_, err = s.Adaptor().Exec(ctx, frag.Query("INSERT INTO t_user (id, name) VALUES (?, ?)", 2, "main"))
+	Expect(t, err, Succeed())
+
+	// writes land on main and reads land on replica
+	n := 0
+	Expect(t, main.db.QueryRow("SELECT COUNT(1) FROM t_user").Scan(&n), Succeed())
+	Expect(t, n, Equal(1))
+	Expect(t, replica.db.QueryRow("SELECT COUNT(1) FROM t_user WHERE name = 'main'").Scan(&n), Succeed())
+	Expect(t, n, Equal(0))
+	for range 3 {
+		Expect(t, selectNames(t, s), Equal([]string{"replica"}))
+	}
+	// D is stable, which returns db of main
+	Expect(t, d.ro.adaptor().D() == main.db, BeTrue())
+
+	// main serves reads if no replica healthy
+	d.ro.list[0].healthy.Store(false)
+	Expect(t, selectNames(t, s), Equal([]string{"main"}))
+
+	// close releases all pools even if unregistering failed
+	d.registrations = append(d.registrations, registration{err: errors.New("unregister")})
+	Expect(t, d.Close(), ErrorContains("unregister"))
+	Expect(t, d.ro.cancel == nil, BeTrue())
+	Expect(t, main.db.Ping(), Failed())
+	Expect(t, replica.db.Ping(), Failed())
+}
+
+type registration struct {
+	otelapimetric.Registration
+	err error
+}
+
+func (r registration) Unregister() error { return r.err }
+
+func TestReplicas(t *testing.T) {
+	t.Run("RoundRobin", func(t *testing.T) {
+		p := newFakeReplicas(BalancerRoundRobin, 0, 0, 0)
+		Expect(t, picked(p, 9), Equal(map[string]int{"a": 3, "b": 3, "c": 3}))
+	})
+	t.Run("LeastConn", func(t *testing.T) {
+		p := newFakeReplicas(BalancerLeastConn, 0, 0)
+		Expect(t, picked(p, 4), Equal(map[string]int{"a": 2, "b": 2}))
+	})
+	t.Run("Weighted", func(t *testing.T) {
+		p := newFakeReplicas(BalancerWeighted, 5, 1, 1)
+		Expect(t, picked(p, 14), Equal(map[string]int{"a": 10, "b": 2, "c": 2}))
+		// smooth: heavy replica is not picked continuously
+		names := ""
+		for range 7 {
+			names += p.pick().(fakeAdaptor).name
+		}
+		Expect(t, names, Equal("aabacaa"))
+	})
+	t.Run("Ejected", func(t *testing.T) {
+		p := newFakeReplicas(BalancerRoundRobin, 0, 0)
+		p.list[0].healthy.Store(false)
+		Expect(t, picked(p, 4), Equal(map[string]int{"b": 4}))
+
+		p.list[1].healthy.Store(false)
+		Expect(t, picked(p, 2), Equal(map[string]int{"main": 2}))
+
+		states := p.states()
+		Expect(t, len(states), Equal(2))
+		Expect(t, states[0].Address, Equal("a"))
+		Expect(t, states[0].Healthy, BeFalse())
+		Expect(t, states[0].Weight, Equal(1))
+	})
+}
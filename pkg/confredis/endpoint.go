@@ -3,11 +3,15 @@ package confredis
 import (
 	"context"
 	"errors"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/xoctopus/x/misc/must"
+	otelapimetric "go.opentelemetry.io/otel/metric"
 
+	"github.com/xoctopus/confx/pkg/confotel/metric"
 	"github.com/xoctopus/confx/pkg/conftls"
 	"github.com/xoctopus/confx/pkg/types"
 	"github.com/xoctopus/confx/pkg/types/kv"
@@ -17,24 +21,80 @@ import (
 type Endpoint struct {
 	types.Endpoint[Option]
 
+	mtx sync.RWMutex
+	// cli is the client injected to contexts. it is created when initializing
+	// and kept until endpoint closed, commands of it are forwarded to cur by
+	// hook after reloading.
 	cli redis.UniversalClient
+	// cur is the client built with current options
+	cur redis.UniversalClient
+	// retiring clients replaced by Reload, they are closed after grace period
+	retiring map[redis.UniversalClient]*time.Timer
+	// registration of pool statistics gauges
+	registration otelapimetric.Registration
 }
 
 func (e *Endpoint) Init(ctx context.Context) error {
 	must.BeTrueF(len(e.Address) > 0, "empty main address")
 
-	if err := e.Endpoint.Init(); err != nil {
+	if err := e.Endpoint.InitByContext(ctx); err != nil {
 		return err
 	}
 
-	if e.cli != nil {
+	if e.client() != nil {
 		return nil
 	}
 
-	if err := e.Option.SentinelAuth.Init(); err != nil {
+	cli, err := e.newClient(ctx)
+	if err != nil {
+		return err
+	}
+	cli.AddHook(&forwarder{e: e})
+
+	e.mtx.Lock()
+	e.cli, e.cur = cli, cli
+	e.mtx.Unlock()
+
+	if err = e.observe(ctx); err != nil {
 		return err
 	}
 
+	d := e.LivenessCheck(ctx)
+	return d.FailureReason()
+}
+
+// observe registers gauges of pool statistics to meter provider in ctx. stats
+// of current client are reported after reloading.
+func (e *Endpoint) observe(ctx context.Context) (err error) {
+	e.registration, err = metric.ObservePool(
+		ctx,
+		func() metric.PoolStats {
+			cli := e.client()
+			if cli == nil {
+				return metric.PoolStats{}
+			}
+			s := cli.PoolStats()
+			return metric.PoolStats{
+				Open:              int64(s.TotalConns),
+				InUse:             int64(s.TotalConns) - int64(s.IdleConns),
+				Idle:              int64(s.IdleConns),
+				WaitCount:         int64(s.WaitCount),
+				WaitDuration:      time.Duration(s.WaitDurationNs),
+				ClosedMaxLifetime: int64(s.StaleConns),
+			}
+		},
+		metric.AttrPoolName.String(e.Option.Prefix),
+		metric.AttrPoolRole.String(metric.POOL_ROLE_MAIN),
+		metric.AttrServerAddress.String(e.Endpoint.Key()),
+	)
+	return err
+}
+
+func (e *Endpoint) newClient(ctx context.Context) (redis.UniversalClient, error) {
+	if err := e.Option.SentinelAuth.InitByContext(ctx); err != nil {
+		return nil, err
+	}
+
 	opt := e.Option.ClientOption(e.Address, e.ExtraAddress...)
 
 	if !e.Auth.IsZero() {
@@ -49,49 +109,185 @@ func (e *Endpoint) Init(ctx context.Context) error {
 		}
 	}
 
-	e.cli = redis.NewUniversalClient(opt)
+	return redis.NewUniversalClient(opt), nil
+}
 
-	d := e.LivenessCheck(ctx)
-	return d.FailureReason()
+// client returns the client built with current options
+func (e *Endpoint) client() redis.UniversalClient {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+	return e.cur
+}
+
+// Lock and Unlock guard options of endpoint. appx assigns reloaded values
+// while endpoint locked.
+func (e *Endpoint) Lock() { e.mtx.Lock() }
+
+func (e *Endpoint) Unlock() { e.mtx.Unlock() }
+
+// Reload rebuilds client with changed address, auth or options, such as
+// PoolSize and MaxIdleConnection, and replaces current client after it is
+// reachable. go-redis cannot resize connection pool in place, so commands of
+// the client injected to contexts are forwarded to the new one, and the
+// replaced client is closed after ReloadGracePeriod. Note that subscriptions,
+// Conn and Watch of injected client keep using the connection pool created when
+// initializing. The client is not rebuilt if only ReloadGracePeriod changed.
+func (e *Endpoint) Reload(ctx context.Context, changed []string) error {
+	if !slices.ContainsFunc(changed, func(k string) bool { return k != "Option_ReloadGracePeriod" }) {
+		return nil
+	}
+	if err := e.Endpoint.InitByContext(ctx); err != nil {
+		return err
+	}
+
+	cli, err := e.newClient(ctx)
+	if err != nil {
+		return err
+	}
+	if err = cli.Ping(ctx).Err(); err != nil {
+		_ = cli.Close()
+		return err
+	}
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	if e.cli == nil {
+		_ = cli.Close()
+		return errors.New("redis: endpoint closed")
+	}
+	if prev := e.cur; prev != e.cli {
+		e.retire(prev, time.Duration(e.Option.ReloadGracePeriod))
+	}
+	e.cur = cli
+	return nil
+}
+
+// retire closes cli after grace period. it must be called with mtx locked
+func (e *Endpoint) retire(cli redis.UniversalClient, grace time.Duration) {
+	if e.retiring == nil {
+		e.retiring = make(map[redis.UniversalClient]*time.Timer)
+	}
+	e.retiring[cli] = time.AfterFunc(grace, func() {
+		e.mtx.Lock()
+		_, ok := e.retiring[cli]
+		delete(e.retiring, cli)
+		e.mtx.Unlock()
+
+		if ok {
+			_ = cli.Close()
+		}
+	})
 }
 
 func (e *Endpoint) LivenessCheck(ctx context.Context) (d liveness.Result) {
 	d = liveness.NewLivenessData()
 
-	if e.cli == nil {
+	cli := e.client()
+	if cli == nil {
 		d.End(errors.New("redis: lost connection"))
 		return
 	}
 
-	d.End(e.cli.Ping(ctx).Err())
+	d.End(cli.Ping(ctx).Err())
 	return
 }
 
 func (e *Endpoint) Close() error {
+	errs := make([]error, 0, len(e.retiring)+3)
+	// unregister before locking, callback may be waiting for reading client
+	if r := e.registration; r != nil {
+		e.registration = nil
+		errs = append(errs, r.Unregister())
+	}
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	for cli, t := range e.retiring {
+		t.Stop()
+		errs = append(errs, cli.Close())
+	}
+	e.retiring = nil
+	if cli := e.cur; cli != nil && cli != e.cli {
+		errs = append(errs, cli.Close())
+	}
+	e.cur = nil
 	if cli := e.cli; cli != nil {
 		e.cli = nil
-		return cli.Close()
+		errs = append(errs, cli.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// forwarder forwards commands of injected client to current client
+type forwarder struct {
+	e *Endpoint
+}
+
+// target returns current client if commands should be forwarded
+func (f *forwarder) target() redis.UniversalClient {
+	f.e.mtx.RLock()
+	defer f.e.mtx.RUnlock()
+	if f.e.cur != f.e.cli {
+		return f.e.cur
 	}
 	return nil
 }
 
+func (f *forwarder) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (f *forwarder) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cli := f.target(); cli != nil {
+			return cli.Process(ctx, cmd)
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (f *forwarder) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		cli := f.target()
+		if cli == nil {
+			return next(ctx, cmds)
+		}
+
+		pipe := cli.Pipeline()
+		// transaction pipeline is wrapped with MULTI and EXEC
+		if n := len(cmds); n >= 2 && cmds[0].Name() == "multi" && cmds[n-1].Name() == "exec" {
+			pipe, cmds = cli.TxPipeline(), cmds[1:n-1]
+		}
+		for _, cmd := range cmds {
+			_ = pipe.Process(ctx, cmd)
+		}
+		_, err := pipe.Exec(ctx)
+		return err
+	}
+}
+
 var (
 	_ kv.Executor      = (*Endpoint)(nil)
 	_ kv.Store         = (*Endpoint)(nil)
 	_ types.Injectable = (*Endpoint)(nil)
+	_ types.Reloadable = (*Endpoint)(nil)
+	_ sync.Locker      = (*Endpoint)(nil)
 )
 
 func (e *Endpoint) Key(k string) string {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
 	return e.Option.Prefix + ":" + k
 }
 
 func (e *Endpoint) Exec(ctx context.Context, cmd string, args ...any) (any, error) {
-	c := e.cli.Do(ctx, append([]any{cmd}, args...)...)
+	c := e.client().Do(ctx, append([]any{cmd}, args...)...)
 	return c.Result()
 }
 
 func (e *Endpoint) Get(ctx context.Context, key string) (string, bool, error) {
-	val, err := e.cli.Get(ctx, key).Result()
+	val, err := e.client().Get(ctx, key).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return "", false, nil
@@ -105,18 +301,18 @@ func (e *Endpoint) Set(ctx context.Context, key, val string, ttl time.Duration)
 	if ttl < 0 {
 		ttl = 0
 	}
-	return e.cli.Set(ctx, key, val, ttl).Err()
+	return e.client().Set(ctx, key, val, ttl).Err()
 }
 
 func (e *Endpoint) SetNX(ctx context.Context, key, val string, ttl time.Duration) (bool, error) {
 	if ttl < 0 {
 		ttl = 0
 	}
-	return e.cli.SetNX(ctx, key, val, ttl).Result()
+	return e.client().SetNX(ctx, key, val, ttl).Result()
 }
 
 func (e *Endpoint) Del(ctx context.Context, key string) (bool, error) {
-	n, err := e.cli.Del(ctx, key).Result()
+	n, err := e.client().Del(ctx, key).Result()
 	if err != nil {
 		return false, err
 	}
@@ -124,7 +320,7 @@ func (e *Endpoint) Del(ctx context.Context, key string) (bool, error) {
 }
 
 func (e *Endpoint) TTL(ctx context.Context, key string) (time.Duration, bool, error) {
-	d, err := e.cli.PTTL(ctx, key).Result()
+	d, err := e.client().PTTL(ctx, key).Result()
 	if err != nil {
 		return 0, false, err
 	}
@@ -139,11 +335,15 @@ func (e *Endpoint) TTL(ctx context.Context, key string) (time.Duration, bool, er
 }
 
 func (e *Endpoint) WithContext(ctx context.Context) context.Context {
+	e.mtx.RLock()
+	cli := e.cli
+	e.mtx.RUnlock()
+
 	x := struct {
 		redis.UniversalClient
 		kv.Executor
 	}{
-		UniversalClient: e.cli,
+		UniversalClient: cli,
 		Executor:        e,
 	}
 
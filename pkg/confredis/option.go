@@ -41,6 +41,10 @@ type Option struct {
 	MasterName string
 
 	ClusterMode bool
+
+	// ReloadGracePeriod is how long the client replaced by reloading keeps
+	// serving in-flight commands before it is closed.
+	ReloadGracePeriod types.Duration `url:"-,default=30s"`
 }
 
 func (o *Option) SetDefault() {
@@ -22,6 +22,7 @@ func TestOption_SetDefault(t *testing.T) {
 		PoolSize:          20,
 		MaxIdleConnection: 10,
 		MaxIdleTime:       types.Duration(time.Hour),
+		ReloadGracePeriod: types.Duration(30 * time.Second),
 	}))
 }
 
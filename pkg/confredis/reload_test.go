@@ -0,0 +1,193 @@
+package confredis_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/confredis"
+	"github.com/xoctopus/confx/pkg/types"
+)
+
+// server is a fake redis server which counts commands and alive connections
+type server struct {
+	net.Listener
+
+	mtx      sync.Mutex
+	commands map[string]int
+	conns    atomic.Int64
+}
+
+func newServer(t *testing.T) *server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(t, err, Succeed())
+
+	s := &server{Listener: l, commands: map[string]int{}}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *server) Address() string {
+	return "redis://" + s.Addr().String()
+}
+
+func (s *server) Count(cmd string) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.commands[cmd]
+}
+
+func (s *server) serve(c net.Conn) {
+	s.conns.Add(1)
+	defer s.conns.Add(-1)
+	defer c.Close()
+
+	var (
+		r      = bufio.NewReader(c)
+		queued []string
+		multi  bool
+	)
+	for {
+		args, err := read(r)
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		s.mtx.Lock()
+		s.commands[cmd]++
+		s.mtx.Unlock()
+
+		reply := ""
+		switch cmd {
+		case "PING":
+			reply = "+PONG\r\n"
+		case "SET", "CLIENT", "SELECT":
+			reply = "+OK\r\n"
+		case "GET":
+			reply = "$1\r\nv\r\n"
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+
+		switch {
+		case cmd == "MULTI":
+			multi, reply = true, "+OK\r\n"
+		case cmd == "EXEC":
+			reply = fmt.Sprintf("*%d\r\n%s", len(queued), strings.Join(queued, ""))
+			multi, queued = false, nil
+		case multi:
+			queued, reply = append(queued, reply), "+QUEUED\r\n"
+		}
+		if _, err = c.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func read(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, n)
+	for range n {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func TestEndpoint_Reload(t *testing.T) {
+	var (
+		ctx = context.Background()
+		s1  = newServer(t)
+		s2  = newServer(t)
+		s3  = newServer(t)
+	)
+
+	ep := &confredis.Endpoint{Endpoint: types.Endpoint[confredis.Option]{Address: s1.Address()}}
+	ep.SetDefault()
+	ep.Option.ConnectionTimeout = types.Duration(time.Second)
+	ep.Option.OperationTimeout = types.Duration(time.Second)
+	ep.Option.ReloadGracePeriod = types.Duration(50 * time.Millisecond)
+	Expect(t, ep.Init(ctx), Succeed())
+	defer ep.Close()
+
+	cli := confredis.MustClient(ep.WithContext(ctx))
+	Expect(t, cli.Set(ctx, "k", "v", 0).Err(), Succeed())
+	Expect(t, s1.Count("SET"), Equal(1))
+
+	ep.Address = s2.Address()
+	Expect(t, ep.Reload(ctx, []string{"Address"}), Succeed())
+
+	// client injected before reloading uses current client
+	Expect(t, cli.Set(ctx, "k", "v", 0).Err(), Succeed())
+	Expect(t, s2.Count("SET"), Equal(1))
+
+	_, err := cli.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, "k", "v", 0)
+		p.Get(ctx, "k")
+		return nil
+	})
+	Expect(t, err, Succeed())
+	Expect(t, s2.Count("GET"), Equal(1))
+
+	var get *redis.StringCmd
+	_, err = cli.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		get = p.Get(ctx, "k")
+		return nil
+	})
+	Expect(t, err, Succeed())
+	Expect(t, get.Val(), Equal("v"))
+	Expect(t, s2.Count("MULTI"), Equal(1))
+	Expect(t, s1.Count("GET"), Equal(0))
+
+	// replaced client is closed after grace period
+	ep.Address = s3.Address()
+	Expect(t, ep.Reload(ctx, []string{"Address"}), Succeed())
+	Expect(t, s2.conns.Load() > 0, BeTrue())
+	time.Sleep(200 * time.Millisecond)
+	Expect(t, s2.conns.Load(), Equal(int64(0)))
+
+	Expect(t, cli.Set(ctx, "k", "v", 0).Err(), Succeed())
+	Expect(t, s3.Count("SET"), Equal(1))
+
+	// client is not rebuilt if only grace period changed
+	pings := s3.Count("PING")
+	ep.Option.ReloadGracePeriod = types.Duration(time.Second)
+	Expect(t, ep.Reload(ctx, []string{"Option_ReloadGracePeriod"}), Succeed())
+	Expect(t, s3.Count("PING"), Equal(pings))
+	Expect(t, cli.Set(ctx, "k", "v", 0).Err(), Succeed())
+	Expect(t, s3.Count("SET"), Equal(2))
+}
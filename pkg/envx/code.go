@@ -26,4 +26,13 @@ const (
 	CODE__ENC_INVALID_ENV_KEY               // invalid env key, expect alphabet string
 	CODE__ENC_FAILED_MARSHAL                // failed to marshal
 	CODE__ENC_DUPLICATE_GROUP_KEY           // group key duplicated
+	CODE__SRC_FAILED_READ                   // failed to read source
+	CODE__SRC_FAILED_PARSE                  // failed to parse source
+	CODE__DEC_MISSING_REQUIRED              // required value is missing
+	CODE__DEC_OUT_OF_RANGE                  // value out of range
+	CODE__DEC_NOT_IN_OPTIONS                // value is not one of options
+	CODE__DEC_PATTERN_MISMATCH              // value mismatches pattern
+	CODE__DEC_INVALID_URL                   // invalid url
+	CODE__DEC_INVALID_HOSTPORT              // invalid host:port
+	CODE__DEC_INVALID_RULE                  // invalid validation rule
 )
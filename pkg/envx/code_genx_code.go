@@ -29,5 +29,23 @@ func (e Code) Message() string {
 		return "[envx.Error:9] failed to marshal"
 	case CODE__ENC_DUPLICATE_GROUP_KEY:
 		return "[envx.Error:10] group key duplicated"
+	case CODE__SRC_FAILED_READ:
+		return "[envx.Error:11] failed to read source"
+	case CODE__SRC_FAILED_PARSE:
+		return "[envx.Error:12] failed to parse source"
+	case CODE__DEC_MISSING_REQUIRED:
+		return "[envx.Error:13] required value is missing"
+	case CODE__DEC_OUT_OF_RANGE:
+		return "[envx.Error:14] value out of range"
+	case CODE__DEC_NOT_IN_OPTIONS:
+		return "[envx.Error:15] value is not one of options"
+	case CODE__DEC_PATTERN_MISMATCH:
+		return "[envx.Error:16] value mismatches pattern"
+	case CODE__DEC_INVALID_URL:
+		return "[envx.Error:17] invalid url"
+	case CODE__DEC_INVALID_HOSTPORT:
+		return "[envx.Error:18] invalid host:port"
+	case CODE__DEC_INVALID_RULE:
+		return "[envx.Error:19] invalid validation rule"
 	}
 }
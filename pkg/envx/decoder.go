@@ -2,7 +2,6 @@ package envx
 
 import (
 	"reflect"
-	"strings"
 
 	"github.com/xoctopus/x/codex"
 	"github.com/xoctopus/x/reflectx"
@@ -121,36 +120,9 @@ func (d *Decoder) decode(pw *PathWalker, rv reflect.Value) error {
 		}
 		return nil
 	case reflect.Struct:
-		for i := 0; i < rv.NumField(); i++ {
-			frt := rt.Field(i)
-			if !frt.IsExported() {
-				continue
-			}
-			name := frt.Name
-			flatten := frt.Anonymous && reflectx.Deref(frt.Type).Kind() == reflect.Struct
-
-			if flag := reflectx.ParseTag(frt.Tag).Get("env"); flag != nil {
-				flatten = false
-				if flag.Name() == "-" {
-					continue
-				}
-				if flag.Name() != "" {
-					name = flag.Name()
-					name = strings.ToUpper(name)
-				}
-			}
-
-			if !flatten {
-				pw.Enter(name)
-			}
-			if err := d.decode(pw, rv.Field(i)); err != nil {
-				return err
-			}
-			if !flatten {
-				pw.Leave()
-			}
-		}
-		return nil
+		return WalkFields(pw, rt, func(i int, _ string, _ *reflectx.Flag) error {
+			return d.decode(pw, rv.Field(i))
+		})
 	default:
 		if v := d.g.Get(pw.String()); v != nil {
 			return codex.Wrapf(CODE__DEC_FAILED_UNMARSHAL, textx.Unmarshal([]byte(v.val), rv), "at %s", pw.String())
@@ -2,7 +2,6 @@ package envx
 
 import (
 	"reflect"
-	"strings"
 
 	"github.com/xoctopus/x/codex"
 	"github.com/xoctopus/x/reflectx"
@@ -124,42 +123,12 @@ func (d *Encoder) encode(pw *PathWalker, rv reflect.Value) error {
 		}
 		return nil
 	case reflect.Struct:
-		for i := 0; i < rv.NumField(); i++ {
-			frt := rt.Field(i)
-			if !frt.IsExported() {
-				continue
-			}
-			name := frt.Name
-			flatten := frt.Anonymous && reflectx.Deref(frt.Type).Kind() == reflect.Struct
-
-			flag := reflectx.ParseTag(frt.Tag).Get("env")
-			if flag != nil {
-				flatten = false
-				if flag.Name() == "-" {
-					continue
-				}
-				if flag.Name() != "" {
-					name = flag.Name()
-					name = strings.ToUpper(name)
-				}
-			}
-
-			if !flatten {
-				pw.Enter(name)
-			}
-
-			if key := pw.String(); len(key) > 0 && !flatten && flag != nil {
+		return WalkFields(pw, rt, func(i int, _ string, flag *reflectx.Flag) error {
+			if key := pw.String(); len(key) > 0 && flag != nil {
 				d.flags[key] = flag
 			}
-
-			if err := d.encode(pw, rv.Field(i)); err != nil {
-				return err
-			}
-			if !flatten {
-				pw.Leave()
-			}
-		}
-		return nil
+			return d.encode(pw, rv.Field(i))
+		})
 	default:
 		return d.set(pw, rv)
 	}
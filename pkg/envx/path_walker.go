@@ -3,7 +3,11 @@ package envx
 import (
 	"bytes"
 	"fmt"
+	"reflect"
 	"strconv"
+	"strings"
+
+	"github.com/xoctopus/x/reflectx"
 )
 
 func NewPathWalker() *PathWalker {
@@ -54,3 +58,45 @@ func stringify(jointer rune, paths ...any) string {
 
 	return buf.String()
 }
+
+// WalkFields walks exported fields of struct type rt by env naming rules. a
+// field is named by name of its `env` tag in upper case, or its field name if
+// not named by tag, and it is skipped if tagged as `env:"-"`. an anonymous
+// struct field without `env` tag is flattened: its name is empty and its fields
+// are in the path of rt. a named field is entered to pw while fn is called.
+// walking stops at the first error returned by fn.
+func WalkFields(pw *PathWalker, rt reflect.Type, fn func(i int, name string, flag *reflectx.Flag) error) error {
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		flatten := f.Anonymous && reflectx.Deref(f.Type).Kind() == reflect.Struct
+
+		flag := reflectx.ParseTag(f.Tag).Get("env")
+		if flag != nil {
+			flatten = false
+			if flag.Name() == "-" {
+				continue
+			}
+			if flag.Name() != "" {
+				name = strings.ToUpper(flag.Name())
+			}
+		}
+
+		if flatten {
+			if err := fn(i, "", nil); err != nil {
+				return err
+			}
+			continue
+		}
+		pw.Enter(name)
+		err := fn(i, name, flag)
+		pw.Leave()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
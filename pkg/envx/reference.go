@@ -0,0 +1,341 @@
+package envx
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/xoctopus/x/codex"
+	"github.com/xoctopus/x/docx"
+	"github.com/xoctopus/x/reflectx"
+	"github.com/xoctopus/x/textx"
+)
+
+// KeyDoc documents a configuration key
+type KeyDoc struct {
+	// Key is the full env key, eg: APP__CONFIG__Endpoint_Address
+	Key string
+	// Type is the go type of value
+	Type string
+	// Default is the default value, masked if Masked
+	Default string
+	// Optional marks key is omitted in config/default.yml and config/.env
+	Optional bool
+	// Masked marks value is printed as SecurityString
+	Masked bool
+	// Rules are validation rules in `env` tag, see NewValidator
+	Rules []string
+	// Description comes from field or type comments by docx.Doc
+	Description string
+
+	kind reflect.Kind
+}
+
+// NewReference returns a Reference which documents keys of group g.
+func NewReference(g *Group) *Reference {
+	return &Reference{g: g}
+}
+
+// Reference walks config values as Encoder does and documents each key with its
+// type, default value, flags and description. empty maps and slices are
+// documented by element with `{key}` or `{index}` placeholder.
+type Reference struct {
+	g    *Group
+	keys []*KeyDoc
+}
+
+func (r *Reference) Group() *Group {
+	return r.g
+}
+
+// Keys returns documented keys in declaration order
+func (r *Reference) Keys() []*KeyDoc {
+	return r.keys
+}
+
+// Describe walks v and documents keys, v is usually the default config value.
+func (r *Reference) Describe(v any) error {
+	rv, ok := v.(reflect.Value)
+	if !ok {
+		rv = reflect.ValueOf(v)
+	}
+	return r.describe(NewPathWalker(), rv, nil, "")
+}
+
+func (r *Reference) set(pw *PathWalker, rv reflect.Value, flag *reflectx.Flag, desc string) error {
+	k := &KeyDoc{
+		Key:         r.g.Key(pw.String()),
+		Type:        rv.Type().String(),
+		Description: desc,
+		kind:        rv.Kind(),
+	}
+	if rv.Type().Implements(TextMarshallerT) || reflect.PointerTo(rv.Type()).Implements(TextMarshallerT) {
+		k.kind = reflect.String
+	}
+	if desc == "" {
+		k.Description = doc(rv)
+	}
+
+	if flag != nil {
+		for opt := range flag.Options() {
+			if opt.Key() == "optional" {
+				k.Optional = true
+				continue
+			}
+			rule := opt.Key()
+			if v := opt.Unquoted(); v != "" {
+				rule += "=" + v
+			}
+			k.Rules = append(k.Rules, rule)
+		}
+	}
+
+	if !isNil(rv) {
+		text, err := textx.Marshal(rv)
+		if err != nil {
+			return codex.Wrapf(CODE__ENC_FAILED_MARSHAL, err, "at %s", pw.String())
+		}
+		k.Default = string(text)
+	}
+	if masker, ok := addressable(rv).Addr().Interface().(interface{ SecurityString() string }); ok {
+		k.Masked = true
+		if k.Default != "" {
+			k.Default = masker.SecurityString()
+		}
+	}
+
+	r.keys = append(r.keys, k)
+	return nil
+}
+
+func (r *Reference) describe(pw *PathWalker, rv reflect.Value, flag *reflectx.Flag, desc string) error {
+	rt := rv.Type()
+
+	if rt.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv = reflect.New(rt.Elem())
+		}
+		return r.describe(pw, rv.Elem(), flag, desc)
+	}
+
+	if rt.Implements(TextMarshallerT) || reflect.PointerTo(rt).Implements(TextMarshallerT) {
+		return r.set(pw, rv, flag, desc)
+	}
+
+	switch rt.Kind() {
+	case reflect.Func, reflect.Chan, reflect.Interface:
+		return nil
+	case reflect.Map:
+		if rv.Len() == 0 {
+			pw.Enter("{key}")
+			defer pw.Leave()
+			return r.describe(pw, reflect.New(rt.Elem()).Elem(), flag, desc)
+		}
+		keys := rv.MapKeys()
+		for i := range keys {
+			pw.Enter(keys[i].Interface())
+			if err := r.describe(pw, addressable(rv.MapIndex(keys[i])), flag, desc); err != nil {
+				return err
+			}
+			pw.Leave()
+		}
+		return nil
+	case reflect.Array, reflect.Slice:
+		if rv.Len() == 0 {
+			pw.Enter("{index}")
+			defer pw.Leave()
+			return r.describe(pw, reflect.New(rt.Elem()).Elem(), flag, desc)
+		}
+		for i := 0; i < rv.Len(); i++ {
+			pw.Enter(i)
+			if err := r.describe(pw, addressable(rv.Index(i)), flag, desc); err != nil {
+				return err
+			}
+			pw.Leave()
+		}
+		return nil
+	case reflect.Struct:
+		rv = addressable(rv)
+		var docs docx.Doc
+		if d, ok := rv.Addr().Interface().(docx.Doc); ok {
+			docs = d
+		}
+		return WalkFields(pw, rt, func(i int, _ string, f *reflectx.Flag) error {
+			fdesc := ""
+			if docs != nil {
+				if lines, ok := docs.DocOf(rt.Field(i).Name); ok {
+					fdesc = strings.TrimSpace(strings.Join(lines, " "))
+				}
+			}
+			return r.describe(pw, rv.Field(i), f, fdesc)
+		})
+	default:
+		return r.set(pw, rv, flag, desc)
+	}
+}
+
+// addressable returns an addressable copy of rv if it is not addressable
+func addressable(rv reflect.Value) reflect.Value {
+	if rv.CanAddr() {
+		return rv
+	}
+	v := reflect.New(rv.Type()).Elem()
+	v.Set(rv)
+	return v
+}
+
+func isNil(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// doc returns type doc of rv
+func doc(rv reflect.Value) string {
+	if d, ok := addressable(rv).Addr().Interface().(docx.Doc); ok {
+		if lines, ok := d.DocOf(); ok {
+			return strings.TrimSpace(strings.Join(lines, " "))
+		}
+	}
+	return ""
+}
+
+// Markdown renders references as markdown tables, one section for each group.
+func Markdown(refs ...*Reference) []byte {
+	buf := bytes.NewBuffer(nil)
+	for i, r := range refs {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("## " + r.g.Name() + "\n\n")
+		buf.WriteString("| Key | Type | Default | Optional | Masked | Rules | Description |\n")
+		buf.WriteString("|-----|------|---------|----------|--------|-------|-------------|\n")
+		for _, k := range r.keys {
+			cells := []string{
+				code(k.Key),
+				code(k.Type),
+				code(k.Default),
+				check(k.Optional),
+				check(k.Masked),
+				code(strings.Join(k.Rules, ",")),
+				escape(k.Description),
+			}
+			buf.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+// JSONSchema renders references as a JSON Schema (draft 2020-12) of the flat key
+// value mapping, such as config/default.yml. rules are mapped to schema keywords
+// and go type, optional and masked flags are kept by `x-` extensions.
+func JSONSchema(refs ...*Reference) ([]byte, error) {
+	var (
+		properties = make(map[string]any)
+		required   = make([]string, 0)
+	)
+	for _, r := range refs {
+		for _, k := range r.keys {
+			typ := jsonType(k.kind)
+			p := map[string]any{"type": typ, "x-go-type": k.Type}
+			if k.Description != "" {
+				p["description"] = k.Description
+			}
+			if k.Default != "" {
+				p["default"] = jsonValue(typ, k.Default)
+			}
+			if k.Optional {
+				p["x-optional"] = true
+			}
+			if k.Masked {
+				p["writeOnly"] = true
+				p["x-masked"] = true
+			}
+			for _, rule := range k.Rules {
+				key, val, _ := strings.Cut(rule, "=")
+				switch key {
+				case "required":
+					required = append(required, k.Key)
+				case "min", "max":
+					switch k.kind {
+					case reflect.String:
+						p[key+"Length"] = jsonValue("integer", val)
+					case reflect.Slice, reflect.Array, reflect.Map:
+					default:
+						p[map[string]string{"min": "minimum", "max": "maximum"}[key]] = jsonValue(typ, val)
+					}
+				case "oneof":
+					options := make([]any, 0)
+					for _, o := range strings.Split(val, "|") {
+						options = append(options, jsonValue(typ, o))
+					}
+					p["enum"] = options
+				case "pattern":
+					p["pattern"] = val
+				case "url":
+					p["format"] = "uri"
+				}
+			}
+			properties[k.Key] = p
+		}
+	}
+
+	schema := map[string]any{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func jsonType(kind reflect.Kind) string {
+	switch {
+	case kind == reflect.Bool:
+		return "boolean"
+	case reflectx.IsInteger(kind):
+		return "integer"
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+func jsonValue(typ, v string) any {
+	switch typ {
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return json.Number(v)
+		}
+	}
+	return v
+}
+
+func code(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + escape(s) + "`"
+}
+
+func check(b bool) string {
+	if b {
+		return "✓"
+	}
+	return ""
+}
+
+func escape(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
@@ -0,0 +1,89 @@
+package envx_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/envx"
+	"github.com/xoctopus/confx/pkg/types"
+)
+
+type DocumentedConfig struct {
+	Address    string         `env:",required,url"`
+	PoolSize   int            `env:",min=1,max=10"`
+	Timeout    types.Duration `env:",optional"`
+	Password   types.Password
+	Hosts      []string
+	Labels     map[string]string
+	Disabled   string `env:"-"`
+	unexported string
+}
+
+func (v *DocumentedConfig) DocOf(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "Address":
+			return []string{"server address", "with | pipe"}, true
+		case "PoolSize":
+			return []string{"connection pool size"}, true
+		}
+		return []string{}, false
+	}
+	return []string{"documented config"}, true
+}
+
+func TestReference(t *testing.T) {
+	ref := envx.NewReference(envx.NewGroup("APP__DOC"))
+	err := ref.Describe(&DocumentedConfig{
+		Address:  "http://localhost",
+		PoolSize: 5,
+		Password: "secret",
+	})
+	Expect(t, err, Succeed())
+	Expect(t, ref.Group().Name(), Equal("APP__DOC"))
+
+	keys := ref.Keys()
+	Expect(t, keys, HaveLen[[]*envx.KeyDoc](6))
+
+	Expect(t, keys[0].Key, Equal("APP__DOC__Address"))
+	Expect(t, keys[0].Type, Equal("string"))
+	Expect(t, keys[0].Default, Equal("http://localhost"))
+	Expect(t, keys[0].Optional, BeFalse())
+	Expect(t, keys[0].Masked, BeFalse())
+	Expect(t, keys[0].Rules, Equal([]string{"required", "url"}))
+	Expect(t, keys[0].Description, Equal("server address with | pipe"))
+	Expect(t, keys[1].Rules, Equal([]string{"min=1", "max=10"}))
+	Expect(t, keys[2].Optional, BeTrue())
+	Expect(t, keys[2].Description, Equal("extends time.Duraiton for arshaling"))
+	Expect(t, keys[3].Default, Equal(types.MaskedPassword))
+	Expect(t, keys[3].Masked, BeTrue())
+	Expect(t, keys[4].Key, Equal("APP__DOC__Hosts_{index}"))
+	Expect(t, keys[5].Key, Equal("APP__DOC__Labels_{key}"))
+
+	t.Run("Markdown", func(t *testing.T) {
+		md := string(envx.Markdown(ref))
+		Expect(t, md, ContainsSubString("## APP__DOC\n"))
+		Expect(t, md, ContainsSubString("| `APP__DOC__Address` | `string` | `http://localhost` |  |  | `required,url` | server address with \\| pipe |"))
+		Expect(t, md, ContainsSubString("| `APP__DOC__Password` | `types.Password` | `--------` |  | ✓ |"))
+	})
+
+	t.Run("JSONSchema", func(t *testing.T) {
+		data, err := envx.JSONSchema(ref)
+		Expect(t, err, Succeed())
+
+		schema := struct {
+			Required   []string                  `json:"required"`
+			Properties map[string]map[string]any `json:"properties"`
+		}{}
+		Expect(t, json.Unmarshal(data, &schema), Succeed())
+		Expect(t, schema.Required, Equal([]string{"APP__DOC__Address"}))
+		Expect(t, schema.Properties["APP__DOC__Address"]["format"], Equal[any]("uri"))
+		Expect(t, schema.Properties["APP__DOC__PoolSize"]["type"], Equal[any]("integer"))
+		Expect(t, schema.Properties["APP__DOC__PoolSize"]["default"], Equal[any](float64(5)))
+		Expect(t, schema.Properties["APP__DOC__PoolSize"]["maximum"], Equal[any](float64(10)))
+		Expect(t, schema.Properties["APP__DOC__Timeout"]["x-optional"], Equal[any](true))
+		Expect(t, schema.Properties["APP__DOC__Password"]["writeOnly"], Equal[any](true))
+	})
+}
@@ -0,0 +1,319 @@
+package envx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/xoctopus/x/codex"
+	"gopkg.in/yaml.v3"
+)
+
+// Source yields configuration key/value pairs, keys are full env keys such as
+// APP__CONFIG__Endpoint_Address.
+type Source interface {
+	Load() (map[string]string, error)
+}
+
+// SourceFunc is an adaptor to allow the use of ordinary functions as Source.
+type SourceFunc func() (map[string]string, error)
+
+func (f SourceFunc) Load() (map[string]string, error) {
+	return f()
+}
+
+// Merge loads sources in order, values from later sources override earlier.
+func Merge(sources ...Source) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, s := range sources {
+		if s == nil {
+			continue
+		}
+		kvs, err := s.Load()
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range kvs {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+// FromEnv yields process environment variables.
+func FromEnv() Source {
+	return SourceFunc(func() (map[string]string, error) {
+		values := make(map[string]string)
+		for _, environ := range os.Environ() {
+			if kv := strings.SplitN(environ, "=", 2); len(kv) == 2 {
+				values[kv[0]] = kv[1]
+			}
+		}
+		return values, nil
+	})
+}
+
+// FromMap yields a copy of values, which is useful for overriding in tests.
+func FromMap(values map[string]string) Source {
+	return SourceFunc(func() (map[string]string, error) {
+		m := make(map[string]string, len(values))
+		for k, v := range values {
+			m[k] = v
+		}
+		return m, nil
+	})
+}
+
+// FromDotEnv yields KEY=VALUE lines in filename. empty lines, comments starting
+// with '#' and `export` prefix are allowed, values can be quoted.
+func FromDotEnv(filename string) Source {
+	return file(filename, parseDotEnv)
+}
+
+// FromYAML yields a flat YAML mapping in filename, eg: config/local.yml.
+func FromYAML(filename string) Source {
+	return file(filename, func(data []byte) (map[string]string, error) {
+		values := make(map[string]string)
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, err
+		}
+		return values, nil
+	})
+}
+
+// FromNestedYAML yields a nested YAML document in filename. top-level keys are
+// group names, their children are joined by `__` and deeper keys are joined by
+// '_' as PathWalker does. top-level scalars are kept as flat keys. eg:
+//
+//	APP__CONFIG:
+//	  Endpoint:
+//	    Address: redis://localhost:6379
+//	  Hosts: [a, b]
+//
+// yields APP__CONFIG__Endpoint_Address, APP__CONFIG__Hosts_0 and APP__CONFIG__Hosts_1.
+func FromNestedYAML(filename string) Source {
+	return file(filename, func(data []byte) (map[string]string, error) {
+		node := &yaml.Node{}
+		if err := yaml.Unmarshal(data, node); err != nil {
+			return nil, err
+		}
+		values := make(map[string]string)
+		if len(node.Content) > 0 {
+			if err := flattenYAML(values, "", 0, node.Content[0]); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	})
+}
+
+// FromJSON yields a nested JSON document in filename, flattened as FromNestedYAML.
+func FromJSON(filename string) Source {
+	return file(filename, func(data []byte) (map[string]string, error) {
+		var v any
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.UseNumber()
+		if err := d.Decode(&v); err != nil {
+			return nil, err
+		}
+		values := make(map[string]string)
+		return values, flatten(values, "", 0, v)
+	})
+}
+
+// FromTOML yields a nested TOML document in filename, flattened as FromNestedYAML.
+func FromTOML(filename string) Source {
+	return file(filename, func(data []byte) (map[string]string, error) {
+		v := make(map[string]any)
+		if err := toml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		values := make(map[string]string)
+		return values, flatten(values, "", 0, v)
+	})
+}
+
+// FromSecretDir yields files in dir as Kubernetes mounts secrets and configmaps,
+// file name is the key and content is the value with trailing newlines trimmed.
+// hidden files (such as ..data) and sub-directories are skipped.
+func FromSecretDir(dir string) Source {
+	return SourceFunc(func() (map[string]string, error) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return map[string]string{}, nil
+			}
+			return nil, codex.Wrapf(CODE__SRC_FAILED_READ, err, "at %s", dir)
+		}
+		values := make(map[string]string)
+		for _, e := range entries {
+			if strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			filename := filepath.Join(dir, e.Name())
+			// stat follows symlinks which are used by kubernetes atomic writer
+			if fi, err := os.Stat(filename); err != nil || !fi.Mode().IsRegular() {
+				continue
+			}
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				return nil, codex.Wrapf(CODE__SRC_FAILED_READ, err, "at %s", filename)
+			}
+			values[e.Name()] = strings.TrimRight(string(data), "\r\n")
+		}
+		return values, nil
+	})
+}
+
+// file reads filename and parse content. a nonexistent file yields nothing.
+func file(filename string, parse func([]byte) (map[string]string, error)) Source {
+	return SourceFunc(func() (map[string]string, error) {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return map[string]string{}, nil
+			}
+			return nil, codex.Wrapf(CODE__SRC_FAILED_READ, err, "at %s", filename)
+		}
+		values, err := parse(data)
+		if err != nil {
+			return nil, codex.Wrapf(CODE__SRC_FAILED_PARSE, err, "at %s", filename)
+		}
+		return values, nil
+	})
+}
+
+func parseDotEnv(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing '='", n)
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if len(v) >= 2 {
+			switch {
+			case v[0] == '"' && v[len(v)-1] == '"':
+				x, err := strconv.Unquote(v)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", n, err)
+				}
+				v = x
+			case v[0] == '\'' && v[len(v)-1] == '\'':
+				v = v[1 : len(v)-1]
+			}
+		}
+		values[k] = v
+	}
+	return values, scanner.Err()
+}
+
+// join returns key of child at depth. top-level keys are group names, children
+// of group are joined by `__` and deeper keys are joined by `_`
+func join(prefix string, child any, depth int) string {
+	switch depth {
+	case 0:
+		return fmt.Sprint(child)
+	case 1:
+		return prefix + "__" + fmt.Sprint(child)
+	default:
+		return prefix + "_" + fmt.Sprint(child)
+	}
+}
+
+func flattenYAML(values map[string]string, prefix string, depth int, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			if err := flattenYAML(values, prefix, depth, c); err != nil {
+				return err
+			}
+		}
+	case yaml.AliasNode:
+		return flattenYAML(values, prefix, depth, n.Alias)
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if err := flattenYAML(values, join(prefix, n.Content[i].Value, depth), depth+1, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		if prefix == "" {
+			return errors.New("top-level sequence is not allowed")
+		}
+		for i, c := range n.Content {
+			if err := flattenYAML(values, join(prefix, i, depth), depth+1, c); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if prefix == "" {
+			return errors.New("top-level scalar is not allowed")
+		}
+		if n.Tag != "!!null" {
+			values[prefix] = n.Value
+		}
+	}
+	return nil
+}
+
+func flatten(values map[string]string, prefix string, depth int, v any) error {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		for k, c := range x {
+			if err := flatten(values, join(prefix, k, depth), depth+1, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		if prefix == "" {
+			return errors.New("top-level array is not allowed")
+		}
+		for i, c := range x {
+			if err := flatten(values, join(prefix, i, depth), depth+1, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []map[string]any:
+		for i, c := range x {
+			if err := flatten(values, join(prefix, i, depth), depth+1, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if prefix == "" {
+		return errors.New("top-level value must be an object")
+	}
+	switch x := v.(type) {
+	case string:
+		values[prefix] = x
+	case float64:
+		values[prefix] = strconv.FormatFloat(x, 'f', -1, 64)
+	case time.Time:
+		values[prefix] = x.Format(time.RFC3339Nano)
+	default:
+		values[prefix] = fmt.Sprint(x)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package envx_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xoctopus/x/misc/must"
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/envx"
+)
+
+func write(t *testing.T, name, content string) string {
+	filename := filepath.Join(t.TempDir(), name)
+	must.NoError(os.WriteFile(filename, []byte(content), os.ModePerm))
+	return filename
+}
+
+func TestSources(t *testing.T) {
+	expect := map[string]string{
+		"APP__CONFIG__Endpoint_Address": "redis://localhost:6379",
+		"APP__CONFIG__Hosts_0":          "a",
+		"APP__CONFIG__Hosts_1":          "b",
+		"APP__CONFIG__PoolSize":         "10",
+		"APP__CONFIG__Ratio":            "0.5",
+		"APP__CONFIG__Enabled":          "true",
+	}
+
+	t.Run("FromEnv", func(t *testing.T) {
+		t.Setenv("TEST__SOURCE__Key", "value")
+		values, err := envx.FromEnv().Load()
+		Expect(t, err, Succeed())
+		Expect(t, values["TEST__SOURCE__Key"], Equal("value"))
+	})
+
+	t.Run("FromDotEnv", func(t *testing.T) {
+		values, err := envx.FromDotEnv(write(t, ".env", `
+# comment
+APP__A=1
+export APP__B = "quoted\nvalue"
+APP__C='single'
+APP__D=
+`)).Load()
+		Expect(t, err, Succeed())
+		Expect(t, values, Equal(map[string]string{
+			"APP__A": "1",
+			"APP__B": "quoted\nvalue",
+			"APP__C": "single",
+			"APP__D": "",
+		}))
+
+		_, err = envx.FromDotEnv(write(t, ".env", "INVALID")).Load()
+		Expect(t, err, IsCodeError(envx.CODE__SRC_FAILED_PARSE))
+	})
+
+	t.Run("FromYAML", func(t *testing.T) {
+		values, err := envx.FromYAML(write(t, "local.yml", `
+APP__CONFIG__PoolSize: "10"
+APP__CONFIG__Enabled: true
+`)).Load()
+		Expect(t, err, Succeed())
+		Expect(t, values, Equal(map[string]string{
+			"APP__CONFIG__PoolSize": "10",
+			"APP__CONFIG__Enabled":  "true",
+		}))
+
+		_, err = envx.FromYAML(write(t, "local.yml", "APP: [")).Load()
+		Expect(t, err, IsCodeError(envx.CODE__SRC_FAILED_PARSE))
+	})
+
+	t.Run("FromNestedYAML", func(t *testing.T) {
+		values, err := envx.FromNestedYAML(write(t, "config.yml", `
+APP__CONFIG:
+  Endpoint:
+    Address: redis://localhost:6379
+  Hosts: [a, b]
+  PoolSize: 10
+  Ratio: 0.5
+  Enabled: true
+  Empty: ~
+`)).Load()
+		Expect(t, err, Succeed())
+		Expect(t, values, Equal(expect))
+
+		values, err = envx.FromNestedYAML(write(t, "config.yml", `APP__CONFIG__PoolSize: 10`)).Load()
+		Expect(t, err, Succeed())
+		Expect(t, values, Equal(map[string]string{"APP__CONFIG__PoolSize": "10"}))
+
+		_, err = envx.FromNestedYAML(write(t, "config.yml", `[1, 2]`)).Load()
+		Expect(t, err, IsCodeError(envx.CODE__SRC_FAILED_PARSE))
+	})
+
+	t.Run("FromJSON", func(t *testing.T) {
+		values, err := envx.FromJSON(write(t, "config.json", `{
+  "APP__CONFIG": {
+    "Endpoint": {"Address": "redis://localhost:6379"},
+    "Hosts": ["a", "b"],
+    "PoolSize": 10,
+    "Ratio": 0.5,
+    "Enabled": true,
+    "Empty": null
+  }
+}`)).Load()
+		Expect(t, err, Succeed())
+		Expect(t, values, Equal(expect))
+
+		_, err = envx.FromJSON(write(t, "config.json", `"scalar"`)).Load()
+		Expect(t, err, IsCodeError(envx.CODE__SRC_FAILED_PARSE))
+	})
+
+	t.Run("FromTOML", func(t *testing.T) {
+		values, err := envx.FromTOML(write(t, "config.toml", `
+[APP__CONFIG]
+Hosts = ["a", "b"]
+PoolSize = 10
+Ratio = 0.5
+Enabled = true
+
+[APP__CONFIG.Endpoint]
+Address = "redis://localhost:6379"
+`)).Load()
+		Expect(t, err, Succeed())
+		Expect(t, values, Equal(expect))
+	})
+
+	t.Run("FromSecretDir", func(t *testing.T) {
+		dir := t.TempDir()
+		data := filepath.Join(dir, "..data")
+		must.NoError(os.MkdirAll(data, os.ModePerm))
+		must.NoError(os.WriteFile(filepath.Join(data, "APP__CONFIG__Endpoint_Auth_Password"), []byte("secret\n"), os.ModePerm))
+		must.NoError(os.Symlink(
+			filepath.Join("..data", "APP__CONFIG__Endpoint_Auth_Password"),
+			filepath.Join(dir, "APP__CONFIG__Endpoint_Auth_Password"),
+		))
+
+		values, err := envx.FromSecretDir(dir).Load()
+		Expect(t, err, Succeed())
+		Expect(t, values, Equal(map[string]string{"APP__CONFIG__Endpoint_Auth_Password": "secret"}))
+
+		values, err = envx.FromSecretDir(filepath.Join(dir, "not_exists")).Load()
+		Expect(t, err, Succeed())
+		Expect(t, values, HaveLen[map[string]string](0))
+	})
+
+	t.Run("NotExists", func(t *testing.T) {
+		values, err := envx.FromYAML(filepath.Join(t.TempDir(), "not_exists.yml")).Load()
+		Expect(t, err, Succeed())
+		Expect(t, values, HaveLen[map[string]string](0))
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		values, err := envx.Merge(
+			envx.FromMap(map[string]string{"APP__A": "1", "APP__B": "1"}),
+			nil,
+			envx.FromMap(map[string]string{"APP__B": "2", "APP__C": "2"}),
+		)
+		Expect(t, err, Succeed())
+		Expect(t, values, Equal(map[string]string{"APP__A": "1", "APP__B": "2", "APP__C": "2"}))
+
+		_, err = envx.Merge(envx.FromJSON(write(t, "config.json", `{`)))
+		Expect(t, err, IsCodeError(envx.CODE__SRC_FAILED_PARSE))
+
+		g := envx.ParseGroup("APP", values)
+		Expect(t, g.Len(), Equal(3))
+		Expect(t, g.Get("B").Value(), Equal("2"))
+	})
+}
@@ -0,0 +1,221 @@
+package envx
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xoctopus/x/codex"
+	"github.com/xoctopus/x/reflectx"
+	"github.com/xoctopus/x/textx"
+)
+
+// NewValidator returns a Validator which checks rules declared by `env` tag
+// options after decoding. supported rules:
+//
+//	required      value must not be zero (nil, empty or 0)
+//	min=N,max=N   bound of number, or length of string, slice and map. for
+//	              types implement TextUnmarshaler (eg: types.Duration) the
+//	              bound is unmarshalled as the field type, eg: min=1s
+//	oneof=a|b|c   text value must be one of options
+//	pattern=RE    text value must match regexp, use quote if RE has comma
+//	url           text value must be an absolute url with scheme and host
+//	hostport      text value must be formatted as host:port
+//
+// rules except required and min/max are skipped for zero values.
+func NewValidator(g *Group) *Validator {
+	return &Validator{g: g}
+}
+
+type Validator struct {
+	g    *Group
+	errs []error
+}
+
+// Validate checks v and returns all violations joined, each error is located
+// by full env key, such as APP__CONFIG__Endpoint_Address.
+func (d *Validator) Validate(v any) error {
+	rv, ok := v.(reflect.Value)
+	if !ok {
+		rv = reflect.ValueOf(v)
+	}
+
+	d.errs = d.errs[:0]
+	d.validate(NewPathWalker(), rv)
+	return errors.Join(d.errs...)
+}
+
+func (d *Validator) key(pw *PathWalker) string {
+	if d.g == nil {
+		return pw.String()
+	}
+	return d.g.Key(pw.String())
+}
+
+func (d *Validator) validate(pw *PathWalker, rv reflect.Value) {
+	if !rv.IsValid() {
+		return
+	}
+
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return
+		}
+		d.validate(pw, rv.Elem())
+		return
+	}
+
+	rt := rv.Type()
+	if rt.Implements(TextMarshallerT) || reflect.PointerTo(rt).Implements(TextMarshallerT) {
+		return
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		keys := rv.MapKeys()
+		for i := range keys {
+			pw.Enter(keys[i].Interface())
+			d.validate(pw, rv.MapIndex(keys[i]))
+			pw.Leave()
+		}
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < rv.Len(); i++ {
+			pw.Enter(i)
+			d.validate(pw, rv.Index(i))
+			pw.Leave()
+		}
+	case reflect.Struct:
+		_ = WalkFields(pw, rt, func(i int, _ string, flag *reflectx.Flag) error {
+			if flag != nil {
+				d.check(pw, rv.Field(i), flag)
+			}
+			d.validate(pw, rv.Field(i))
+			return nil
+		})
+	}
+}
+
+func (d *Validator) fail(pw *PathWalker, code Code, format string, args ...any) {
+	d.errs = append(d.errs, codex.Errorf(code, "at %s: "+format, append([]any{d.key(pw)}, args...)...))
+}
+
+func (d *Validator) check(pw *PathWalker, rv reflect.Value, flag *reflectx.Flag) {
+	zero := rv.IsZero()
+	for opt := range flag.Options() {
+		var (
+			rule  = opt.Key()
+			value = opt.Unquoted()
+		)
+		switch rule {
+		case "required":
+			if zero {
+				d.fail(pw, CODE__DEC_MISSING_REQUIRED, "value is required")
+				return
+			}
+		case "min", "max":
+			bounded := rv
+			if bounded.Kind() == reflect.Pointer {
+				if bounded.IsNil() {
+					continue
+				}
+				bounded = bounded.Elem()
+			}
+			d.bound(pw, bounded, rule, value)
+		case "oneof", "pattern", "url", "hostport":
+			if zero {
+				continue
+			}
+			text, err := textx.Marshal(rv)
+			if err != nil {
+				d.fail(pw, CODE__DEC_INVALID_RULE, "failed to marshal value: %v", err)
+				continue
+			}
+			d.text(pw, string(text), rule, value)
+		}
+	}
+}
+
+func (d *Validator) bound(pw *PathWalker, rv reflect.Value, rule, value string) {
+	var (
+		cmp int
+		err error
+	)
+
+	switch kind := rv.Kind(); {
+	case kind == reflect.String || kind == reflect.Slice || kind == reflect.Map || kind == reflect.Array:
+		var n int
+		if n, err = strconv.Atoi(value); err == nil {
+			cmp = compare(rv.Len(), n)
+		}
+	case reflectx.IsInteger(kind) || kind == reflect.Float32 || kind == reflect.Float64:
+		b := reflect.New(rv.Type())
+		if err = textx.Unmarshal([]byte(value), b); err == nil {
+			switch {
+			case rv.CanInt():
+				cmp = compare(rv.Int(), b.Elem().Int())
+			case rv.CanUint():
+				cmp = compare(rv.Uint(), b.Elem().Uint())
+			default:
+				cmp = compare(rv.Float(), b.Elem().Float())
+			}
+		}
+	default:
+		err = errors.New("unsupported kind " + kind.String())
+	}
+
+	if err != nil {
+		d.fail(pw, CODE__DEC_INVALID_RULE, "%s=%s: %v", rule, value, err)
+		return
+	}
+	if (rule == "min" && cmp < 0) || (rule == "max" && cmp > 0) {
+		d.fail(pw, CODE__DEC_OUT_OF_RANGE, "expect %s=%s", rule, value)
+	}
+}
+
+func (d *Validator) text(pw *PathWalker, text, rule, value string) {
+	switch rule {
+	case "oneof":
+		for _, option := range strings.Split(value, "|") {
+			if text == option {
+				return
+			}
+		}
+		d.fail(pw, CODE__DEC_NOT_IN_OPTIONS, "expect one of [%s] but got `%s`", value, text)
+	case "pattern":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			d.fail(pw, CODE__DEC_INVALID_RULE, "pattern=%s: %v", value, err)
+			return
+		}
+		if !re.MatchString(text) {
+			d.fail(pw, CODE__DEC_PATTERN_MISMATCH, "expect matching `%s`", value)
+		}
+	case "url":
+		if u, err := url.Parse(text); err != nil || u.Scheme == "" || u.Host == "" {
+			d.fail(pw, CODE__DEC_INVALID_URL, "expect absolute url with scheme and host")
+		}
+	case "hostport":
+		_, port, err := net.SplitHostPort(text)
+		if err == nil {
+			_, err = strconv.ParseUint(port, 10, 16)
+		}
+		if err != nil {
+			d.fail(pw, CODE__DEC_INVALID_HOSTPORT, "expect host:port")
+		}
+	}
+}
+
+func compare[T int | int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,101 @@
+package envx_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/envx"
+	"github.com/xoctopus/confx/pkg/types"
+)
+
+type ValidatedEndpoint struct {
+	Address string `env:",required,url"`
+	Listen  string `env:",hostport"`
+}
+
+type ValidatedConfig struct {
+	Endpoint  ValidatedEndpoint
+	Endpoints []ValidatedEndpoint
+	Level     string         `env:",oneof=debug|info|warn"`
+	Name      string         `env:",pattern='^[a-z][a-z0-9_]*$'"`
+	PoolSize  int            `env:",min=1,max=100"`
+	Ratio     float64        `env:",max=1"`
+	Hosts     []string       `env:",min=1"`
+	Timeout   types.Duration `env:",min=1s"`
+	Optional  *int           `env:",min=1"`
+	Ignored   string         `env:"-"`
+}
+
+func ExpectCodes(t *testing.T, err error, codes ...envx.Code) {
+	t.Helper()
+	errs := err.(interface{ Unwrap() []error }).Unwrap()
+	Expect(t, len(errs), Equal(len(codes)))
+	for i := range errs {
+		Expect(t, errs[i], IsCodeError(codes[i]))
+	}
+}
+
+func TestValidator_Validate(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		v := &ValidatedConfig{
+			Endpoint:  ValidatedEndpoint{Address: "redis://localhost:6379", Listen: ":8080"},
+			Endpoints: []ValidatedEndpoint{{Address: "http://localhost"}},
+			Level:     "info",
+			Name:      "app_1",
+			PoolSize:  10,
+			Ratio:     0.5,
+			Hosts:     []string{"a"},
+			Timeout:   types.Duration(time.Second),
+			Ignored:   "any",
+		}
+		Expect(t, envx.NewValidator(envx.NewGroup("APP")).Validate(v), Succeed())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		optional := 0
+		v := &ValidatedConfig{
+			Endpoint:  ValidatedEndpoint{Listen: "localhost"},
+			Endpoints: []ValidatedEndpoint{{Address: "localhost"}},
+			Level:     "trace",
+			Name:      "1app",
+			PoolSize:  0,
+			Ratio:     1.5,
+			Timeout:   types.Duration(time.Millisecond),
+			Optional:  &optional,
+		}
+		err := envx.NewValidator(envx.NewGroup("APP")).Validate(v)
+		Expect(t, err, Failed())
+		ExpectCodes(
+			t, err,
+			envx.CODE__DEC_MISSING_REQUIRED, // Endpoint_Address
+			envx.CODE__DEC_INVALID_HOSTPORT, // Endpoint_Listen
+			envx.CODE__DEC_INVALID_URL,      // Endpoints_0_Address
+			envx.CODE__DEC_NOT_IN_OPTIONS,   // Level
+			envx.CODE__DEC_PATTERN_MISMATCH, // Name
+			envx.CODE__DEC_OUT_OF_RANGE,     // PoolSize
+			envx.CODE__DEC_OUT_OF_RANGE,     // Ratio
+			envx.CODE__DEC_OUT_OF_RANGE,     // Hosts
+			envx.CODE__DEC_OUT_OF_RANGE,     // Timeout
+			envx.CODE__DEC_OUT_OF_RANGE,     // Optional
+		)
+		Expect(t, err.Error(), ContainsSubString("APP__Endpoint_Address"))
+		Expect(t, err.Error(), ContainsSubString("APP__Endpoints_0_Address"))
+	})
+
+	t.Run("InvalidRule", func(t *testing.T) {
+		v := &struct {
+			Size    int    `env:",min=x"`
+			Name    string `env:",pattern='['"`
+			Enabled bool   `env:",max=1"`
+		}{Name: "name"}
+		err := envx.NewValidator(nil).Validate(v)
+		ExpectCodes(
+			t, err,
+			envx.CODE__DEC_INVALID_RULE,
+			envx.CODE__DEC_INVALID_RULE,
+			envx.CODE__DEC_INVALID_RULE,
+		)
+	})
+}
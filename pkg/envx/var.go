@@ -2,7 +2,6 @@ package envx
 
 import (
 	"bytes"
-	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -49,16 +48,20 @@ func NewGroup(name string) *Group {
 }
 
 func ParseGroupFromEnv(prefix string) *Group {
+	values, _ := FromEnv().Load()
+	return ParseGroup(prefix, values)
+}
+
+// ParseGroup picks vars with prefix from values, which are usually loaded from
+// Sources by Merge.
+func ParseGroup(prefix string, values map[string]string) *Group {
 	g := NewGroup(prefix)
-	for _, environ := range os.Environ() {
-		kv := strings.SplitN(environ, "=", 2)
-		if len(kv) == 2 {
-			if strings.HasPrefix(kv[0], prefix) {
-				g.Add(&Var{
-					key: strings.TrimPrefix(kv[0], prefix+"__"),
-					val: kv[1],
-				})
-			}
+	for k, v := range values {
+		if strings.HasPrefix(k, prefix) {
+			g.Add(&Var{
+				key: strings.TrimPrefix(k, prefix+"__"),
+				val: v,
+			})
 		}
 	}
 	return g
@@ -0,0 +1,318 @@
+// Package cache provides typed cache-aside helper over kv.Store.
+//
+// Cache[K, V] reads value from store and loads it by Loader when missed. keys
+// are built by kg.KeyGen.SharedKey, so that peers of the same creator share
+// cached values. Loadings of the same key are merged by singleflight, not found
+// results can be cached as negative entries, and expired values can be served
+// while revalidating in background.
+package cache
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xoctopus/x/codex"
+	"github.com/xoctopus/x/misc/must"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/xoctopus/confx/pkg/types/kg"
+	"github.com/xoctopus/confx/pkg/types/kv"
+)
+
+// Loader loads value of k from source when cache missed. it should return
+// ERROR__NOT_FOUND, or an error matched by WithNotFound, if value not exists.
+type Loader[K kg.KeyCode, V any] func(ctx context.Context, k K) (V, error)
+
+type options struct {
+	ttl        time.Duration
+	jitter     time.Duration
+	negative   time.Duration
+	stale      time.Duration
+	isNotFound func(error) bool
+}
+
+// Option configures Cache
+type Option func(*options)
+
+// WithTTL sets expiration of cached value. default is 5 minutes.
+func WithTTL(d time.Duration) Option {
+	return func(o *options) { o.ttl = d }
+}
+
+// WithJitter adds a random duration in [0, d) to ttl to avoid values cached at
+// the same time expiring together.
+func WithJitter(d time.Duration) Option {
+	return func(o *options) { o.jitter = d }
+}
+
+// WithNegativeTTL enables caching not found result in d.
+func WithNegativeTTL(d time.Duration) Option {
+	return func(o *options) { o.negative = d }
+}
+
+// WithStaleTTL keeps expired value d longer in store. an expired value in d is
+// returned directly and revalidated in background.
+func WithStaleTTL(d time.Duration) Option {
+	return func(o *options) { o.stale = d }
+}
+
+// WithNotFound sets f to match not found error returned by Loader, besides
+// ERROR__NOT_FOUND.
+func WithNotFound(f func(error) bool) Option {
+	return func(o *options) { o.isNotFound = f }
+}
+
+// New creates Cache of domain. g should be initialized.
+func New[K kg.KeyCode, V any](s kv.Store, g *kg.KeyGen, domain string, loader Loader[K, V], appliers ...Option) *Cache[K, V] {
+	must.BeTrueF(s != nil, "cache: store is required")
+	must.BeTrueF(g != nil, "cache: key generator is required")
+	must.BeTrueF(domain != "", "cache: domain is required")
+	must.NoErrorF(kg.ValidateDomain(domain), "cache: invalid domain")
+
+	c := &Cache[K, V]{
+		store:  s,
+		kg:     g,
+		domain: domain,
+		loader: loader,
+		codec:  JSON[V]{},
+		options: options{
+			ttl: 5 * time.Minute,
+		},
+	}
+	for _, applier := range appliers {
+		applier(&c.options)
+	}
+	return c
+}
+
+// Cache is typed cache-aside helper
+type Cache[K kg.KeyCode, V any] struct {
+	options
+
+	store  kv.Store
+	kg     *kg.KeyGen
+	domain string
+	loader Loader[K, V]
+	codec  Codec[V]
+	group  singleflight.Group
+}
+
+// WithCodec replaces codec of c, default is JSON.
+func (c *Cache[K, V]) WithCodec(codec Codec[V]) *Cache[K, V] {
+	c.codec = codec
+	return c
+}
+
+// Key returns store key of k
+func (c *Cache[K, V]) Key(k K) string {
+	return c.kg.SharedKey(c.domain, k)
+}
+
+// Get returns cached value of k or loads it when missed. ERROR__NOT_FOUND is
+// returned if value not exists. Failures of store are regarded as missed.
+func (c *Cache[K, V]) Get(ctx context.Context, k K) (V, error) {
+	key := c.Key(k)
+	raw, exists, err := c.store.Get(ctx, key)
+	if err == nil && exists {
+		if e, ok := c.decode(raw); ok {
+			if !e.expired(time.Now()) {
+				return e.value, e.err(k)
+			}
+			if e.found {
+				// stale while revalidate
+				c.group.DoChan(key, func() (any, error) {
+					return c.load(context.WithoutCancel(ctx), key, k)
+				})
+				return e.value, nil
+			}
+		}
+	}
+	return c.get(ctx, key, k)
+}
+
+// MGet returns values of ks. values not found are omitted. cached values are
+// fetched by `MGET` if store implements kv.Executor.
+func (c *Cache[K, V]) MGet(ctx context.Context, ks ...K) (map[K]V, error) {
+	var (
+		now    = time.Now()
+		values = make(map[K]V, len(ks))
+		raws   = c.mget(ctx, ks)
+	)
+	for i, k := range ks {
+		if e, ok := c.decode(raws[i]); ok && !e.expired(now) {
+			if e.found {
+				values[k] = e.value
+			}
+			continue
+		}
+		v, err := c.Get(ctx, k)
+		if err != nil {
+			if c.notFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		values[k] = v
+	}
+	return values, nil
+}
+
+// Set caches v as value of k
+func (c *Cache[K, V]) Set(ctx context.Context, k K, v V) error {
+	return c.set(ctx, c.Key(k), entry[V]{value: v, found: true})
+}
+
+// Delete removes cached value of k, it is usually called after source updated.
+func (c *Cache[K, V]) Delete(ctx context.Context, k K) error {
+	_, err := c.store.Del(ctx, c.Key(k))
+	return err
+}
+
+// get loads value by singleflight, each caller waits result respecting its ctx
+func (c *Cache[K, V]) get(ctx context.Context, key string, k K) (V, error) {
+	var zero V
+	ch := c.group.DoChan(key, func() (any, error) {
+		return c.load(context.WithoutCancel(ctx), key, k)
+	})
+	select {
+	case <-ctx.Done():
+		return zero, context.Cause(ctx)
+	case r := <-ch:
+		if r.Err != nil {
+			return zero, r.Err
+		}
+		// r.Val is nil if V is an interface and loaded value is nil
+		v, _ := r.Val.(V)
+		return v, nil
+	}
+}
+
+func (c *Cache[K, V]) load(ctx context.Context, key string, k K) (V, error) {
+	var zero V
+	if c.loader == nil {
+		return zero, codex.Errorf(ERROR__LOADER_REQUIRED, "cache: %s", c.domain)
+	}
+
+	v, err := c.loader(ctx, k)
+	if err != nil {
+		if c.notFound(err) {
+			if c.negative > 0 {
+				_ = c.set(ctx, key, entry[V]{})
+			}
+			return zero, codex.Wrap(ERROR__NOT_FOUND, err)
+		}
+		return zero, err
+	}
+	_ = c.set(ctx, key, entry[V]{value: v, found: true})
+	return v, nil
+}
+
+func (c *Cache[K, V]) notFound(err error) bool {
+	return codex.IsCode(err, ERROR__NOT_FOUND) || (c.isNotFound != nil && c.isNotFound(err))
+}
+
+func (c *Cache[K, V]) set(ctx context.Context, key string, e entry[V]) error {
+	ttl, stale := c.negative, time.Duration(0)
+	if e.found {
+		ttl, stale = c.ttl, c.stale
+		if c.jitter > 0 {
+			ttl += rand.N(c.jitter)
+		}
+	}
+	e.expiredAt = time.Now().Add(ttl)
+
+	raw, err := c.encode(e)
+	if err != nil {
+		return err
+	}
+	return c.store.Set(ctx, key, raw, ttl+stale)
+}
+
+func (c *Cache[K, V]) mget(ctx context.Context, ks []K) []string {
+	raws := make([]string, len(ks))
+	if x, ok := c.store.(kv.Executor); ok && len(ks) > 0 {
+		args := make([]any, 0, len(ks))
+		for _, k := range ks {
+			args = append(args, c.Key(k))
+		}
+		if res, err := x.Exec(ctx, "MGET", args...); err == nil {
+			if vs, ok := res.([]any); ok && len(vs) == len(ks) {
+				for i, v := range vs {
+					raws[i], _ = v.(string)
+				}
+				return raws
+			}
+		}
+	}
+	for i, k := range ks {
+		raws[i], _, _ = c.store.Get(ctx, c.Key(k))
+	}
+	return raws
+}
+
+// entry is cached value with logical expiration. it is encoded as
+//
+//	v<expired_at_unix_milli>|<encoded value>
+//	n<expired_at_unix_milli>|
+//
+// for value found and not found
+type entry[V any] struct {
+	value     V
+	found     bool
+	expiredAt time.Time
+}
+
+func (e entry[V]) expired(t time.Time) bool {
+	return !t.Before(e.expiredAt)
+}
+
+func (e entry[V]) err(k any) error {
+	if e.found {
+		return nil
+	}
+	return codex.Errorf(ERROR__NOT_FOUND, "cache: %v", k)
+}
+
+func (c *Cache[K, V]) encode(e entry[V]) (string, error) {
+	b := strings.Builder{}
+	if e.found {
+		b.WriteByte('v')
+	} else {
+		b.WriteByte('n')
+	}
+	b.WriteString(strconv.FormatInt(e.expiredAt.UnixMilli(), 10))
+	b.WriteByte('|')
+	if e.found {
+		data, err := c.codec.Marshal(e.value)
+		if err != nil {
+			return "", codex.Wrap(ERROR__CODEC_FAILED, err)
+		}
+		b.Write(data)
+	}
+	return b.String(), nil
+}
+
+func (c *Cache[K, V]) decode(raw string) (e entry[V], ok bool) {
+	if len(raw) < 2 || (raw[0] != 'v' && raw[0] != 'n') {
+		return e, false
+	}
+	i := strings.IndexByte(raw, '|')
+	if i < 0 {
+		return e, false
+	}
+	ms, err := strconv.ParseInt(raw[1:i], 10, 64)
+	if err != nil {
+		return e, false
+	}
+	e.expiredAt = time.UnixMilli(ms)
+	e.found = raw[0] == 'v'
+	if e.found {
+		if err = c.codec.Unmarshal([]byte(raw[i+1:]), &e.value); err != nil {
+			return e, false
+		}
+	}
+	return e, true
+}
@@ -0,0 +1,293 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/xoctopus/x/testx"
+
+	. "github.com/xoctopus/confx/pkg/types/cache"
+	"github.com/xoctopus/confx/pkg/types/kg"
+)
+
+type entry struct {
+	val       string
+	expiredAt time.Time
+}
+
+// store is a kv.Store with expiration for testing
+type store struct {
+	mtx sync.Mutex
+	m   map[string]entry
+}
+
+func (s *store) get(k string) (entry, bool) {
+	e, ok := s.m[k]
+	if ok && !e.expiredAt.IsZero() && time.Now().After(e.expiredAt) {
+		delete(s.m, k)
+		return entry{}, false
+	}
+	return e, ok
+}
+
+func (s *store) Get(_ context.Context, k string) (string, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	e, ok := s.get(k)
+	return e.val, ok, nil
+}
+
+func (s *store) Set(_ context.Context, k, v string, ttl time.Duration) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	e := entry{val: v}
+	if ttl > 0 {
+		e.expiredAt = time.Now().Add(ttl)
+	}
+	s.m[k] = e
+	return nil
+}
+
+func (s *store) SetNX(ctx context.Context, k, v string, ttl time.Duration) (bool, error) {
+	if _, ok, _ := s.Get(ctx, k); ok {
+		return false, nil
+	}
+	return true, s.Set(ctx, k, v, ttl)
+}
+
+func (s *store) Del(_ context.Context, k string) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, ok := s.get(k)
+	delete(s.m, k)
+	return ok, nil
+}
+
+func (s *store) TTL(_ context.Context, k string) (time.Duration, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	e, ok := s.get(k)
+	if !ok || e.expiredAt.IsZero() {
+		return 0, ok, nil
+	}
+	return time.Until(e.expiredAt), true, nil
+}
+
+// executor is a store supports MGET
+type executor struct {
+	*store
+	mget atomic.Int32
+}
+
+func (x *executor) Key(k string) string { return k }
+
+func (x *executor) Exec(ctx context.Context, cmd string, args ...any) (any, error) {
+	if cmd != "MGET" {
+		return nil, errors.New("unsupported")
+	}
+	x.mget.Add(1)
+	vs := make([]any, 0, len(args))
+	for _, arg := range args {
+		if v, ok, _ := x.Get(ctx, arg.(string)); ok {
+			vs = append(vs, v)
+		} else {
+			vs = append(vs, nil)
+		}
+	}
+	return vs, nil
+}
+
+func newKeyGen() *kg.KeyGen {
+	g := &kg.KeyGen{}
+	g.Init("svc-cache")
+	return g
+}
+
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type source struct {
+	loads atomic.Int32
+	delay time.Duration
+}
+
+func (s *source) load(_ context.Context, id int) (User, error) {
+	s.loads.Add(1)
+	time.Sleep(s.delay)
+	if id <= 0 {
+		return User{}, errors.New("user not found")
+	}
+	return User{ID: id, Name: "user" + strconv.Itoa(id)}, nil
+}
+
+func isNotFound(err error) bool {
+	return err.Error() == "user not found"
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("GetAndSet", func(t *testing.T) {
+		s, src := &store{m: map[string]entry{}}, &source{}
+		c := New(s, newKeyGen(), "USER", src.load, WithTTL(time.Minute), WithJitter(time.Second))
+		Expect(t, c.Key(1), Equal("SVC_CACHE:PEER:USER:1"))
+
+		u, err := c.Get(ctx, 1)
+		Expect(t, err, Succeed())
+		Expect(t, u, Equal(User{ID: 1, Name: "user1"}))
+		u, err = c.Get(ctx, 1)
+		Expect(t, err, Succeed())
+		Expect(t, u.ID, Equal(1))
+		Expect(t, src.loads.Load(), Equal(int32(1)))
+
+		ttl, exists, _ := s.TTL(ctx, c.Key(1))
+		Expect(t, exists, BeTrue())
+		Expect(t, ttl > time.Minute-time.Second && ttl <= time.Minute+time.Second, BeTrue())
+
+		Expect(t, c.Set(ctx, 1, User{ID: 1, Name: "updated"}), Succeed())
+		u, _ = c.Get(ctx, 1)
+		Expect(t, u.Name, Equal("updated"))
+
+		Expect(t, c.Delete(ctx, 1), Succeed())
+		u, _ = c.Get(ctx, 1)
+		Expect(t, u.Name, Equal("user1"))
+		Expect(t, src.loads.Load(), Equal(int32(2)))
+	})
+
+	t.Run("Singleflight", func(t *testing.T) {
+		src := &source{delay: 50 * time.Millisecond}
+		c := New(&store{m: map[string]entry{}}, newKeyGen(), "USER", src.load)
+
+		wg := sync.WaitGroup{}
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				u, err := c.Get(ctx, 2)
+				Expect(t, err, Succeed())
+				Expect(t, u.ID, Equal(2))
+			}()
+		}
+		wg.Wait()
+		Expect(t, src.loads.Load(), Equal(int32(1)))
+	})
+
+	t.Run("CallerCanceled", func(t *testing.T) {
+		src := &source{delay: 50 * time.Millisecond}
+		c := New(&store{m: map[string]entry{}}, newKeyGen(), "USER", src.load)
+
+		timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err := c.Get(timeout, 3)
+		Expect(t, errors.Is(err, context.DeadlineExceeded), BeTrue())
+
+		// loading is not canceled by the first caller
+		u, err := c.Get(ctx, 3)
+		Expect(t, err, Succeed())
+		Expect(t, u.ID, Equal(3))
+		Expect(t, src.loads.Load(), Equal(int32(1)))
+	})
+
+	t.Run("NegativeCaching", func(t *testing.T) {
+		src := &source{}
+		c := New(
+			&store{m: map[string]entry{}}, newKeyGen(), "USER", src.load,
+			WithNotFound(isNotFound), WithNegativeTTL(50*time.Millisecond),
+		)
+
+		_, err := c.Get(ctx, 0)
+		Expect(t, err, IsCodeError(ERROR__NOT_FOUND))
+		_, err = c.Get(ctx, 0)
+		Expect(t, err, IsCodeError(ERROR__NOT_FOUND))
+		Expect(t, src.loads.Load(), Equal(int32(1)))
+
+		time.Sleep(60 * time.Millisecond)
+		_, err = c.Get(ctx, 0)
+		Expect(t, err, IsCodeError(ERROR__NOT_FOUND))
+		Expect(t, src.loads.Load(), Equal(int32(2)))
+
+		// negative caching disabled
+		c = New(&store{m: map[string]entry{}}, newKeyGen(), "USER", src.load, WithNotFound(isNotFound))
+		_, _ = c.Get(ctx, 0)
+		_, _ = c.Get(ctx, 0)
+		Expect(t, src.loads.Load(), Equal(int32(4)))
+	})
+
+	t.Run("StaleWhileRevalidate", func(t *testing.T) {
+		src := &source{}
+		c := New(
+			&store{m: map[string]entry{}}, newKeyGen(), "USER", src.load,
+			WithTTL(30*time.Millisecond), WithStaleTTL(time.Second),
+		)
+		Expect(t, c.Set(ctx, 4, User{ID: 4, Name: "stale"}), Succeed())
+
+		time.Sleep(40 * time.Millisecond)
+		u, err := c.Get(ctx, 4)
+		Expect(t, err, Succeed())
+		Expect(t, u.Name, Equal("stale"))
+
+		time.Sleep(20 * time.Millisecond)
+		u, err = c.Get(ctx, 4)
+		Expect(t, err, Succeed())
+		Expect(t, u.Name, Equal("user4"))
+		Expect(t, src.loads.Load(), Equal(int32(1)))
+	})
+
+	t.Run("MGet", func(t *testing.T) {
+		src := &source{}
+		x := &executor{store: &store{m: map[string]entry{}}}
+		c := New(x, newKeyGen(), "USER", src.load, WithNotFound(isNotFound))
+		Expect(t, c.Set(ctx, 1, User{ID: 1, Name: "cached"}), Succeed())
+
+		values, err := c.MGet(ctx, 1, 2, 0)
+		Expect(t, err, Succeed())
+		Expect(t, values, Equal(map[int]User{
+			1: {ID: 1, Name: "cached"},
+			2: {ID: 2, Name: "user2"},
+		}))
+		Expect(t, x.mget.Load(), Equal(int32(1)))
+		Expect(t, src.loads.Load(), Equal(int32(2)))
+
+		// without executor
+		c = New(x.store, newKeyGen(), "USER", src.load, WithNotFound(isNotFound))
+		values, err = c.MGet(ctx, 1, 2)
+		Expect(t, err, Succeed())
+		Expect(t, values, HaveLen[map[int]User](2))
+		Expect(t, src.loads.Load(), Equal(int32(2)))
+	})
+
+	t.Run("Codec", func(t *testing.T) {
+		type Name string
+		s := &store{m: map[string]entry{}}
+		c := New[string, Name](s, newKeyGen(), "NAME", nil).WithCodec(String[Name]{})
+
+		_, err := c.Get(ctx, "any")
+		Expect(t, err, IsCodeError(ERROR__LOADER_REQUIRED))
+
+		Expect(t, c.Set(ctx, "a", "alice"), Succeed())
+		raw, _, _ := s.Get(ctx, c.Key("a"))
+		Expect(t, raw[len(raw)-6:], Equal("|alice"))
+		v, err := c.Get(ctx, "a")
+		Expect(t, err, Succeed())
+		Expect(t, v, Equal(Name("alice")))
+	})
+	t.Run("NilInterface", func(t *testing.T) {
+		c := New(&store{m: map[string]entry{}}, newKeyGen(), "STRINGER", func(context.Context, int) (fmt.Stringer, error) {
+			return nil, nil
+		})
+		v, err := c.Get(ctx, 1)
+		Expect(t, err, Succeed())
+		Expect(t, v == nil, BeTrue())
+	})
+	t.Run("InvalidDomain", func(t *testing.T) {
+		ExpectPanic[error](t, func() { New(&store{m: map[string]entry{}}, newKeyGen(), "USER:ID", (&source{}).load) })
+	})
+}
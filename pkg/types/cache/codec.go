@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"encoding/json"
+)
+
+// Codec serializes cached values
+type Codec[V any] interface {
+	Marshal(V) ([]byte, error)
+	Unmarshal([]byte, *V) error
+}
+
+// JSON is the default codec encodes value by encoding/json
+type JSON[V any] struct{}
+
+func (JSON[V]) Marshal(v V) ([]byte, error) { return json.Marshal(v) }
+
+func (JSON[V]) Unmarshal(data []byte, v *V) error { return json.Unmarshal(data, v) }
+
+// String codec caches string value as is
+type String[V ~string] struct{}
+
+func (String[V]) Marshal(v V) ([]byte, error) { return []byte(v), nil }
+
+func (String[V]) Unmarshal(data []byte, v *V) error {
+	*v = V(data)
+	return nil
+}
@@ -0,0 +1,12 @@
+package cache
+
+// Error presents error codes of cache
+// +genx:code
+type Error int8
+
+const (
+	ERROR_UNDEFINED        Error = iota
+	ERROR__NOT_FOUND             // value not found
+	ERROR__CODEC_FAILED          // failed to encode or decode value
+	ERROR__LOADER_REQUIRED       // loader is required
+)
@@ -0,0 +1,69 @@
+package types
+
+import (
+	"reflect"
+
+	"github.com/xoctopus/x/reflectx"
+)
+
+// Dependent is implemented by components which must be initialized after other
+// components. names refer to sibling fields or configurations, such as `Log` or
+// `Config.Otel`, and appx initializes dependencies first and closes them last.
+type Dependent interface {
+	DependsOn() []string
+}
+
+func IsDependent(v any) bool {
+	switch x := v.(type) {
+	case Dependent:
+		return true
+	case reflect.Value:
+		x = reflectx.IndirectNew(v)
+		if x == reflectx.InvalidValue {
+			return false
+		}
+		if x.CanInterface() {
+			if IsDependent(x.Interface()) {
+				return true
+			}
+		}
+		if x.CanAddr() {
+			if x.Addr().CanInterface() {
+				if IsDependent(x.Addr().Interface()) {
+					return true
+				}
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// DependsOn returns names of components v depends on
+func DependsOn(v any) []string {
+	switch x := v.(type) {
+	case Dependent:
+		return x.DependsOn()
+	case reflect.Value:
+		x = reflectx.IndirectNew(v)
+		if x == reflectx.InvalidValue {
+			return nil
+		}
+		if x.CanInterface() {
+			if IsDependent(x.Interface()) {
+				return DependsOn(x.Interface())
+			}
+		}
+		if x.CanAddr() {
+			if x.Addr().CanInterface() {
+				if IsDependent(x.Addr().Interface()) {
+					return DependsOn(x.Addr().Interface())
+				}
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+}
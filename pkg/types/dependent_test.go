@@ -0,0 +1,31 @@
+package types_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/types"
+)
+
+type Dependent struct{}
+
+func (Dependent) DependsOn() []string { return []string{"Log", "Otel"} }
+
+func TestDependsOn(t *testing.T) {
+	for _, v := range [...]struct {
+		v    any
+		deps []string
+	}{
+		{Dependent{}, []string{"Log", "Otel"}},
+		{&Dependent{}, []string{"Log", "Otel"}},
+		{reflect.ValueOf(&struct{ V Dependent }{}).Elem().Field(0), []string{"Log", "Otel"}},
+		{reflect.ValueOf(&struct{ V *Dependent }{}).Elem().Field(0), []string{"Log", "Otel"}},
+		{reflect.ValueOf(&struct{ v Dependent }{}).Elem().Field(0), nil},
+		{struct{}{}, nil},
+	} {
+		Expect(t, types.IsDependent(v.v), Equal(v.deps != nil))
+		Expect(t, types.DependsOn(v.v), Equal(v.deps))
+	}
+}
@@ -45,7 +45,13 @@ func (e *Endpoint[Option]) SetDefault() {
 	}
 }
 
-func (e *Endpoint[Option]) Init() (err error) {
+func (e *Endpoint[Option]) Init() error {
+	return e.InitByContext(context.Background())
+}
+
+// InitByContext initializes endpoint, ctx is passed to SecretResolver when Auth
+// initialized
+func (e *Endpoint[Option]) InitByContext(ctx context.Context) (err error) {
 	e.addr, err = url.Parse(e.Address)
 	if err != nil {
 		return
@@ -78,7 +84,7 @@ func (e *Endpoint[Option]) Init() (err error) {
 		e.Auth.Password = Password(password)
 		e.Auth.Username = e.addr.User.Username()
 	}
-	if err = e.Auth.Init(); err != nil {
+	if err = e.Auth.InitByContext(ctx); err != nil {
 		return err
 	}
 	if !e.Auth.IsZero() {
@@ -0,0 +1,11 @@
+package kg
+
+// Error presents error codes of key generator
+// +genx:code
+type Error int8
+
+const (
+	ERROR_UNDEFINED       Error = iota
+	ERROR__INVALID_KEY          // key is not generated by kg
+	ERROR__INVALID_DOMAIN       // domain contains ':' or starts with tenant marker
+)
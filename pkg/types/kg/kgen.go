@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/oklog/ulid/v2"
+	"github.com/xoctopus/x/codex"
 	"github.com/xoctopus/x/misc/must"
 )
 
@@ -18,9 +19,18 @@ import (
 //   - domain:   business domain chosen by the caller.
 //   - biz:      domain-specific business identifier.
 //
-// Namespace prefixes (e.g. site_id) are intentionally excluded;
-// callers who need tenant isolation should prepend their own prefix
-// via [Option] or wrap [KeyGen].
+// Two optional segments can be added:
+//
+//	[{prefix}:]{creator}:{audience}[:@{tenant}]:{domain}:{biz}
+//
+//   - prefix:   a static namespace segment set by [WithPrefix].
+//   - tenant:   the tenant segment marked by [TenantMarker], set by the
+//     tenant view of [KeyGen.WithTenant] or [KeyGen.ForTenant].
+//
+// Segments except biz must not contain ':', so that a key can be split back
+// into segments by [Parse]. biz is the remaining of key and may contain ':'.
+// domain is not checked when generating keys, it should be validated by
+// [ValidateDomain] when registered, eg: by cache.New.
 
 // Audience reserved words.
 const (
@@ -50,6 +60,7 @@ type KeyGen struct {
 	prefix  string
 	creator string
 	inst    string
+	tenant  string
 }
 
 // Init locks the creator identity and generates a per-process ULID instance ID.
@@ -59,6 +70,7 @@ func (g *KeyGen) Init(creator string, opts ...Option) {
 		for _, o := range opts {
 			o(g)
 		}
+		must.BeTrueF(!strings.Contains(g.prefix, ":"), "kgen: prefix contains ':' %q", g.prefix)
 		g.creator = NormalizeCreator(creator)
 		if g.inst == "" {
 			g.inst = ulid.Make().String()
@@ -111,10 +123,23 @@ func (g *KeyGen) GlobalKeyFrom(creatorIdentity, domain string, k any) string {
 }
 
 func (g *KeyGen) join(creator, audience, domain string, k any) string {
-	if g.prefix != "" {
-		return fmt.Sprintf("%s:%s:%s:%s:%v", g.prefix, creator, audience, domain, k)
+	return Parts{
+		Prefix:   g.prefix,
+		Creator:  creator,
+		Audience: audience,
+		Tenant:   g.tenant,
+		Domain:   domain,
+		Biz:      fmt.Sprint(k),
+	}.String()
+}
+
+// ValidateDomain returns ERROR__INVALID_DOMAIN if domain contains ':' or starts
+// with TenantMarker, which makes keys unable to be parsed.
+func ValidateDomain(domain string) error {
+	if strings.Contains(domain, ":") || strings.HasPrefix(domain, TenantMarker) {
+		return codex.Errorf(ERROR__INVALID_DOMAIN, "kgen: %q", domain)
 	}
-	return fmt.Sprintf("%s:%s:%s:%v", creator, audience, domain, k)
+	return nil
 }
 
 // NormalizeSegment normalises a key segment: TrimSpace, ToUpper, '-' to '_'.
@@ -127,6 +152,7 @@ func NormalizeSegment(name string) string {
 func NormalizeCreator(name string) string {
 	c := NormalizeSegment(name)
 	must.BeTrueF(c != "", "kgen: creator is empty")
+	must.BeTrueF(!strings.Contains(c, ":"), "kgen: creator contains ':' %q", c)
 	must.BeTrueF(c != AudiencePeer && c != AudienceAny, "kgen: creator is reserved %q", c)
 	return c
 }
@@ -136,6 +162,7 @@ func NormalizeCreator(name string) string {
 func NormalizeAudience(name string) string {
 	a := NormalizeSegment(name)
 	must.BeTrueF(a != "", "kgen: audience is empty")
+	must.BeTrueF(!strings.Contains(a, ":"), "kgen: audience contains ':' %q", a)
 	must.BeTrueF(a != AudiencePeer && a != AudienceAny, "kgen: audience is reserved %q", a)
 	return a
 }
@@ -1,6 +1,7 @@
 package kg_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	_ "unsafe"
@@ -119,3 +120,105 @@ func TestKeyGenReservedCreator(t *testing.T) {
 
 	ExpectPanic[error](t, func() { g.Init("PEER") })
 }
+
+func TestKeyGenTenant(t *testing.T) {
+	var g kg.KeyGen
+	t.Cleanup(func() { reset(&g) })
+
+	g.Init("svc-alpha", kg.WithPrefix("1050"))
+
+	t.Run("WithTenant from context", func(t *testing.T) {
+		Expect(t, g.WithTenant(context.Background()), Equal(&g))
+
+		ctx := kg.ContextWithTenant(context.Background(), "site-7")
+		v := g.WithTenant(ctx)
+		Expect(t, v.Tenant(), Equal("SITE_7"))
+		Expect(t, v.Creator(), Equal(g.Creator()))
+		Expect(t, v.InstanceID(), Equal(g.InstanceID()))
+		Expect(t, v.SharedKey("SESSION", int64(42)),
+			Equal("1050:SVC_ALPHA:PEER:@SITE_7:SESSION:42"))
+		Expect(t, g.SharedKey("SESSION", int64(42)),
+			Equal("1050:SVC_ALPHA:PEER:SESSION:42"))
+
+		// view is initialized
+		v.Init("other-service")
+		Expect(t, v.Creator(), Equal("SVC_ALPHA"))
+	})
+
+	t.Run("invalid segments panic", func(t *testing.T) {
+		ExpectPanic[error](t, func() { _ = g.ForTenant("") })
+		ExpectPanic[error](t, func() { _ = g.ForTenant("a:b") })
+		ExpectPanic[error](t, func() { _ = kg.NormalizeCreator("svc:a") })
+		ExpectPanic[error](t, func() { _ = kg.NormalizeAudience("svc:a") })
+
+		var x kg.KeyGen
+		ExpectPanic[error](t, func() { x.Init("svc", kg.WithPrefix("a:b")) })
+	})
+
+	t.Run("ValidateDomain", func(t *testing.T) {
+		Expect(t, kg.ValidateDomain("SESSION"), Succeed())
+		Expect(t, kg.ValidateDomain("A:B"), IsCodeError(kg.ERROR__INVALID_DOMAIN))
+		Expect(t, kg.ValidateDomain("@A"), IsCodeError(kg.ERROR__INVALID_DOMAIN))
+	})
+}
+
+func TestParse(t *testing.T) {
+	var g kg.KeyGen
+	t.Cleanup(func() { reset(&g) })
+	g.Init("svc-alpha")
+
+	cases := []struct {
+		name  string
+		key   string
+		parts kg.Parts
+	}{
+		{
+			name:  "Key",
+			key:   g.Key("SESSION", 1),
+			parts: kg.Parts{Creator: "SVC_ALPHA", Audience: g.InstanceID(), Domain: "SESSION", Biz: "1"},
+		},
+		{
+			name:  "SharedKey with ':' in biz",
+			key:   g.SharedKey("RATELIMIT", "api:127.0.0.1"),
+			parts: kg.Parts{Creator: "SVC_ALPHA", Audience: kg.AudiencePeer, Domain: "RATELIMIT", Biz: "api:127.0.0.1"},
+		},
+		{
+			name:  "Tenant",
+			key:   g.ForTenant("t1").GlobalKey("SESSION", "a:b"),
+			parts: kg.Parts{Creator: "SVC_ALPHA", Audience: kg.AudienceAny, Tenant: "T1", Domain: "SESSION", Biz: "a:b"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			parts, err := g.Parse(c.key)
+			Expect(t, err, Succeed())
+			Expect(t, parts, Equal(c.parts))
+			Expect(t, parts.String(), Equal(c.key))
+		})
+	}
+
+	t.Run("Prefix", func(t *testing.T) {
+		parts, err := kg.Parse("1050:SVC_ALPHA:PEER:@T1:SESSION:42", "1050")
+		Expect(t, err, Succeed())
+		Expect(t, parts, Equal(kg.Parts{
+			Prefix: "1050", Creator: "SVC_ALPHA", Audience: kg.AudiencePeer,
+			Tenant: "T1", Domain: "SESSION", Biz: "42",
+		}))
+		Expect(t, parts.IsShared(), BeTrue())
+		Expect(t, parts.IsGlobal(), BeFalse())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, key := range []string{
+			"SVC_ALPHA:PEER:SESSION",
+			":PEER:SESSION:1",
+			"SVC_ALPHA:PEER:@:SESSION:1",
+			"SVC_ALPHA:PEER:@T1:SESSION",
+		} {
+			_, err := kg.Parse(key, "")
+			Expect(t, err, IsCodeError(kg.ERROR__INVALID_KEY))
+		}
+		_, err := kg.Parse("SVC_ALPHA:PEER:SESSION:1", "1050")
+		Expect(t, err, IsCodeError(kg.ERROR__INVALID_KEY))
+	})
+}
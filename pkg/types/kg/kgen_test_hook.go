@@ -8,4 +8,5 @@ func reset(g *KeyGen) {
 	g.prefix = ""
 	g.creator = ""
 	g.inst = ""
+	g.tenant = ""
 }
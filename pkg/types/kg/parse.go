@@ -0,0 +1,81 @@
+package kg
+
+import (
+	"strings"
+
+	"github.com/xoctopus/x/codex"
+)
+
+// Parts presents segments of a key generated by KeyGen
+type Parts struct {
+	Prefix   string
+	Creator  string
+	Audience string
+	Tenant   string
+	Domain   string
+	Biz      string
+}
+
+// String joins segments to key
+func (p Parts) String() string {
+	b := strings.Builder{}
+	if p.Prefix != "" {
+		b.WriteString(p.Prefix)
+		b.WriteByte(':')
+	}
+	b.WriteString(p.Creator)
+	b.WriteByte(':')
+	b.WriteString(p.Audience)
+	b.WriteByte(':')
+	if p.Tenant != "" {
+		b.WriteString(TenantMarker)
+		b.WriteString(p.Tenant)
+		b.WriteByte(':')
+	}
+	b.WriteString(p.Domain)
+	b.WriteByte(':')
+	b.WriteString(p.Biz)
+	return b.String()
+}
+
+// IsShared reports if key is shared by peers of creator
+func (p Parts) IsShared() bool { return p.Audience == AudiencePeer }
+
+// IsGlobal reports if key is shared to any service
+func (p Parts) IsGlobal() bool { return p.Audience == AudienceAny }
+
+// Parse splits key generated by KeyGen initialized with prefix back into
+// segments. prefix should be empty if KeyGen has no prefix. ERROR__INVALID_KEY
+// is returned if key is not well-formed.
+func Parse(key, prefix string) (Parts, error) {
+	p, rest := Parts{}, key
+	if prefix != "" {
+		r, ok := strings.CutPrefix(rest, prefix+":")
+		if !ok {
+			return Parts{}, codex.Errorf(ERROR__INVALID_KEY, "kgen: prefix %q mismatched: %s", prefix, key)
+		}
+		p.Prefix, rest = prefix, r
+	}
+
+	segments := strings.SplitN(rest, ":", 4)
+	if len(segments) != 4 || segments[0] == "" || segments[1] == "" {
+		return Parts{}, codex.Errorf(ERROR__INVALID_KEY, "kgen: %s", key)
+	}
+	p.Creator, p.Audience = segments[0], segments[1]
+
+	if tenant, ok := strings.CutPrefix(segments[2], TenantMarker); ok {
+		domain, biz, found := strings.Cut(segments[3], ":")
+		if tenant == "" || !found {
+			return Parts{}, codex.Errorf(ERROR__INVALID_KEY, "kgen: %s", key)
+		}
+		p.Tenant, p.Domain, p.Biz = tenant, domain, biz
+	} else {
+		p.Domain, p.Biz = segments[2], segments[3]
+	}
+	return p, nil
+}
+
+// Parse splits key generated by g or other KeyGen with the same prefix
+func (g *KeyGen) Parse(key string) (Parts, error) {
+	return Parse(key, g.prefix)
+}
@@ -0,0 +1,59 @@
+package kg
+
+import (
+	"context"
+	"strings"
+
+	"github.com/xoctopus/x/contextx"
+	"github.com/xoctopus/x/misc/must"
+)
+
+// TenantMarker marks the tenant segment of key
+const TenantMarker = "@"
+
+type tCtxTenant struct{}
+
+var (
+	// ContextWithTenant injects tenant identity into context
+	ContextWithTenant = contextx.With[tCtxTenant, string]
+	// TenantFromContext returns tenant identity from context
+	TenantFromContext = contextx.From[tCtxTenant, string]
+	// CarryTenant returns a context carrier of tenant identity
+	CarryTenant = contextx.Carry[tCtxTenant, string]
+)
+
+// NormalizeTenant normalizes tenant identity for the tenant segment; panics on
+// empty or containing ':'.
+func NormalizeTenant(name string) string {
+	t := NormalizeSegment(name)
+	must.BeTrueF(t != "", "kgen: tenant is empty")
+	must.BeTrueF(!strings.Contains(t, ":"), "kgen: tenant contains ':' %q", t)
+	return t
+}
+
+// Tenant returns the tenant identity of view, empty if g is not a tenant view.
+func (g *KeyGen) Tenant() string { return g.tenant }
+
+// WithTenant returns the tenant view of g by tenant identity from ctx. g itself
+// is returned if ctx carries no tenant.
+func (g *KeyGen) WithTenant(ctx context.Context) *KeyGen {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok || strings.TrimSpace(tenant) == "" {
+		return g
+	}
+	return g.ForTenant(tenant)
+}
+
+// ForTenant returns the tenant view of g. keys generated by the view carry the
+// tenant segment: [{prefix}:]{creator}:{audience}:@{tenant}:{domain}:{biz}.
+// The view shares creator and instance identity with g.
+func (g *KeyGen) ForTenant(tenant string) *KeyGen {
+	v := &KeyGen{
+		prefix:  g.prefix,
+		creator: g.creator,
+		inst:    g.inst,
+		tenant:  NormalizeTenant(tenant),
+	}
+	v.once.Do(func() {})
+	return v
+}
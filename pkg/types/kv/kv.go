@@ -29,3 +29,28 @@ type Store interface {
 	//  3. key exists with expiration:   ttl > 0,  exists == true
 	TTL(ctx context.Context, key string) (ttl time.Duration, exists bool, err error)
 }
+
+// Atomic is implemented by stores supporting conditional operations atomically
+// without scripts, such as memkv.Store. helpers built on kv.Store use them for
+// check-then-act operations when scripts of Executor are not available.
+type Atomic interface {
+	// CompareAndSet writes key to val only if key holds old.
+	// ok is false when the key does not exist or holds another value.
+	// ttl <= 0 means the key has no expiration.
+	CompareAndSet(ctx context.Context, key, old, val string, ttl time.Duration) (ok bool, err error)
+	// CompareAndDel deletes key only if key holds old.
+	// deleted is false when the key does not exist or holds another value.
+	CompareAndDel(ctx context.Context, key, old string) (deleted bool, err error)
+	// Incr increments integer value of key by one and returns the result.
+	// A key does not exist is regarded as 0, and expiration of key is retained.
+	Incr(ctx context.Context, key string) (int64, error)
+}
+
+// InProcess is implemented by stores living in current process, such as
+// memkv.Store. these stores don't run lua scripts even if they implement
+// Executor, so helpers built on kv.Store should not execute scripts on them.
+// each operation of these stores is atomic, but a sequence of operations is
+// not, helpers should use Atomic or serialize the sequence by themselves.
+type InProcess interface {
+	InProcess()
+}
@@ -0,0 +1,12 @@
+package memkv
+
+// Error presents error codes of in-memory kv store
+// +genx:code
+type Error int8
+
+const (
+	ERROR_UNDEFINED            Error = iota
+	ERROR__UNSUPPORTED_COMMAND       // command is not supported
+	ERROR__INVALID_ARGUMENTS         // invalid command arguments
+	ERROR__VALUE_NOT_INTEGER         // value is not an integer or out of range
+)
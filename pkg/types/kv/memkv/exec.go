@@ -0,0 +1,252 @@
+package memkv
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xoctopus/x/codex"
+)
+
+// Key returns key with Option.Prefix
+func (s *Store) Key(k string) string {
+	if s.Prefix == "" {
+		return k
+	}
+	return s.Prefix + ":" + k
+}
+
+// Exec executes a subset of redis commands, command name is case-insensitive.
+// replies are typed as go-redis replies: status and bulk string as string,
+// integer as int64, array as []any. different from go-redis, a nil reply is
+// returned as nil without error.
+//
+// Supported commands:
+//
+//	PING
+//	GET key
+//	MGET key [key ...]
+//	SET key value [EX seconds | PX milliseconds] [NX | XX]
+//	SETNX key value
+//	DEL key [key ...]
+//	EXISTS key [key ...]
+//	EXPIRE key seconds
+//	PEXPIRE key milliseconds
+//	TTL key
+//	PTTL key
+//	INCR key
+//	INCRBY key increment
+//	DECR key
+//	DECRBY key decrement
+func (s *Store) Exec(_ context.Context, cmd string, args ...any) (any, error) {
+	argv := make([]string, len(args))
+	for i, arg := range args {
+		argv[i] = toString(arg)
+	}
+	cmd = strings.ToUpper(cmd)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := time.Now()
+	switch cmd {
+	case "PING":
+		return "PONG", nil
+	case "GET":
+		if len(argv) != 1 {
+			return nil, invalid(cmd)
+		}
+		if i, ok := s.get(argv[0], now); ok {
+			return i.val, nil
+		}
+		return nil, nil
+	case "MGET":
+		if len(argv) == 0 {
+			return nil, invalid(cmd)
+		}
+		vals := make([]any, len(argv))
+		for n, k := range argv {
+			if i, ok := s.get(k, now); ok {
+				vals[n] = i.val
+			}
+		}
+		return vals, nil
+	case "SET":
+		return s.execSet(now, argv)
+	case "SETNX":
+		if len(argv) != 2 {
+			return nil, invalid(cmd)
+		}
+		if _, ok := s.get(argv[0], now); ok {
+			return int64(0), nil
+		}
+		s.set(argv[0], item{val: argv[1]})
+		return int64(1), nil
+	case "DEL", "EXISTS":
+		if len(argv) == 0 {
+			return nil, invalid(cmd)
+		}
+		n := int64(0)
+		for _, k := range argv {
+			if _, ok := s.get(k, now); ok {
+				n++
+				if cmd == "DEL" {
+					delete(s.items, k)
+				}
+			}
+		}
+		return n, nil
+	case "EXPIRE", "PEXPIRE":
+		if len(argv) != 2 {
+			return nil, invalid(cmd)
+		}
+		d, err := strconv.ParseInt(argv[1], 10, 64)
+		if err != nil {
+			return nil, codex.Wrap(ERROR__VALUE_NOT_INTEGER, err)
+		}
+		i, ok := s.get(argv[0], now)
+		if !ok {
+			return int64(0), nil
+		}
+		unit := time.Second
+		if cmd == "PEXPIRE" {
+			unit = time.Millisecond
+		}
+		if d <= 0 {
+			delete(s.items, argv[0])
+			return int64(1), nil
+		}
+		i.expiredAt = now.Add(time.Duration(d) * unit)
+		s.set(argv[0], i)
+		return int64(1), nil
+	case "TTL", "PTTL":
+		if len(argv) != 1 {
+			return nil, invalid(cmd)
+		}
+		i, ok := s.get(argv[0], now)
+		switch {
+		case !ok:
+			return int64(-2), nil
+		case i.expiredAt.IsZero():
+			return int64(-1), nil
+		case cmd == "TTL":
+			return int64(math.Round(i.expiredAt.Sub(now).Seconds())), nil
+		default:
+			return i.expiredAt.Sub(now).Milliseconds(), nil
+		}
+	case "INCR", "DECR", "INCRBY", "DECRBY":
+		return s.execIncr(now, cmd, argv)
+	default:
+		return nil, codex.Errorf(ERROR__UNSUPPORTED_COMMAND, "memkv: %s", cmd)
+	}
+}
+
+// execSet executes `SET key value [EX seconds | PX milliseconds] [NX | XX]`
+func (s *Store) execSet(now time.Time, argv []string) (any, error) {
+	if len(argv) < 2 {
+		return nil, invalid("SET")
+	}
+
+	var (
+		ttl    time.Duration
+		nx, xx bool
+	)
+	for n := 2; n < len(argv); n++ {
+		switch opt := strings.ToUpper(argv[n]); opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX", "PX":
+			if ttl != 0 || n+1 >= len(argv) {
+				return nil, invalid("SET")
+			}
+			n++
+			d, err := strconv.ParseInt(argv[n], 10, 64)
+			if err != nil || d <= 0 {
+				return nil, invalid("SET")
+			}
+			ttl = time.Duration(d) * time.Second
+			if opt == "PX" {
+				ttl = time.Duration(d) * time.Millisecond
+			}
+		default:
+			return nil, invalid("SET")
+		}
+	}
+	if nx && xx {
+		return nil, invalid("SET")
+	}
+
+	_, exists := s.get(argv[0], now)
+	if (nx && exists) || (xx && !exists) {
+		return nil, nil
+	}
+	s.set(argv[0], item{val: argv[1], expiredAt: expiration(now, ttl)})
+	return "OK", nil
+}
+
+// execIncr executes INCR/DECR/INCRBY/DECRBY, ttl of key is retained
+func (s *Store) execIncr(now time.Time, cmd string, argv []string) (any, error) {
+	delta := int64(1)
+	switch cmd {
+	case "INCR", "DECR":
+		if len(argv) != 1 {
+			return nil, invalid(cmd)
+		}
+	default:
+		if len(argv) != 2 {
+			return nil, invalid(cmd)
+		}
+		d, err := strconv.ParseInt(argv[1], 10, 64)
+		if err != nil {
+			return nil, codex.Wrap(ERROR__VALUE_NOT_INTEGER, err)
+		}
+		delta = d
+	}
+	if strings.HasPrefix(cmd, "DECR") {
+		if delta == math.MinInt64 {
+			return nil, codex.Errorf(ERROR__VALUE_NOT_INTEGER, "memkv: %s", cmd)
+		}
+		delta = -delta
+	}
+
+	i, _ := s.get(argv[0], now)
+	v := int64(0)
+	if i.val != "" {
+		x, err := strconv.ParseInt(i.val, 10, 64)
+		if err != nil {
+			return nil, codex.Wrap(ERROR__VALUE_NOT_INTEGER, err)
+		}
+		v = x
+	}
+	if (delta > 0 && v > math.MaxInt64-delta) || (delta < 0 && v < math.MinInt64-delta) {
+		return nil, codex.Errorf(ERROR__VALUE_NOT_INTEGER, "memkv: %s overflow", cmd)
+	}
+	v += delta
+	i.val = strconv.FormatInt(v, 10)
+	s.set(argv[0], i)
+	return v, nil
+}
+
+func invalid(cmd string) error {
+	return codex.Errorf(ERROR__INVALID_ARGUMENTS, "memkv: %s", cmd)
+}
+
+func toString(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case time.Duration:
+		return strconv.FormatInt(int64(x), 10)
+	case fmt.Stringer:
+		return x.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,246 @@
+// Package memkv provides an in-process kv.Store and kv.Executor for unit tests
+// and local development. It can also be used as a L1 cache in front of redis.
+//
+// Keys are expired lazily when accessed, and swept in background periodically
+// after Store.Init. SetNX has the same semantics as redis `SET NX`: an expired
+// key is regarded as not existed. Store implements kv.Atomic, so that helpers
+// can compare and update keys atomically without lua scripts.
+package memkv
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xoctopus/x/misc/must"
+	"github.com/xoctopus/x/textx"
+
+	"github.com/xoctopus/confx/pkg/types"
+	"github.com/xoctopus/confx/pkg/types/kv"
+)
+
+// Option presents in-memory store options
+type Option struct {
+	// Prefix is prepended to key by Store.Key as `<Prefix>:<key>`
+	Prefix string `url:",default="`
+	// SweepInterval interval of sweeping expired keys in background
+	SweepInterval types.Duration `url:",default=1m"`
+}
+
+func (o *Option) SetDefault() {
+	must.NoErrorV(textx.SetDefault(o))
+	if o.SweepInterval <= 0 {
+		o.SweepInterval = types.Duration(time.Minute)
+	}
+}
+
+// NewStore returns an in-memory store with default options
+func NewStore() *Store {
+	s := &Store{}
+	s.SetDefault()
+	return s
+}
+
+// Store is a thread-safe in-memory kv store with expiration
+type Store struct {
+	Option
+
+	mtx    sync.Mutex
+	items  map[string]item
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var (
+	_ kv.Store                            = (*Store)(nil)
+	_ kv.Executor                         = (*Store)(nil)
+	_ kv.Atomic                           = (*Store)(nil)
+	_ kv.InProcess                        = (*Store)(nil)
+	_ types.Defaulter                     = (*Store)(nil)
+	_ types.InitializerByContextWithError = (*Store)(nil)
+	_ types.ClosableWithError             = (*Store)(nil)
+)
+
+type item struct {
+	val       string
+	expiredAt time.Time
+}
+
+func (i item) expired(now time.Time) bool {
+	return !i.expiredAt.IsZero() && !now.Before(i.expiredAt)
+}
+
+// InProcess marks Store as kv.InProcess
+func (s *Store) InProcess() {}
+
+// Init starts sweeping expired keys in background
+func (s *Store) Init(_ context.Context) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.cancel != nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel, s.done = cancel, make(chan struct{})
+	go s.sweeping(ctx, time.Duration(s.SweepInterval), s.done)
+	return nil
+}
+
+// Close stops background sweeping. Store can still be used after closed.
+func (s *Store) Close() error {
+	s.mtx.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mtx.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+	return nil
+}
+
+// Len returns number of keys, including expired keys not swept yet
+func (s *Store) Len() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.items)
+}
+
+func (s *Store) sweeping(ctx context.Context, interval time.Duration, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep()
+		}
+	}
+}
+
+func (s *Store) sweep() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := time.Now()
+	for k, i := range s.items {
+		if i.expired(now) {
+			delete(s.items, k)
+		}
+	}
+}
+
+// get returns unexpired item of key, expired item is removed. it should be
+// called with lock held.
+func (s *Store) get(k string, now time.Time) (item, bool) {
+	i, ok := s.items[k]
+	if ok && i.expired(now) {
+		delete(s.items, k)
+		return item{}, false
+	}
+	return i, ok
+}
+
+// set should be called with lock held
+func (s *Store) set(k string, i item) {
+	if s.items == nil {
+		s.items = make(map[string]item)
+	}
+	s.items[k] = i
+}
+
+func expiration(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+func (s *Store) Get(_ context.Context, key string) (string, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	i, ok := s.get(key, time.Now())
+	return i.val, ok, nil
+}
+
+func (s *Store) Set(_ context.Context, key, val string, ttl time.Duration) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.set(key, item{val: val, expiredAt: expiration(time.Now(), ttl)})
+	return nil
+}
+
+func (s *Store) SetNX(_ context.Context, key, val string, ttl time.Duration) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := time.Now()
+	if _, ok := s.get(key, now); ok {
+		return false, nil
+	}
+	s.set(key, item{val: val, expiredAt: expiration(now, ttl)})
+	return true, nil
+}
+
+func (s *Store) Del(_ context.Context, key string) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	_, ok := s.get(key, time.Now())
+	delete(s.items, key)
+	return ok, nil
+}
+
+func (s *Store) TTL(_ context.Context, key string) (time.Duration, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := time.Now()
+	i, ok := s.get(key, now)
+	if !ok || i.expiredAt.IsZero() {
+		return 0, ok, nil
+	}
+	return i.expiredAt.Sub(now), true, nil
+}
+
+func (s *Store) CompareAndSet(_ context.Context, key, old, val string, ttl time.Duration) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := time.Now()
+	if i, ok := s.get(key, now); !ok || i.val != old {
+		return false, nil
+	}
+	s.set(key, item{val: val, expiredAt: expiration(now, ttl)})
+	return true, nil
+}
+
+func (s *Store) CompareAndDel(_ context.Context, key, old string) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if i, ok := s.get(key, time.Now()); !ok || i.val != old {
+		return false, nil
+	}
+	delete(s.items, key)
+	return true, nil
+}
+
+func (s *Store) Incr(_ context.Context, key string) (int64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	v, err := s.execIncr(time.Now(), "INCR", []string{key})
+	if err != nil {
+		return 0, err
+	}
+	return v.(int64), nil
+}
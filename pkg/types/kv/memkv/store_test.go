@@ -0,0 +1,219 @@
+package memkv_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/types"
+	. "github.com/xoctopus/confx/pkg/types/kv/memkv"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+
+	t.Run("Store", func(t *testing.T) {
+		_, exists, err := s.Get(ctx, "k")
+		Expect(t, err, Succeed())
+		Expect(t, exists, BeFalse())
+
+		Expect(t, s.Set(ctx, "k", "v", 0), Succeed())
+		v, exists, _ := s.Get(ctx, "k")
+		Expect(t, exists, BeTrue())
+		Expect(t, v, Equal("v"))
+		ttl, exists, _ := s.TTL(ctx, "k")
+		Expect(t, exists, BeTrue())
+		Expect(t, ttl, Equal(time.Duration(0)))
+
+		ok, _ := s.SetNX(ctx, "k", "v2", 0)
+		Expect(t, ok, BeFalse())
+
+		deleted, _ := s.Del(ctx, "k")
+		Expect(t, deleted, BeTrue())
+		deleted, _ = s.Del(ctx, "k")
+		Expect(t, deleted, BeFalse())
+		_, exists, _ = s.TTL(ctx, "k")
+		Expect(t, exists, BeFalse())
+	})
+
+	t.Run("Expiration", func(t *testing.T) {
+		ok, _ := s.SetNX(ctx, "ttl", "v", 30*time.Millisecond)
+		Expect(t, ok, BeTrue())
+		ttl, exists, _ := s.TTL(ctx, "ttl")
+		Expect(t, exists, BeTrue())
+		Expect(t, ttl > 0 && ttl <= 30*time.Millisecond, BeTrue())
+
+		time.Sleep(40 * time.Millisecond)
+		_, exists, _ = s.Get(ctx, "ttl")
+		Expect(t, exists, BeFalse())
+		// expired key is regarded as not existed
+		ok, _ = s.SetNX(ctx, "ttl", "v2", 0)
+		Expect(t, ok, BeTrue())
+	})
+
+	t.Run("SetNXConcurrently", func(t *testing.T) {
+		var (
+			wg  sync.WaitGroup
+			set atomic.Int32
+		)
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if ok, _ := s.SetNX(ctx, "nx", "v", time.Second); ok {
+					set.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		Expect(t, set.Load(), Equal(int32(1)))
+	})
+
+	t.Run("Sweep", func(t *testing.T) {
+		s := NewStore()
+		s.SweepInterval = types.Duration(10 * time.Millisecond)
+		Expect(t, s.Init(ctx), Succeed())
+		defer s.Close()
+
+		for _, k := range []string{"a", "b", "c"} {
+			Expect(t, s.Set(ctx, k, k, 20*time.Millisecond), Succeed())
+		}
+		Expect(t, s.Set(ctx, "d", "d", 0), Succeed())
+		Expect(t, s.Len(), Equal(4))
+
+		time.Sleep(60 * time.Millisecond)
+		Expect(t, s.Len(), Equal(1))
+		Expect(t, s.Close(), Succeed())
+	})
+}
+
+func TestStore_Exec(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	s.Prefix = "app"
+	Expect(t, s.Key("k"), Equal("app:k"))
+
+	exec := func(cmd string, args ...any) any {
+		v, err := s.Exec(ctx, cmd, args...)
+		Expect(t, err, Succeed())
+		return v
+	}
+
+	Expect(t, exec("ping"), Equal[any]("PONG"))
+	Expect(t, exec("GET", "k"), Equal[any](nil))
+	Expect(t, exec("SET", "k", "v"), Equal[any]("OK"))
+	Expect(t, exec("SET", "k", "v", "NX"), Equal[any](nil))
+	Expect(t, exec("SET", "x", "v", "XX"), Equal[any](nil))
+	Expect(t, exec("SET", "x", []byte("v"), "px", 1000, "nx"), Equal[any]("OK"))
+	Expect(t, exec("PTTL", "x").(int64) > 900, BeTrue())
+	Expect(t, exec("TTL", "x"), Equal[any](int64(1)))
+	Expect(t, exec("TTL", "k"), Equal[any](int64(-1)))
+	Expect(t, exec("TTL", "none"), Equal[any](int64(-2)))
+	Expect(t, exec("MGET", "k", "none", "x"), Equal[any]([]any{"v", nil, "v"}))
+	Expect(t, exec("SETNX", "k", "v"), Equal[any](int64(0)))
+	Expect(t, exec("SETNX", "y", "v"), Equal[any](int64(1)))
+	Expect(t, exec("EXISTS", "k", "x", "none"), Equal[any](int64(2)))
+	Expect(t, exec("EXPIRE", "k", 10), Equal[any](int64(1)))
+	Expect(t, exec("EXPIRE", "none", 10), Equal[any](int64(0)))
+	Expect(t, exec("PEXPIRE", "y", 0), Equal[any](int64(1)))
+	Expect(t, exec("DEL", "k", "y", "none"), Equal[any](int64(1)))
+
+	Expect(t, exec("INCR", "n"), Equal[any](int64(1)))
+	Expect(t, exec("INCRBY", "n", 10), Equal[any](int64(11)))
+	Expect(t, exec("DECR", "n"), Equal[any](int64(10)))
+	Expect(t, exec("DECRBY", "n", "3"), Equal[any](int64(7)))
+
+	_, err := s.Exec(ctx, "INCR", "x")
+	Expect(t, err, IsCodeError(ERROR__VALUE_NOT_INTEGER))
+	_, err = s.Exec(ctx, "SET", "k", "v", "EX")
+	Expect(t, err, IsCodeError(ERROR__INVALID_ARGUMENTS))
+	_, err = s.Exec(ctx, "SET", "k", "v", "NX", "XX")
+	Expect(t, err, IsCodeError(ERROR__INVALID_ARGUMENTS))
+	_, err = s.Exec(ctx, "GET")
+	Expect(t, err, IsCodeError(ERROR__INVALID_ARGUMENTS))
+	_, err = s.Exec(ctx, "EVAL", "return 1", 0)
+	Expect(t, err, IsCodeError(ERROR__UNSUPPORTED_COMMAND))
+}
+
+func TestStore_Atomic(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+
+	t.Run("CompareAndSet", func(t *testing.T) {
+		ok, err := s.CompareAndSet(ctx, "cas", "", "v", 0)
+		Expect(t, err, Succeed())
+		Expect(t, ok, BeFalse())
+
+		Expect(t, s.Set(ctx, "cas", "v1", 0), Succeed())
+		ok, _ = s.CompareAndSet(ctx, "cas", "v0", "v2", 0)
+		Expect(t, ok, BeFalse())
+		ok, _ = s.CompareAndSet(ctx, "cas", "v1", "v2", 30*time.Millisecond)
+		Expect(t, ok, BeTrue())
+		v, _, _ := s.Get(ctx, "cas")
+		Expect(t, v, Equal("v2"))
+		ttl, _, _ := s.TTL(ctx, "cas")
+		Expect(t, ttl > 0, BeTrue())
+
+		time.Sleep(40 * time.Millisecond)
+		ok, _ = s.CompareAndSet(ctx, "cas", "v2", "v3", 0)
+		Expect(t, ok, BeFalse())
+	})
+
+	t.Run("CompareAndDel", func(t *testing.T) {
+		Expect(t, s.Set(ctx, "cad", "v1", 0), Succeed())
+		deleted, err := s.CompareAndDel(ctx, "cad", "v0")
+		Expect(t, err, Succeed())
+		Expect(t, deleted, BeFalse())
+		deleted, _ = s.CompareAndDel(ctx, "cad", "v1")
+		Expect(t, deleted, BeTrue())
+		_, exists, _ := s.Get(ctx, "cad")
+		Expect(t, exists, BeFalse())
+		deleted, _ = s.CompareAndDel(ctx, "cad", "v1")
+		Expect(t, deleted, BeFalse())
+	})
+
+	t.Run("Incr", func(t *testing.T) {
+		Expect(t, s.Set(ctx, "incr", "1", time.Minute), Succeed())
+		v, err := s.Incr(ctx, "incr")
+		Expect(t, err, Succeed())
+		Expect(t, v, Equal(int64(2)))
+		ttl, _, _ := s.TTL(ctx, "incr")
+		Expect(t, ttl > 0, BeTrue())
+
+		_ = s.Set(ctx, "incr", "x", 0)
+		_, err = s.Incr(ctx, "incr")
+		Expect(t, err, IsCodeError(ERROR__VALUE_NOT_INTEGER))
+	})
+
+	t.Run("Concurrently", func(t *testing.T) {
+		Expect(t, s.Set(ctx, "owner", "init", 0), Succeed())
+		var (
+			wg      sync.WaitGroup
+			swapped atomic.Int32
+			deleted atomic.Int32
+		)
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if ok, _ := s.CompareAndSet(ctx, "owner", "init", "next", 0); ok {
+					swapped.Add(1)
+				}
+				if ok, _ := s.CompareAndDel(ctx, "owner", "next"); ok {
+					deleted.Add(1)
+				}
+				_, _ = s.Incr(ctx, "counter")
+			}()
+		}
+		wg.Wait()
+		Expect(t, swapped.Load(), Equal(int32(1)))
+		Expect(t, deleted.Load(), Equal(int32(1)))
+		v, _, _ := s.Get(ctx, "counter")
+		Expect(t, v, Equal("50"))
+	})
+}
@@ -0,0 +1,93 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/xoctopus/logx"
+	"github.com/xoctopus/x/codex"
+
+	"github.com/xoctopus/confx/pkg/types"
+)
+
+// NewLeaderElector creates LeaderElector campaigns leadership named name and
+// runs f when elected.
+func NewLeaderElector(l *Locker, name string, f func(context.Context) error) *LeaderElector {
+	return &LeaderElector{locker: l, name: name, f: f}
+}
+
+// LeaderElector runs callback only on the leader instance among peers. the
+// leader is the owner of lock named by election, identified by
+// kg.KeyGen.InstanceID. If leadership is lost, the context of callback is
+// canceled and the elector campaigns again.
+type LeaderElector struct {
+	locker *Locker
+	name   string
+	f      func(context.Context) error
+	leader atomic.Bool
+}
+
+var _ types.Runnable = (*LeaderElector)(nil)
+
+// IsLeader reports if current instance is the leader
+func (e *LeaderElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run campaigns leadership and runs callback while leading until ctx done. if
+// callback returns, leadership is released for other peers, and an error
+// returned by callback is returned by Run.
+func (e *LeaderElector) Run(ctx context.Context) error {
+	log := logx.From(ctx).With("election", e.name, "instance", e.locker.Owner())
+
+	for {
+		x, err := e.locker.Lock(ctx, e.name)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Warn(err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(e.locker.retry):
+			}
+			continue
+		}
+
+		log.With("token", x.Token()).Info("elected")
+		lost, err := e.lead(ctx, x)
+		if lost {
+			log.Warn(errors.New("leadership lost"))
+			continue
+		}
+		return err
+	}
+}
+
+// lead runs callback while holding x and reports if leadership was lost
+func (e *LeaderElector) lead(ctx context.Context, x *Lock) (bool, error) {
+	e.leader.Store(true)
+	defer e.leader.Store(false)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- e.f(ctx) }()
+
+	select {
+	case err := <-done:
+		if err := x.Unlock(context.WithoutCancel(ctx)); err != nil && !codex.IsCode(err, ERROR__LOCK_NOT_HELD) {
+			logx.From(ctx).Warn(err)
+		}
+		return false, err
+	case <-x.Lost():
+		cancel()
+		<-done
+		_ = x.Unlock(context.WithoutCancel(ctx))
+		return true, nil
+	}
+}
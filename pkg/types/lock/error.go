@@ -0,0 +1,12 @@
+package lock
+
+// Error presents error codes of distributed lock
+// +genx:code
+type Error int8
+
+const (
+	ERROR_UNDEFINED          Error = iota
+	ERROR__LOCK_NOT_ACQUIRED       // lock is held by others
+	ERROR__LOCK_NOT_HELD           // lock is not held by owner
+	ERROR__LOCK_STORE_FAILED       // lock store failed
+)
@@ -0,0 +1,100 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xoctopus/x/codex"
+)
+
+func newLock(l *Locker, name, key string, token int64) *Lock {
+	ctx, cancel := context.WithCancel(context.Background())
+	x := &Lock{
+		locker: l,
+		name:   name,
+		key:    key,
+		token:  token,
+		value:  l.value(token),
+		lost:   make(chan struct{}),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go x.renewing(ctx)
+	return x
+}
+
+// Lock is a held distributed lock. it is renewed automatically until Unlock
+// called or renewal failed.
+type Lock struct {
+	locker *Locker
+	name   string
+	key    string
+	token  int64
+	value  string
+
+	once   sync.Once
+	lost   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Name returns lock name
+func (x *Lock) Name() string { return x.name }
+
+// Token returns fencing token of lock. tokens are increased for each acquiring
+// of the same lock, resource guarded by lock should reject writings with token
+// lower than the latest seen.
+func (x *Lock) Token() int64 { return x.token }
+
+// Lost returns a channel closed when lock is lost, because renewal failed
+// before expiration or the lock was taken over.
+func (x *Lock) Lost() <-chan struct{} { return x.lost }
+
+// Unlock stops renewal and releases lock if it is still held by owner.
+// ERROR__LOCK_NOT_HELD is returned if the lock was lost.
+func (x *Lock) Unlock(ctx context.Context) error {
+	x.cancel()
+	<-x.done
+
+	released, err := x.locker.release(ctx, x.key, x.value)
+	if err != nil {
+		return codex.Wrap(ERROR__LOCK_STORE_FAILED, err)
+	}
+	if !released {
+		return codex.Errorf(ERROR__LOCK_NOT_HELD, "lock: %s", x.name)
+	}
+	return nil
+}
+
+func (x *Lock) markLost() {
+	x.once.Do(func() { close(x.lost) })
+}
+
+// renewing extends lock every ttl/3 until ctx canceled. the lock is regarded as
+// lost if it was taken over or failed to renew before expiration.
+func (x *Lock) renewing(ctx context.Context) {
+	defer close(x.done)
+
+	ttl := x.locker.ttl
+	ticker := time.NewTicker(max(ttl/3, time.Millisecond))
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		held, err := x.locker.renew(ctx, x.key, x.value)
+		switch {
+		case err == nil && held:
+			renewed = time.Now()
+		case err == nil && !held, time.Since(renewed) >= ttl:
+			x.markLost()
+			return
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package lock_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/types/kg"
+	"github.com/xoctopus/confx/pkg/types/kv"
+	. "github.com/xoctopus/confx/pkg/types/lock"
+)
+
+type entry struct {
+	val       string
+	expiredAt time.Time
+}
+
+// store is a kv.Store and kv.Atomic with expiration for testing
+type store struct {
+	mtx sync.Mutex
+	m   map[string]entry
+}
+
+func (s *store) get(k string) (entry, bool) {
+	e, ok := s.m[k]
+	if ok && !e.expiredAt.IsZero() && time.Now().After(e.expiredAt) {
+		delete(s.m, k)
+		return entry{}, false
+	}
+	return e, ok
+}
+
+func (s *store) set(k, v string, ttl time.Duration) {
+	e := entry{val: v}
+	if ttl > 0 {
+		e.expiredAt = time.Now().Add(ttl)
+	}
+	s.m[k] = e
+}
+
+func (s *store) Get(_ context.Context, k string) (string, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	e, ok := s.get(k)
+	return e.val, ok, nil
+}
+
+func (s *store) Set(_ context.Context, k, v string, ttl time.Duration) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.set(k, v, ttl)
+	return nil
+}
+
+func (s *store) SetNX(_ context.Context, k, v string, ttl time.Duration) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.get(k); ok {
+		return false, nil
+	}
+	s.set(k, v, ttl)
+	return true, nil
+}
+
+func (s *store) Del(_ context.Context, k string) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, ok := s.get(k)
+	delete(s.m, k)
+	return ok, nil
+}
+
+func (s *store) TTL(_ context.Context, k string) (time.Duration, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	e, ok := s.get(k)
+	if !ok || e.expiredAt.IsZero() {
+		return 0, ok, nil
+	}
+	return time.Until(e.expiredAt), true, nil
+}
+
+func (s *store) CompareAndSet(_ context.Context, k, old, v string, ttl time.Duration) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if e, ok := s.get(k); !ok || e.val != old {
+		return false, nil
+	}
+	s.set(k, v, ttl)
+	return true, nil
+}
+
+func (s *store) CompareAndDel(_ context.Context, k, old string) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if e, ok := s.get(k); !ok || e.val != old {
+		return false, nil
+	}
+	delete(s.m, k)
+	return true, nil
+}
+
+func (s *store) Incr(_ context.Context, k string) (int64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	e, _ := s.get(k)
+	n, _ := strconv.ParseInt(e.val, 10, 64)
+	e.val = strconv.FormatInt(n+1, 10)
+	s.m[k] = e
+	return n + 1, nil
+}
+
+func newKeyGen() *kg.KeyGen {
+	g := &kg.KeyGen{}
+	g.Init("svc-lock")
+	return g
+}
+
+func TestLocker(t *testing.T) {
+	ctx := context.Background()
+	s := &store{m: map[string]entry{}}
+	l1 := NewLocker(s, newKeyGen(), WithTTL(90*time.Millisecond), WithRetryInterval(5*time.Millisecond))
+	l2 := NewLocker(s, newKeyGen(), WithTTL(90*time.Millisecond), WithRetryInterval(5*time.Millisecond))
+	Expect(t, l1.Owner(), NotEqual(l2.Owner()))
+	Expect(t, l1.Key("job"), Equal("{SVC_LOCK:PEER:LOCK:job}"))
+
+	t.Run("TryLock", func(t *testing.T) {
+		x1, err := l1.TryLock(ctx, "try")
+		Expect(t, err, Succeed())
+
+		_, err = l2.TryLock(ctx, "try")
+		Expect(t, err, IsCodeError(ERROR__LOCK_NOT_ACQUIRED))
+
+		// renewed and not expired
+		time.Sleep(200 * time.Millisecond)
+		_, err = l2.TryLock(ctx, "try")
+		Expect(t, err, IsCodeError(ERROR__LOCK_NOT_ACQUIRED))
+
+		Expect(t, x1.Unlock(ctx), Succeed())
+		x2, err := l2.TryLock(ctx, "try")
+		Expect(t, err, Succeed())
+		Expect(t, x2.Token() > x1.Token(), BeTrue())
+		// token is derived from the stored counter shared by lockers
+		fence, _, _ := s.Get(ctx, l1.Key("try")+":FENCE")
+		Expect(t, fence, Equal(strconv.FormatInt(x2.Token(), 10)))
+		Expect(t, x2.Unlock(ctx), Succeed())
+	})
+
+	t.Run("NonAtomicStore", func(t *testing.T) {
+		ExpectPanic[error](t, func() { NewLocker(struct{ kv.Store }{s}, newKeyGen()) })
+	})
+
+	t.Run("Lock", func(t *testing.T) {
+		x1, err := l1.Lock(ctx, "wait")
+		Expect(t, err, Succeed())
+		go func() {
+			time.Sleep(30 * time.Millisecond)
+			_ = x1.Unlock(ctx)
+		}()
+
+		x2, err := l2.Lock(ctx, "wait")
+		Expect(t, err, Succeed())
+		Expect(t, x2.Unlock(ctx), Succeed())
+
+		x1, _ = l1.Lock(ctx, "wait")
+		defer x1.Unlock(ctx)
+		timeout, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+		defer cancel()
+		_, err = l2.Lock(timeout, "wait")
+		Expect(t, errors.Is(err, context.DeadlineExceeded), BeTrue())
+	})
+
+	t.Run("Lost", func(t *testing.T) {
+		x, err := l1.TryLock(ctx, "lost")
+		Expect(t, err, Succeed())
+
+		_, _ = s.Del(ctx, l1.Key("lost"))
+		_ = s.Set(ctx, l1.Key("lost"), "other", 0)
+
+		select {
+		case <-x.Lost():
+		case <-time.After(time.Second):
+			t.Fatal("lost lock not detected")
+		}
+		Expect(t, x.Unlock(ctx), IsCodeError(ERROR__LOCK_NOT_HELD))
+	})
+}
+
+func TestLeaderElector(t *testing.T) {
+	s := &store{m: map[string]entry{}}
+
+	var (
+		leading atomic.Int32
+		overlap atomic.Bool
+		elected = make(chan int, 2)
+	)
+	campaign := func(id int) (*LeaderElector, context.CancelFunc, chan error) {
+		l := NewLocker(s, newKeyGen(), WithTTL(90*time.Millisecond), WithRetryInterval(5*time.Millisecond))
+		e := NewLeaderElector(l, "cron", func(ctx context.Context) error {
+			if leading.Add(1) > 1 {
+				overlap.Store(true)
+			}
+			defer leading.Add(-1)
+			elected <- id
+			<-ctx.Done()
+			return nil
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- e.Run(ctx) }()
+		return e, cancel, done
+	}
+
+	e1, cancel1, done1 := campaign(1)
+	first := <-elected
+	Expect(t, first, Equal(1))
+	Expect(t, e1.IsLeader(), BeTrue())
+
+	e2, cancel2, done2 := campaign(2)
+	defer cancel2()
+	time.Sleep(200 * time.Millisecond)
+	Expect(t, e2.IsLeader(), BeFalse())
+
+	cancel1()
+	Expect(t, <-done1, Succeed())
+	Expect(t, e1.IsLeader(), BeFalse())
+
+	select {
+	case id := <-elected:
+		Expect(t, id, Equal(2))
+	case <-time.After(time.Second):
+		t.Fatal("leadership is not taken over")
+	}
+	Expect(t, e2.IsLeader(), BeTrue())
+	Expect(t, overlap.Load(), BeFalse())
+
+	cancel2()
+	Expect(t, <-done2, Succeed())
+}
@@ -0,0 +1,195 @@
+// Package lock provides distributed lock and leader election on top of kv.Store
+// and kg.KeyGen.
+//
+// Lock keys are shared by peers of the same creator, {<kg.SharedKey>}, and the
+// lock value is `<owner>#<token>` where owner is kg.KeyGen.InstanceID and token
+// is a fencing token increased for each acquiring. The token is increased by a
+// counter stored in key `<lock key>:FENCE`. If the store implements kv.Atomic,
+// such as memkv.Store, acquiring, renewal and release are executed by its atomic
+// operations. Otherwise the store should implement kv.Executor, such as
+// confredis.Endpoint, and they are executed atomically by lua scripts.
+package lock
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xoctopus/x/codex"
+	"github.com/xoctopus/x/misc/must"
+
+	"github.com/xoctopus/confx/pkg/types/kg"
+	"github.com/xoctopus/confx/pkg/types/kv"
+)
+
+// Domain is kg domain of lock keys
+const Domain = "LOCK"
+
+const (
+	scriptAcquire = `if redis.call('EXISTS', KEYS[1]) == 1 then return 0 end
+local t = redis.call('INCR', KEYS[2])
+redis.call('SET', KEYS[1], ARGV[1] .. '#' .. t, 'PX', ARGV[2])
+return t`
+	scriptRenew = `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('PEXPIRE', KEYS[1], ARGV[2]) end
+return 0`
+	scriptRelease = `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end
+return 0`
+)
+
+// Option configures Locker
+type Option func(*Locker)
+
+// WithTTL sets expiration of lock, a held lock is renewed every ttl/3. default
+// is 10 seconds.
+func WithTTL(ttl time.Duration) Option {
+	return func(l *Locker) { l.ttl = ttl }
+}
+
+// WithRetryInterval sets interval of retrying when Lock waits for a lock held by
+// others. default is 100 milliseconds.
+func WithRetryInterval(d time.Duration) Option {
+	return func(l *Locker) { l.retry = d }
+}
+
+// NewLocker creates Locker. g should be initialized, and s should implement
+// kv.Atomic or kv.Executor.
+func NewLocker(s kv.Store, g *kg.KeyGen, options ...Option) *Locker {
+	must.BeTrueF(s != nil, "lock: store is required")
+	must.BeTrueF(g != nil && g.InstanceID() != "", "lock: key generator is not initialized")
+
+	l := &Locker{
+		store: s,
+		kg:    g,
+		ttl:   10 * time.Second,
+		retry: 100 * time.Millisecond,
+	}
+	if x, ok := s.(kv.Atomic); ok {
+		l.atomic = x
+	} else if x, ok := s.(kv.Executor); ok {
+		l.exec = x
+	}
+	must.BeTrueF(l.atomic != nil || l.exec != nil, "lock: store should implement kv.Atomic or kv.Executor")
+	for _, o := range options {
+		o(l)
+	}
+	must.BeTrueF(l.ttl > 0, "lock: ttl should be positive")
+	must.BeTrueF(l.retry > 0, "lock: retry interval should be positive")
+	return l
+}
+
+// Locker acquires distributed locks owned by kg.KeyGen.InstanceID
+type Locker struct {
+	store  kv.Store
+	atomic kv.Atomic
+	exec   kv.Executor
+	kg     *kg.KeyGen
+	ttl    time.Duration
+	retry  time.Duration
+}
+
+// Owner returns owner identity of locks
+func (l *Locker) Owner() string {
+	return l.kg.InstanceID()
+}
+
+// Key returns store key of lock name. the key is wrapped as a redis hash tag to
+// keep the lock key and its fencing key in the same cluster slot.
+func (l *Locker) Key(name string) string {
+	return "{" + l.kg.SharedKey(Domain, name) + "}"
+}
+
+// TryLock acquires lock of name once. ERROR__LOCK_NOT_ACQUIRED is returned if
+// it is held by others.
+func (l *Locker) TryLock(ctx context.Context, name string) (*Lock, error) {
+	key := l.Key(name)
+	token, err := l.acquire(ctx, key)
+	if err != nil {
+		return nil, codex.Wrap(ERROR__LOCK_STORE_FAILED, err)
+	}
+	if token == 0 {
+		return nil, codex.Errorf(ERROR__LOCK_NOT_ACQUIRED, "lock: %s", name)
+	}
+	return newLock(l, name, key, token), nil
+}
+
+// Lock acquires lock of name and waits until acquired or ctx done
+func (l *Locker) Lock(ctx context.Context, name string) (*Lock, error) {
+	for {
+		x, err := l.TryLock(ctx, name)
+		if err == nil || !codex.IsCode(err, ERROR__LOCK_NOT_ACQUIRED) {
+			return x, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		case <-time.After(l.retry):
+		}
+	}
+}
+
+func (l *Locker) value(token int64) string {
+	return l.Owner() + "#" + strconv.FormatInt(token, 10)
+}
+
+// acquire returns fencing token, 0 means not acquired
+func (l *Locker) acquire(ctx context.Context, key string) (int64, error) {
+	if l.atomic == nil {
+		v, err := l.exec.Exec(ctx, "EVAL", scriptAcquire, 2, key, key+":FENCE", l.Owner(), l.ttl.Milliseconds())
+		if err != nil {
+			return 0, err
+		}
+		return toInt64(v)
+	}
+
+	// the counter is increased even if not acquired, tokens are still increasing
+	token, err := l.atomic.Incr(ctx, key+":FENCE")
+	if err != nil {
+		return 0, err
+	}
+	ok, err := l.store.SetNX(ctx, key, l.value(token), l.ttl)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return token, nil
+}
+
+// renew extends ttl of key if it is held by value
+func (l *Locker) renew(ctx context.Context, key, value string) (bool, error) {
+	if l.atomic != nil {
+		return l.atomic.CompareAndSet(ctx, key, value, value, l.ttl)
+	}
+	v, err := l.exec.Exec(ctx, "EVAL", scriptRenew, 1, key, value, l.ttl.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+	n, err := toInt64(v)
+	return n > 0, err
+}
+
+// release removes key if it is held by value
+func (l *Locker) release(ctx context.Context, key, value string) (bool, error) {
+	if l.atomic != nil {
+		return l.atomic.CompareAndDel(ctx, key, value)
+	}
+	v, err := l.exec.Exec(ctx, "EVAL", scriptRelease, 1, key, value)
+	if err != nil {
+		return false, err
+	}
+	n, err := toInt64(v)
+	return n > 0, err
+}
+
+func toInt64(v any) (int64, error) {
+	switch x := v.(type) {
+	case int64:
+		return x, nil
+	case nil:
+		return 0, nil
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(x), 10, 64)
+	default:
+		return 0, fmt.Errorf("lock: unexpected script result %T", v)
+	}
+}
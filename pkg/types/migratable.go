@@ -0,0 +1,19 @@
+package types
+
+import (
+	"context"
+	"io"
+)
+
+// Migratable is implemented by components which manage versioned migrations,
+// eg: confrdb endpoints. progress and reports are written to w.
+type Migratable interface {
+	// MigratePlan reports pending migrations without applying
+	MigratePlan(ctx context.Context, w io.Writer) error
+	// MigrateUp applies pending migrations
+	MigrateUp(ctx context.Context, w io.Writer) error
+	// MigrateDown rolls back the latest steps applied migrations
+	MigrateDown(ctx context.Context, w io.Writer, steps int) error
+	// MigrateStatus reports applied and pending migrations
+	MigrateStatus(ctx context.Context, w io.Writer) error
+}
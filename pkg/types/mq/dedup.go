@@ -0,0 +1,177 @@
+package mq
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/xoctopus/logx"
+	"github.com/xoctopus/x/codex"
+	"github.com/xoctopus/x/misc/must"
+
+	"github.com/xoctopus/confx/pkg/types/kv"
+)
+
+// Identity computes identity of message for deduplication. message with empty
+// identity is not deduplicated.
+type Identity[M any] func(M) string
+
+// IdentityByMessageID identifies message by id assigned by broker. note that
+// message id may change when redelivered by some brokers, eg: a retried message
+// of pulsar.
+func IdentityByMessageID[M HasMessageID]() Identity[M] {
+	return func(m M) string { return m.MessageID() }
+}
+
+// IdentityByExtra identifies message by extra value of key assigned by producer
+func IdentityByExtra[M HasExtra](key string) Identity[M] {
+	return func(m M) string {
+		v, _ := m.ExtraValueOf(key)
+		return v
+	}
+}
+
+// IdentityByPayload identifies message by sha256 of its topic and payload
+func IdentityByPayload[M interface {
+	HasTopic
+	HasPayload
+}]() Identity[M] {
+	return func(m M) string {
+		h := sha256.New()
+		h.Write([]byte(m.Topic()))
+		h.Write([]byte{0})
+		h.Write(m.Payload())
+		return hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+const (
+	dedupInFlight = "in_flight"
+	dedupDone     = "done"
+
+	scriptClaim = `local v = redis.call('GET', KEYS[1])
+if v then return v end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return ARGV[1]`
+	scriptRelease = `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end
+return 0`
+)
+
+// DedupOption configures Dedup. zero fields use defaults.
+type DedupOption struct {
+	// Key builds store key by message identity. default is `mq:dedup:<id>`. it
+	// is recommended to build shared key by kg.KeyGen, eg:
+	//	func(id string) string { return g.SharedKey("MQ_DEDUP", id) }
+	Key func(id string) string
+	// InFlightTTL expiration of the in-flight state. if the handler crashed
+	// before finished, the message can be handled again after InFlightTTL.
+	// default is 1 minute and it should be longer than handling duration.
+	InFlightTTL time.Duration
+	// DoneTTL expiration of the done state, duplicated messages in DoneTTL are
+	// skipped. default is 24 hours.
+	DoneTTL time.Duration
+}
+
+// Dedup skips messages already handled. the identity of message is marked as
+// in-flight before handling, and as done after handled. s should implement
+// kv.Atomic or kv.Executor, claiming and releasing are executed by atomic
+// operations of kv.Atomic, or atomically by lua scripts of kv.Executor.
+//   - a done message is skipped and nil is returned, so it is acknowledged.
+//   - an in-flight message returns ERROR__MESSAGE_IN_FLIGHT, so it can be
+//     NACKed and redelivered after the other handling finished or expired.
+//   - if handler failed, the in-flight state is removed for retrying. the
+//     in-flight state holds a token of claiming, and it is removed only if the
+//     state is still claimed by the token.
+//   - the handled message is acknowledged even if it failed to be marked as
+//     done, the failure is logged.
+//
+// confredis.Endpoint implements kv.Store.
+func Dedup[M any](s kv.Store, identity Identity[M], opt DedupOption) SubMiddleware[M] {
+	if opt.Key == nil {
+		opt.Key = func(id string) string { return "mq:dedup:" + id }
+	}
+	if opt.InFlightTTL <= 0 {
+		opt.InFlightTTL = time.Minute
+	}
+	if opt.DoneTTL <= 0 {
+		opt.DoneTTL = 24 * time.Hour
+	}
+
+	cas, atomic := s.(kv.Atomic)
+	exec, _ := s.(kv.Executor)
+	must.BeTrueF(atomic || exec != nil, "mq: dedup store should implement kv.Atomic or kv.Executor")
+
+	// claim marks key as token if not exists, and returns the state of key
+	claim := func(ctx context.Context, key, token string) (string, error) {
+		if !atomic {
+			v, err := exec.Exec(ctx, "EVAL", scriptClaim, 1, key, token, opt.InFlightTTL.Milliseconds())
+			state, _ := v.(string)
+			return state, err
+		}
+		for {
+			claimed, err := s.SetNX(ctx, key, token, opt.InFlightTTL)
+			if err != nil || claimed {
+				return token, err
+			}
+			state, exists, err := s.Get(ctx, key)
+			if err != nil || exists {
+				return state, err
+			}
+			// expired or released between SetNX and Get
+			if err = ctx.Err(); err != nil {
+				return "", err
+			}
+		}
+	}
+	release := func(ctx context.Context, key, token string) {
+		ctx = context.WithoutCancel(ctx)
+		if atomic {
+			_, _ = cas.CompareAndDel(ctx, key, token)
+			return
+		}
+		_, _ = exec.Exec(ctx, "EVAL", scriptRelease, 1, key, token)
+	}
+
+	return func(next SubHandler[M]) SubHandler[M] {
+		return func(ctx context.Context, m M) error {
+			id := identity(m)
+			if id == "" {
+				return next(ctx, m)
+			}
+			var (
+				key   = opt.Key(id)
+				token = dedupInFlight + "#" + rand.Text()
+			)
+
+			state, err := claim(ctx, key, token)
+			switch {
+			case err != nil:
+				return codex.Wrap(ERROR__DEDUP_STORE_FAILED, err)
+			case state == dedupDone:
+				return nil
+			case state != token:
+				return codex.Errorf(ERROR__MESSAGE_IN_FLIGHT, "identity: %s", id)
+			}
+
+			defer func() {
+				if r := recover(); r != nil {
+					release(ctx, key, token)
+					panic(r)
+				}
+			}()
+
+			if err = next(ctx, m); err != nil {
+				release(ctx, key, token)
+				return err
+			}
+			// message is handled, returns nil to avoid handling it again even
+			// if it is failed to be marked as done
+			if err = s.Set(context.WithoutCancel(ctx), key, dedupDone, opt.DoneTTL); err != nil {
+				logx.From(ctx).With("identity", id).Warn(codex.Wrap(ERROR__DEDUP_STORE_FAILED, err))
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package mq_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/types/kv"
+	"github.com/xoctopus/confx/pkg/types/mq"
+	"github.com/xoctopus/confx/pkg/types/mq/memq"
+)
+
+// store is a kv.Store and kv.Atomic for testing, ttl is ignored
+type store struct {
+	mtx sync.Mutex
+	m   map[string]string
+	err error
+	// failed only for Set
+	setErr error
+}
+
+func (s *store) Get(_ context.Context, k string) (string, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	v, ok := s.m[k]
+	return v, ok, s.err
+}
+
+func (s *store) Set(_ context.Context, k, v string, _ time.Duration) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.setErr != nil {
+		return s.setErr
+	}
+	s.m[k] = v
+	return s.err
+}
+
+func (s *store) SetNX(_ context.Context, k, v string, _ time.Duration) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.err != nil {
+		return false, s.err
+	}
+	if _, ok := s.m[k]; ok {
+		return false, nil
+	}
+	s.m[k] = v
+	return true, nil
+}
+
+func (s *store) Del(_ context.Context, k string) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, ok := s.m[k]
+	delete(s.m, k)
+	return ok, s.err
+}
+
+func (s *store) TTL(_ context.Context, k string) (time.Duration, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, ok := s.m[k]
+	return 0, ok, s.err
+}
+
+func (s *store) CompareAndSet(_ context.Context, k, old, v string, _ time.Duration) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if cur, ok := s.m[k]; !ok || cur != old {
+		return false, s.err
+	}
+	s.m[k] = v
+	return true, s.err
+}
+
+func (s *store) CompareAndDel(_ context.Context, k, old string) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if cur, ok := s.m[k]; !ok || cur != old {
+		return false, s.err
+	}
+	delete(s.m, k)
+	return true, s.err
+}
+
+func (s *store) Incr(_ context.Context, k string) (int64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	n, _ := strconv.ParseInt(s.m[k], 10, 64)
+	s.m[k] = strconv.FormatInt(n+1, 10)
+	return n + 1, s.err
+}
+
+func TestDedup(t *testing.T) {
+	ctx := context.Background()
+
+	message := func(payload string, extra ...string) memq.ProducerMessage {
+		m := memq.NewProducerMessage("topic", []byte(payload))
+		for i := 0; i+1 < len(extra); i += 2 {
+			m.AddExtra(extra[i], extra[i+1])
+		}
+		return m
+	}
+
+	t.Run("Identity", func(t *testing.T) {
+		byExtra := mq.IdentityByExtra[memq.ProducerMessage]("event_id")
+		Expect(t, byExtra(message("", "event_id", "1")), Equal("1"))
+		Expect(t, byExtra(message("")), Equal(""))
+
+		byPayload := mq.IdentityByPayload[memq.ProducerMessage]()
+		Expect(t, byPayload(message("a")), Equal(byPayload(message("a"))))
+		Expect(t, byPayload(message("a")), NotEqual(byPayload(message("b"))))
+	})
+
+	t.Run("SkipDone", func(t *testing.T) {
+		s := &store{m: map[string]string{}}
+		handled := 0
+		h := mq.ChainSub(
+			func(context.Context, memq.ProducerMessage) error { handled++; return nil },
+			mq.Dedup(s, mq.IdentityByExtra[memq.ProducerMessage]("event_id"), mq.DedupOption{}),
+		)
+		Expect(t, h(ctx, message("", "event_id", "1")), Succeed())
+		Expect(t, h(ctx, message("", "event_id", "1")), Succeed())
+		Expect(t, h(ctx, message("", "event_id", "2")), Succeed())
+		Expect(t, handled, Equal(2))
+		Expect(t, s.m["mq:dedup:1"], Equal("done"))
+
+		// without identity
+		Expect(t, h(ctx, message("")), Succeed())
+		Expect(t, h(ctx, message("")), Succeed())
+		Expect(t, handled, Equal(4))
+	})
+
+	t.Run("RetryFailed", func(t *testing.T) {
+		s := &store{m: map[string]string{}}
+		failed := errors.New("failed")
+		handled := 0
+		h := mq.ChainSub(
+			func(context.Context, memq.ProducerMessage) error {
+				handled++
+				if handled == 1 {
+					return failed
+				}
+				return nil
+			},
+			mq.Dedup(s, mq.IdentityByPayload[memq.ProducerMessage](), mq.DedupOption{
+				Key: func(id string) string { return "key:" + id },
+			}),
+		)
+		Expect(t, h(ctx, message("payload")), Equal(failed))
+		Expect(t, s.m, HaveLen[map[string]string](0))
+		Expect(t, h(ctx, message("payload")), Succeed())
+		Expect(t, h(ctx, message("payload")), Succeed())
+		Expect(t, handled, Equal(2))
+	})
+
+	t.Run("InFlight", func(t *testing.T) {
+		s := &store{m: map[string]string{}}
+		var h mq.SubHandler[memq.ProducerMessage]
+		var inner error
+		h = mq.ChainSub(
+			func(ctx context.Context, m memq.ProducerMessage) error {
+				inner = h(ctx, m)
+				return nil
+			},
+			mq.Dedup(s, mq.IdentityByExtra[memq.ProducerMessage]("event_id"), mq.DedupOption{}),
+		)
+		Expect(t, h(ctx, message("", "event_id", "1")), Succeed())
+		Expect(t, inner, IsCodeError(mq.ERROR__MESSAGE_IN_FLIGHT))
+	})
+
+	t.Run("ReleaseOwnClaim", func(t *testing.T) {
+		s := &store{m: map[string]string{}}
+		failed := errors.New("failed")
+		h := mq.ChainSub(
+			func(context.Context, memq.ProducerMessage) error {
+				// in-flight state expired and claimed by another consumer
+				s.mtx.Lock()
+				s.m["mq:dedup:1"] = "in_flight#other"
+				s.mtx.Unlock()
+				return failed
+			},
+			mq.Dedup(s, mq.IdentityByExtra[memq.ProducerMessage]("event_id"), mq.DedupOption{}),
+		)
+		Expect(t, h(ctx, message("", "event_id", "1")), Equal(failed))
+		Expect(t, s.m["mq:dedup:1"], Equal("in_flight#other"))
+	})
+
+	t.Run("MarkDoneFailed", func(t *testing.T) {
+		s := &store{m: map[string]string{}, setErr: errors.New("lost connection")}
+		handled := 0
+		h := mq.ChainSub(
+			func(context.Context, memq.ProducerMessage) error { handled++; return nil },
+			mq.Dedup(s, mq.IdentityByExtra[memq.ProducerMessage]("event_id"), mq.DedupOption{}),
+		)
+		// handled message is not redelivered
+		Expect(t, h(ctx, message("", "event_id", "1")), Succeed())
+		Expect(t, handled, Equal(1))
+	})
+
+	t.Run("StoreFailed", func(t *testing.T) {
+		s := &store{m: map[string]string{}, err: errors.New("lost connection")}
+		h := mq.ChainSub(
+			func(context.Context, memq.ProducerMessage) error { return nil },
+			mq.Dedup(s, mq.IdentityByExtra[memq.ProducerMessage]("event_id"), mq.DedupOption{}),
+		)
+		Expect(t, h(ctx, message("", "event_id", "1")), IsCodeError(mq.ERROR__DEDUP_STORE_FAILED))
+	})
+
+	t.Run("NonAtomicStore", func(t *testing.T) {
+		s := struct{ kv.Store }{&store{m: map[string]string{}}}
+		ExpectPanic[error](t, func() {
+			mq.Dedup(s, mq.IdentityByExtra[memq.ProducerMessage]("event_id"), mq.DedupOption{})
+		})
+	})
+}
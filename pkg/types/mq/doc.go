@@ -5,6 +5,10 @@
 //     enabling different MQ drivers to implement specific features via composition.
 //   - pubsub.go: Defines standard roles including Consumer, Producer, Observer,
 //     and Factory.
+//   - middleware.go: Defines SubMiddleware and PubMiddleware wrapping consuming
+//     and publishing, with built-ins for recovering, timeout, rate limiting and
+//     payload validation. drivers honor them by WithSubMiddleware and
+//     WithPubMiddleware.
 //   - resource.go: Provides universal resource management, allowing resources
 //     created by the Factory to be managed through a centralized ResourceManager.
 package mq
@@ -15,4 +15,9 @@ const (
 	ERROR__SUB_UNSUBSCRIBED              // subscriber unsubscribed
 	ERROR__PUB_CLOSED                    // publisher closed
 	ERROR__PUB_INVALID_MESSAGE           // publisher got invalid message
+	ERROR__HANDLER_TIMEOUT               // handling timeout
+	ERROR__RATE_LIMITED                  // handling rate limited
+	ERROR__INVALID_PAYLOAD               // invalid message payload
+	ERROR__MESSAGE_IN_FLIGHT             // message is being handled by another consumer
+	ERROR__DEDUP_STORE_FAILED            // dedup store failed
 )
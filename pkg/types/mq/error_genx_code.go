@@ -29,5 +29,15 @@ func (e Error) Message() string {
 		return "[mq.Error:8] publisher closed"
 	case ERROR__PUB_INVALID_MESSAGE:
 		return "[mq.Error:9] publisher got invalid message"
+	case ERROR__HANDLER_TIMEOUT:
+		return "[mq.Error:10] handling timeout"
+	case ERROR__RATE_LIMITED:
+		return "[mq.Error:11] handling rate limited"
+	case ERROR__INVALID_PAYLOAD:
+		return "[mq.Error:12] invalid message payload"
+	case ERROR__MESSAGE_IN_FLIGHT:
+		return "[mq.Error:13] message is being handled by another consumer"
+	case ERROR__DEDUP_STORE_FAILED:
+		return "[mq.Error:14] dedup store failed"
 	}
 }
@@ -0,0 +1,359 @@
+// Package memq provides an in-process message broker implements mq.PubSub for
+// unit tests and local development.
+//
+// Messages are kept in memory by topic. consumers with the same subscription
+// name share messages of the subscription, and a new subscription starts from
+// the earliest message unless WithSubStartFromLatest. delayed messages are
+// appended to topic when due, expired messages are dropped before handling and
+// NACKed messages are redelivered with increased retry count until they are
+// moved to the dead letter topic `<topic>_DLQ`.
+package memq
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xoctopus/logx"
+	"github.com/xoctopus/x/codex"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+
+	"github.com/xoctopus/confx/pkg/types/mq"
+	"github.com/xoctopus/confx/pkg/types/mq/mqotel"
+)
+
+// NewBroker returns a broker with default options
+func NewBroker() *Broker {
+	b := &Broker{}
+	b.SetDefault()
+	return b
+}
+
+// Broker is an in-process message broker
+type Broker struct {
+	Option
+
+	mtx    sync.Mutex
+	topics map[string]*topic
+	timers map[*time.Timer]struct{}
+	closed atomic.Bool
+
+	mq.ResourceManager `env:"-"`
+}
+
+var _ mq.PubSub[ProducerMessage, ConsumerMessage] = (*Broker)(nil)
+
+func (b *Broker) SetDefault() {
+	if b.ResourceManager == nil {
+		b.ResourceManager = mq.NewResourceManager()
+	}
+	b.Option.SetDefault()
+}
+
+func (b *Broker) Init(_ context.Context) error {
+	b.closed.Store(false)
+	return nil
+}
+
+func (b *Broker) NewProducer(ctx context.Context, options ...mq.OptionApplier) (_ mq.Producer[ProducerMessage], err error) {
+	var (
+		_, log = logx.Enter(ctx)
+		x      *producer
+		opt    = b.Option.PubOption(options...)
+	)
+	defer func() {
+		if err != nil {
+			log.Error(err)
+		} else {
+			b.AddProducer(x)
+			log.Info("pub created")
+		}
+		log.End()
+	}()
+
+	if b.closed.Load() {
+		return nil, codex.New(mq.ERROR__CLI_CLOSED)
+	}
+
+	middlewares := opt.middlewares
+	if !opt.disableTracing {
+		middlewares = append(
+			[]mq.PubMiddleware[ProducerMessage]{mqotel.PubTracing[ProducerMessage]("memq")},
+			middlewares...,
+		)
+	}
+
+	x = &producer{
+		cli:         b,
+		log:         logx.NewStd().With("producer", opt.name, "topic", opt.topic),
+		topic:       opt.topic,
+		name:        opt.name,
+		callback:    opt.callback,
+		middlewares: middlewares,
+	}
+	return x, nil
+}
+
+func (b *Broker) NewConsumer(ctx context.Context, options ...mq.OptionApplier) (_ mq.Consumer[ConsumerMessage], err error) {
+	var (
+		_, log = logx.Enter(ctx)
+		x      *consumer
+		opt    = b.Option.SubOption(options...)
+	)
+	defer func() {
+		if err != nil {
+			log.Error(err)
+		} else {
+			b.AddConsumer(x)
+			log.Info("sub created")
+		}
+		log.End()
+	}()
+
+	if b.closed.Load() {
+		return nil, codex.New(mq.ERROR__CLI_CLOSED)
+	}
+
+	middlewares := opt.middlewares
+	if !opt.disableTracing {
+		middlewares = append(
+			[]mq.SubMiddleware[ConsumerMessage]{
+				mqotel.SubTracing[ConsumerMessage]("memq", semconv.MessagingDestinationSubscriptionName(opt.name)),
+			},
+			middlewares...,
+		)
+	}
+
+	x = &consumer{
+		cli:         b,
+		sub:         b.topic(opt.topic).subscribe(opt.name, opt.latest),
+		log:         logx.NewStd().With("subscription", opt.name, "topic", opt.topic),
+		mode:        opt.mode,
+		worker:      opt.worker,
+		buffer:      opt.buffer,
+		hasher:      opt.hasher,
+		callback:    opt.callback,
+		middlewares: middlewares,
+		autoAck:     !opt.disableAutoAck,
+		retryNack:   opt.retryNack,
+		maxRetry:    opt.maxRetry,
+		nackDelay:   opt.nackDelay,
+	}
+	return x, nil
+}
+
+// Messages returns messages published to topic in order, including messages
+// already consumed. it helps to assert messages of dead letter topic.
+func (b *Broker) Messages(name string) []ConsumerMessage {
+	t := b.topic(name)
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	messages := make([]ConsumerMessage, 0, len(t.log))
+	for _, m := range t.log {
+		messages = append(messages, m.clone())
+	}
+	return messages
+}
+
+// Backlog returns count of messages not acknowledged by subscription, including
+// messages not delivered yet. delayed messages are not counted before due.
+func (b *Broker) Backlog(name, subscription string) int {
+	t := b.topic(name)
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	s, ok := t.subs[subscription]
+	if !ok {
+		return 0
+	}
+	return len(t.log) - s.next + len(s.redeliver) + len(s.pending)
+}
+
+func (b *Broker) Close() error {
+	if b.closed.CompareAndSwap(false, true) {
+		b.mtx.Lock()
+		for t := range b.timers {
+			t.Stop()
+		}
+		b.timers = nil
+		b.mtx.Unlock()
+		return b.ResourceManager.Close()
+	}
+	return nil
+}
+
+func (b *Broker) topic(name string) *topic {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.topics == nil {
+		b.topics = make(map[string]*topic)
+	}
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topic{
+			name:   name,
+			subs:   make(map[string]*subscription),
+			notify: make(chan struct{}),
+		}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// publish appends m to its topic, or after its delay
+func (b *Broker) publish(m *message) error {
+	if b.closed.Load() {
+		return codex.New(mq.ERROR__CLI_CLOSED)
+	}
+
+	if b.Partitions > 1 && m.key != "" {
+		m.partition = int64(mq.CRC(m.key) % b.Partitions)
+	}
+
+	t := b.topic(m.topic)
+	due := m.due()
+	if due <= 0 {
+		t.append(m)
+		return nil
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.timers == nil {
+		b.timers = make(map[*time.Timer]struct{})
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(due, func() {
+		b.mtx.Lock()
+		delete(b.timers, timer)
+		b.mtx.Unlock()
+		if !b.closed.Load() {
+			t.append(m)
+		}
+	})
+	b.timers[timer] = struct{}{}
+	return nil
+}
+
+type topic struct {
+	name string
+
+	mtx    sync.Mutex
+	log    []*message
+	subs   map[string]*subscription
+	notify chan struct{}
+}
+
+func (t *topic) append(m *message) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	m.offset = int64(len(t.log))
+	t.log = append(t.log, m)
+	close(t.notify)
+	t.notify = make(chan struct{})
+}
+
+func (t *topic) subscribe(name string, latest bool) *subscription {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	s, ok := t.subs[name]
+	if !ok {
+		s = &subscription{
+			name:    name,
+			topic:   t,
+			pending: make(map[*message]struct{}),
+		}
+		if latest {
+			s.next = len(t.log)
+		}
+		t.subs[name] = s
+	}
+	return s
+}
+
+func (t *topic) unsubscribe(s *subscription) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.subs[s.name] == s {
+		delete(t.subs, s.name)
+	}
+}
+
+// subscription tracks consuming progress and messages delivered but not
+// acknowledged. it is guarded by topic mutex.
+type subscription struct {
+	name  string
+	topic *topic
+
+	next      int
+	redeliver []*message
+	pending   map[*message]struct{}
+}
+
+// fetch blocks until a message can be delivered to c or ctx done
+func (s *subscription) fetch(ctx context.Context, c *consumer) (*message, error) {
+	t := s.topic
+	for {
+		t.mtx.Lock()
+		var m *message
+		if len(s.redeliver) > 0 {
+			m, s.redeliver = s.redeliver[0], s.redeliver[1:]
+		} else if s.next < len(t.log) {
+			m = t.log[s.next].clone()
+			s.next++
+		}
+		if m != nil {
+			m.owner = c
+			s.pending[m] = struct{}{}
+			t.mtx.Unlock()
+			return m, nil
+		}
+		notify := t.notify
+		t.mtx.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		case <-notify:
+		}
+	}
+}
+
+// settle removes m from pending and reports if it was pending
+func (s *subscription) settle(m *message) bool {
+	s.topic.mtx.Lock()
+	defer s.topic.mtx.Unlock()
+
+	_, ok := s.pending[m]
+	delete(s.pending, m)
+	return ok
+}
+
+// release returns messages delivered to c but not acknowledged for
+// redelivering to other consumers
+func (s *subscription) release(c *consumer) {
+	s.topic.mtx.Lock()
+	defer s.topic.mtx.Unlock()
+
+	released := make([]*message, 0)
+	for m := range s.pending {
+		if m.owner == c {
+			delete(s.pending, m)
+			m.owner = nil
+			released = append(released, m)
+		}
+	}
+	slices.SortFunc(released, func(a, b *message) int { return int(a.offset - b.offset) })
+	s.redeliver = append(released, s.redeliver...)
+	if len(released) > 0 {
+		close(s.topic.notify)
+		s.topic.notify = make(chan struct{})
+	}
+}
@@ -0,0 +1,277 @@
+package memq_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/types/mq"
+	. "github.com/xoctopus/confx/pkg/types/mq/memq"
+)
+
+func TopicFor(t testing.TB) string {
+	return strings.ReplaceAll(t.Name(), "/", "_")
+}
+
+// consume runs consumer c until n messages handled or timeout
+func consume(t testing.TB, c Consumer, n int, h mq.SubHandler[ConsumerMessage]) []ConsumerMessage {
+	var (
+		mtx      sync.Mutex
+		messages []ConsumerMessage
+		done     = make(chan struct{})
+		once     sync.Once
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	go func() {
+		_ = c.Run(ctx, func(ctx context.Context, m ConsumerMessage) error {
+			mtx.Lock()
+			messages = append(messages, m)
+			if len(messages) == n {
+				once.Do(func() { close(done) })
+			}
+			mtx.Unlock()
+			if h != nil {
+				return h(ctx, m)
+			}
+			return nil
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatalf("consumed %d messages in timeout, expect %d", len(messages), n)
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	return append([]ConsumerMessage(nil), messages...)
+}
+
+func TestBroker(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("PubSub", func(t *testing.T) {
+		b := NewBroker()
+		defer b.Close()
+
+		topic := TopicFor(t)
+		p, err := b.NewProducer(ctx, WithPubTopic(topic), WithPublisherName("pub"))
+		Expect(t, err, Succeed())
+		for i := range 3 {
+			_, err = p.Publish(ctx, topic, fmt.Appendf(nil, "%d", i))
+			Expect(t, err, Succeed())
+		}
+
+		c, err := b.NewConsumer(ctx, WithSubTopic(topic))
+		Expect(t, err, Succeed())
+		messages := consume(t, c, 3, nil)
+		for i, m := range messages {
+			Expect(t, m.Payload(), Equal(fmt.Appendf(nil, "%d", i)))
+			Expect(t, m.Offset(), Equal(int64(i)))
+			Expect(t, m.ProducedBy(), Equal("pub"))
+			Expect(t, m.RetryCount(), Equal(uint32(0)))
+		}
+		Expect(t, c.Close(), Succeed())
+		Expect(t, b.Backlog(topic, topic), Equal(0))
+
+		_, err = p.Publish(ctx, "other", nil)
+		Expect(t, err, IsCodeError(mq.ERROR__PUB_INVALID_MESSAGE))
+	})
+
+	t.Run("PartitionOrdered", func(t *testing.T) {
+		b := NewBroker()
+		defer b.Close()
+
+		topic := TopicFor(t)
+		p, _ := b.NewProducer(ctx, WithPubTopic(topic))
+		for i := range 30 {
+			_, err := p.PublishWithKey(ctx, topic, fmt.Sprintf("key%d", i%3), fmt.Appendf(nil, "%d", i))
+			Expect(t, err, Succeed())
+		}
+
+		c, _ := b.NewConsumer(ctx, WithSubTopic(topic), WithSubConsumingMode(mq.PartitionOrdered), WithSubWorkerSize(3))
+		messages := consume(t, c, 30, func(context.Context, ConsumerMessage) error {
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+		last := make(map[string]int64)
+		for _, m := range messages {
+			if offset, ok := last[m.PartitionKey()]; ok {
+				Expect(t, m.Offset() > offset, BeTrue())
+			}
+			last[m.PartitionKey()] = m.Offset()
+		}
+		Expect(t, last, HaveLen[map[string]int64](3))
+	})
+
+	t.Run("DelayAndExpiration", func(t *testing.T) {
+		b := NewBroker()
+		defer b.Close()
+
+		topic := TopicFor(t)
+		p, _ := b.NewProducer(ctx, WithPubTopic(topic))
+
+		delayed := NewProducerMessage(topic, []byte("delayed"))
+		delayed.SetDelay(200 * time.Millisecond)
+		Expect(t, p.PublishMessage(ctx, delayed), Succeed())
+
+		expired := NewProducerMessage(topic, []byte("expired"))
+		expired.SetExpiredAfter(-2 * time.Second)
+		Expect(t, p.PublishMessage(ctx, expired), Succeed())
+
+		_, err := p.Publish(ctx, topic, []byte("normal"))
+		Expect(t, err, Succeed())
+
+		c, _ := b.NewConsumer(ctx, WithSubTopic(topic))
+		start := time.Now()
+		messages := consume(t, c, 2, nil)
+		Expect(t, messages[0].Payload(), Equal([]byte("normal")))
+		Expect(t, messages[1].Payload(), Equal([]byte("delayed")))
+		Expect(t, time.Since(start) >= 150*time.Millisecond, BeTrue())
+	})
+
+	t.Run("NackRetryAndDLQ", func(t *testing.T) {
+		b := NewBroker()
+		defer b.Close()
+
+		topic := TopicFor(t)
+		p, _ := b.NewProducer(ctx, WithPubTopic(topic))
+		_, _ = p.Publish(ctx, topic, []byte("retry"))
+
+		c, _ := b.NewConsumer(
+			ctx,
+			WithSubTopic(topic),
+			WithSubEnableRetryNack(2),
+			WithSubDisableAutoAck(),
+			WithSubCallback(func(a mq.Acknowledger[ConsumerMessage], m ConsumerMessage, err error) {
+				if err != nil {
+					_ = a.Nack(m)
+					return
+				}
+				_ = a.Ack(m)
+			}),
+		)
+		messages := consume(t, c, 3, func(context.Context, ConsumerMessage) error {
+			return errors.New("failed")
+		})
+		for i, m := range messages {
+			Expect(t, m.RetryCount(), Equal(uint32(i)))
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for len(b.Messages(topic+DLQ_SUFFIX)) == 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		dead := b.Messages(topic + DLQ_SUFFIX)
+		Expect(t, dead, HaveLen[[]ConsumerMessage](1))
+		Expect(t, dead[0].RetryCount(), Equal(uint32(3)))
+		Expect(t, dead[0].Payload(), Equal([]byte("retry")))
+	})
+
+	t.Run("Discard", func(t *testing.T) {
+		b := NewBroker()
+		defer b.Close()
+
+		topic := TopicFor(t)
+		p, _ := b.NewProducer(ctx, WithPubTopic(topic))
+		_, _ = p.Publish(ctx, topic, []byte("invalid"))
+
+		c, _ := b.NewConsumer(ctx, WithSubTopic(topic), WithSubDisableAutoAck(), WithSubCallback(
+			func(a mq.Acknowledger[ConsumerMessage], m ConsumerMessage, err error) {
+				_ = a.(mq.AcknowledgerCanDiscard[ConsumerMessage]).Discard(m)
+			},
+		))
+		consume(t, c, 1, nil)
+		Expect(t, c.Close(), Succeed())
+
+		Expect(t, b.Messages(topic+DLQ_SUFFIX), HaveLen[[]ConsumerMessage](1))
+		Expect(t, b.Backlog(topic, topic), Equal(0))
+	})
+
+	t.Run("RedeliverUnacked", func(t *testing.T) {
+		b := NewBroker()
+		defer b.Close()
+
+		topic := TopicFor(t)
+		p, _ := b.NewProducer(ctx, WithPubTopic(topic))
+		_, _ = p.Publish(ctx, topic, []byte("unacked"))
+
+		c, _ := b.NewConsumer(ctx, WithSubTopic(topic), WithSubDisableAutoAck(), WithSubCallback(
+			func(mq.Acknowledger[ConsumerMessage], ConsumerMessage, error) {},
+		))
+		consume(t, c, 1, nil)
+		Expect(t, b.Backlog(topic, topic), Equal(1))
+		Expect(t, c.Close(), Succeed())
+
+		c, _ = b.NewConsumer(ctx, WithSubTopic(topic))
+		messages := consume(t, c, 1, nil)
+		Expect(t, messages[0].Payload(), Equal([]byte("unacked")))
+	})
+
+	t.Run("Middleware", func(t *testing.T) {
+		b := NewBroker()
+		defer b.Close()
+
+		topic := TopicFor(t)
+		p, _ := b.NewProducer(ctx, WithPubTopic(topic), WithPubMiddleware(
+			func(next mq.PubHandler[ProducerMessage]) mq.PubHandler[ProducerMessage] {
+				return func(ctx context.Context, m ProducerMessage) error {
+					m.AddExtra("tenant", "t1")
+					return next(ctx, m)
+				}
+			},
+			mq.Validate[ProducerMessage](func(b []byte) error {
+				if len(b) == 0 {
+					return errors.New("empty payload")
+				}
+				return nil
+			}).Pub(),
+		))
+		_, err := p.Publish(ctx, topic, nil)
+		Expect(t, err, IsCodeError(mq.ERROR__INVALID_PAYLOAD))
+		_, err = p.Publish(ctx, topic, []byte("payload"))
+		Expect(t, err, Succeed())
+
+		tenants := make(chan string, 1)
+		c, _ := b.NewConsumer(ctx, WithSubTopic(topic), WithSubMiddleware(
+			func(next mq.SubHandler[ConsumerMessage]) mq.SubHandler[ConsumerMessage] {
+				return func(ctx context.Context, m ConsumerMessage) error {
+					v, _ := m.ExtraValueOf("tenant")
+					tenants <- v
+					return next(ctx, m)
+				}
+			},
+		))
+		consume(t, c, 1, nil)
+		Expect(t, <-tenants, Equal("t1"))
+		Expect(t, b.Messages(topic), HaveLen[[]ConsumerMessage](1))
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		b := NewBroker()
+		topic := TopicFor(t)
+
+		p, _ := b.NewProducer(ctx, WithPubTopic(topic))
+		c, _ := b.NewConsumer(ctx, WithSubTopic(topic))
+		Expect(t, b.ConsumerCount(), Equal(1))
+		Expect(t, b.ProducerCount(), Equal(1))
+
+		Expect(t, b.Close(), Succeed())
+		Expect(t, b.ConsumerCount(), Equal(0))
+
+		_, err := p.Publish(ctx, topic, nil)
+		Expect(t, err, IsCodeError(mq.ERROR__CLI_CLOSED))
+		err = c.Run(ctx, nil)
+		Expect(t, err, IsCodeError(mq.ERROR__CLI_CLOSED))
+		_, err = b.NewProducer(ctx, WithPubTopic(topic))
+		Expect(t, err, IsCodeError(mq.ERROR__CLI_CLOSED))
+	})
+}
@@ -0,0 +1,265 @@
+package memq
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xoctopus/logx"
+	"github.com/xoctopus/x/codex"
+
+	"github.com/xoctopus/confx/pkg/types/mq"
+)
+
+type consumer struct {
+	cli    *Broker
+	elem   *list.Element
+	closed atomic.Bool
+	booted atomic.Bool
+	sub    *subscription
+	log    logx.Logger
+
+	mode   mq.ConsumeHandleMode
+	worker uint16
+	buffer uint16
+	tasks  []chan *message
+	wg     sync.WaitGroup
+
+	hasher   mq.Hasher
+	handler  mq.SubHandler[ConsumerMessage]
+	callback mq.SubCallback[ConsumerMessage]
+
+	middlewares []mq.SubMiddleware[ConsumerMessage]
+
+	cancel    context.CancelCauseFunc
+	autoAck   bool
+	retryNack bool
+	maxRetry  uint32
+	nackDelay time.Duration
+}
+
+var (
+	_ mq.Consumer[ConsumerMessage]               = (*consumer)(nil)
+	_ mq.AcknowledgerCanDiscard[ConsumerMessage] = (*consumer)(nil)
+	_ mq.Unsubscriber                            = (*consumer)(nil)
+)
+
+func (s *consumer) process(ctx context.Context, wid uint16) error {
+	defer s.wg.Done()
+
+	log := s.log.With("worker_id", wid)
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.Join(ctx.Err(), context.Cause(ctx))
+		case m := <-s.tasks[wid]:
+			logd := log.With("offset", m.offset, "partition_key", m.key)
+			m.RefreshConsumedAt()
+			if m.expired(m.consumedAt) {
+				logd.Warn(fmt.Errorf("message expired at %d", m.ExpiredAt()))
+				s.sub.settle(m)
+				continue
+			}
+			if err := s.handle(ctx, m); err != nil {
+				logd.With("action", "handle").Error(err)
+			}
+			if s.autoAck || s.callback == nil {
+				if err := s.Ack(m); err != nil {
+					logd.With("action", "ack").Error(err)
+				}
+			}
+		}
+	}
+}
+
+func (s *consumer) dispatch(ctx context.Context) error {
+	var (
+		count uint16
+		wid   uint16
+	)
+	for {
+		m, err := s.sub.fetch(ctx, s)
+		if err != nil {
+			return err
+		}
+		switch s.mode {
+		case mq.PartitionOrdered:
+			wid = s.hasher(m.key) % s.worker
+		case mq.Concurrent:
+			count = (count + 1) % math.MaxUint16
+			wid = count % s.worker
+		default:
+			wid = 0
+		}
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case s.tasks[wid] <- m:
+		}
+	}
+}
+
+// Run starts consuming messages and processing them.
+func (s *consumer) Run(ctx context.Context, h mq.SubHandler[ConsumerMessage]) error {
+	if !s.booted.CompareAndSwap(false, true) {
+		return codex.Errorf(mq.ERROR__SUB_BOOTED, "reentered")
+	}
+	if s.cli.closed.Load() {
+		return codex.New(mq.ERROR__CLI_CLOSED)
+	}
+	if s.closed.Load() {
+		return codex.New(mq.ERROR__SUB_CLOSED)
+	}
+
+	s.tasks = make([]chan *message, s.worker)
+	for i := range s.tasks {
+		s.tasks[i] = make(chan *message, s.buffer)
+	}
+
+	s.handler = mq.ChainSub(h, s.middlewares...)
+	ctx, s.cancel = context.WithCancelCause(ctx)
+
+	defer func() { _ = s.Close() }()
+	s.wg.Add(int(s.worker))
+	for i := range s.worker {
+		go func() { _ = s.process(ctx, i) }()
+	}
+
+	err := s.dispatch(ctx)
+	s.log.Info(fmt.Sprintf("dispatching stopped caused by: %v", err))
+	return err
+}
+
+// handle wrapped consumer handle task
+func (s *consumer) handle(ctx context.Context, msg ConsumerMessage) (err error) {
+	_, log := logx.Enter(
+		ctx,
+		"topic", msg.Topic(),
+		"pub_at", msg.PublishedAt(),
+		"latency", msg.Latency().Milliseconds(),
+	)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = codex.Errorf(mq.ERROR__SUB_HANDLER_PANICKED, "cause: %v", r)
+			if x, ok := r.(error); ok {
+				err = codex.Wrap(mq.ERROR__SUB_HANDLER_PANICKED, x)
+			}
+		}
+		if err != nil {
+			log.Error(err)
+		} else {
+			log.Info("handled")
+		}
+		if s.callback != nil {
+			s.callback(s, msg, err)
+		}
+		log.End()
+	}()
+	return s.handler(ctx, msg)
+}
+
+func (s *consumer) delivered(m ConsumerMessage) (*message, error) {
+	x, ok := m.(*message)
+	if !ok {
+		return nil, codex.Errorf(mq.ERROR__SUB_PARSE_MESSAGE_ERROR, "message is not delivered by memq")
+	}
+	return x, nil
+}
+
+// Ack acknowledges message. acknowledging a settled message has no effect.
+func (s *consumer) Ack(m ConsumerMessage) error {
+	x, err := s.delivered(m)
+	if err != nil {
+		return err
+	}
+	s.sub.settle(x)
+	return nil
+}
+
+// Nack redelivers message with increased retry count after nack redelivery
+// delay if retry nack enabled. if reached max retry times the message is
+// published to dead letter topic. if retry nack disabled, the message is dropped.
+func (s *consumer) Nack(m ConsumerMessage) error {
+	x, err := s.delivered(m)
+	if err != nil {
+		return err
+	}
+	if !s.sub.settle(x) || !s.retryNack {
+		return nil
+	}
+
+	retry := x.clone()
+	retry.retry++
+	retry.delay, retry.deliverAt = s.nackDelay, time.Time{}
+	if retry.retry > s.maxRetry {
+		retry.topic = x.topic + DLQ_SUFFIX
+		retry.delay = 0
+	}
+	return s.cli.publish(retry)
+}
+
+// Discard publishes message to dead letter topic directly
+func (s *consumer) Discard(m ConsumerMessage) error {
+	x, err := s.delivered(m)
+	if err != nil {
+		return err
+	}
+	if !s.sub.settle(x) {
+		return nil
+	}
+
+	dead := x.clone()
+	dead.topic = x.topic + DLQ_SUFFIX
+	dead.delay, dead.deliverAt = 0, time.Time{}
+	return s.cli.publish(dead)
+}
+
+func (s *consumer) Elem() *list.Element {
+	return s.elem
+}
+
+func (s *consumer) SetElem(elem *list.Element) {
+	s.elem = elem
+}
+
+// Release stops consuming. messages delivered to this consumer but not
+// acknowledged are redelivered to other consumers of the subscription. if
+// opt.Unsub is set, the subscription is removed from topic.
+func (s *consumer) Release(appliers ...mq.ReleaseOptionFunc) error {
+	var opt mq.ReleaseOption
+
+	for _, applier := range appliers {
+		applier(&opt)
+	}
+
+	if s.closed.CompareAndSwap(false, true) {
+		cause := mq.ERROR__SUB_CLOSED
+		if opt.Unsub {
+			cause = mq.ERROR__SUB_UNSUBSCRIBED
+		}
+		if s.cancel != nil {
+			s.cancel(codex.New(cause))
+		}
+		s.wg.Wait()
+		s.sub.release(s)
+		if opt.Unsub {
+			s.sub.topic.unsubscribe(s.sub)
+		}
+		s.log.With("unsub", opt.Unsub).Info("consumer closed")
+	}
+	return nil
+}
+
+func (s *consumer) Unsubscribe() error {
+	return s.cli.ResourceManager.Unsubscribe(s)
+}
+
+func (s *consumer) Close() error {
+	return s.cli.ResourceManager.CloseConsumer(s)
+}
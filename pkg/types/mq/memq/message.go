@@ -0,0 +1,225 @@
+package memq
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/xoctopus/x/misc/must"
+
+	"github.com/xoctopus/confx/pkg/types/mq"
+)
+
+type ProducerMessage interface {
+	mq.HasTopic
+	mq.CanSetTopic
+	mq.HasPayload
+	mq.CanSetPayload
+	mq.HasExtra
+	mq.CanAppendExtra
+	mq.HasExpiredAt
+	mq.CanSetExpiredAt
+	mq.HasPartitionKey
+	mq.CanSetPartitionKey
+	mq.HasDelay
+	mq.CanSetDelay
+	mq.HasPublishedAt
+	mq.CanRefreshPublishedAt
+}
+
+func NewProducerMessage(topic string, payload []byte) ProducerMessage {
+	m := &message{}
+	m.SetTopic(topic)
+	m.SetPayload(payload)
+	m.RefreshPublishedAt()
+	return m
+}
+
+type ConsumerMessage interface {
+	mq.HasTopic
+	mq.HasPayload
+	mq.HasExtra
+	mq.HasExpiredAt
+	mq.HasPartitionKey
+	mq.HasPartitionID
+	mq.HasOffset
+	mq.HasMessageID
+	mq.HasProducer
+	mq.HasPublishedAt
+	mq.HasConsumedAt
+	mq.CanRefreshConsumedAt
+	mq.HasLatency
+	mq.HasRetryCount
+}
+
+// message implements both ProducerMessage and ConsumerMessage. the broker keeps
+// a copy of published message, and each delivery is another copy bound to its
+// subscription for acknowledgement.
+type message struct {
+	topic     string
+	payload   []byte
+	extra     map[string]string
+	expiredAt int64
+	key       string
+	delay     time.Duration
+	deliverAt time.Time
+
+	publishedAt time.Time
+	consumedAt  time.Time
+	producer    string
+	partition   int64
+	offset      int64
+	retry       uint32
+
+	// owner is the consumer the message delivered to
+	owner *consumer
+}
+
+func (x *message) Topic() string {
+	return x.topic
+}
+
+func (x *message) SetTopic(topic string) {
+	x.topic = topic
+}
+
+func (x *message) Payload() []byte {
+	return x.payload
+}
+
+func (x *message) SetPayload(payload []byte) {
+	x.payload = payload
+}
+
+func (x *message) Extra() map[string]string {
+	return x.extra
+}
+
+func (x *message) ExtraValueOf(k string) (string, bool) {
+	v, ok := x.extra[k]
+	return v, ok
+}
+
+func (x *message) AddExtra(k, v string) {
+	if x.extra == nil {
+		x.extra = make(map[string]string)
+	}
+	x.extra[k] = v
+}
+
+func (x *message) ExpiredAt() int64 {
+	if x.expiredAt == 0 {
+		return math.MaxInt64
+	}
+	return x.expiredAt
+}
+
+func (x *message) SetExpiredAt(expiredAt int64) {
+	must.BeTrueF(expiredAt > time.Now().Unix(), "invalid expired timestamp")
+	x.expiredAt = expiredAt
+}
+
+func (x *message) SetExpiredAfter(du time.Duration) {
+	x.expiredAt = time.Now().Add(du).Unix()
+}
+
+// expired reports if message is expired at t
+func (x *message) expired(t time.Time) bool {
+	return t.Unix() > x.ExpiredAt()
+}
+
+func (x *message) PartitionKey() string {
+	return x.key
+}
+
+func (x *message) SetPartitionKey(k string) {
+	x.key = k
+}
+
+func (x *message) Delay() time.Duration {
+	return x.delay
+}
+
+func (x *message) SetDelay(du time.Duration) {
+	if du > 0 {
+		x.delay = du
+	}
+}
+
+func (x *message) SetDeliveryAt(t time.Time) {
+	x.deliverAt = t
+}
+
+// due returns the time message can be delivered since it published
+func (x *message) due() time.Duration {
+	d := x.delay
+	if !x.deliverAt.IsZero() {
+		d = max(d, time.Until(x.deliverAt))
+	}
+	return d
+}
+
+func (x *message) PartitionID() int64 {
+	return x.partition
+}
+
+func (x *message) Offset() int64 {
+	return x.offset
+}
+
+// MessageID returns offset of message in its topic
+func (x *message) MessageID() string {
+	return strconv.FormatInt(x.offset, 10)
+}
+
+func (x *message) ProducedBy() string {
+	return x.producer
+}
+
+func (x *message) PublishedAt() time.Time {
+	return x.publishedAt
+}
+
+func (x *message) RefreshPublishedAt() {
+	x.publishedAt = time.Now()
+}
+
+func (x *message) ConsumedAt() time.Time {
+	return x.consumedAt
+}
+
+func (x *message) RefreshConsumedAt() {
+	x.consumedAt = time.Now()
+}
+
+func (x *message) Latency() time.Duration {
+	t1, t2 := x.PublishedAt(), x.ConsumedAt()
+	if !t1.IsZero() && !t2.IsZero() && t1.Before(t2) {
+		return t2.Sub(t1)
+	}
+	return 0
+}
+
+func (x *message) RetryCount() uint32 {
+	return x.retry
+}
+
+// clone copies message without delivery state
+func (x *message) clone() *message {
+	return &message{
+		topic:       x.topic,
+		payload:     slices.Clone(x.payload),
+		extra:       maps.Clone(x.extra),
+		expiredAt:   x.expiredAt,
+		key:         x.key,
+		delay:       x.delay,
+		deliverAt:   x.deliverAt,
+		publishedAt: x.publishedAt,
+		producer:    x.producer,
+		partition:   x.partition,
+		offset:      x.offset,
+		retry:       x.retry,
+	}
+}
@@ -0,0 +1,325 @@
+package memq
+
+import (
+	"slices"
+	"time"
+
+	"github.com/xoctopus/x/misc/must"
+	"github.com/xoctopus/x/textx"
+
+	"github.com/xoctopus/confx/pkg/types"
+	"github.com/xoctopus/confx/pkg/types/mq"
+)
+
+// DLQ_SUFFIX is appended to the consuming topic as the dead letter topic of
+// messages reached max nack retry times or discarded
+const DLQ_SUFFIX = "_DLQ"
+
+// Option presents broker options and default pub/sub options. it can be
+// overridden by option applier when call Broker.NewProducer and
+// Broker.NewConsumer
+type Option struct {
+	// Partitions [Broker] number of partitions of each topic. partition id of
+	// a message is hashed from its partition key
+	Partitions uint16 `url:",default=1"`
+
+	// StartFromLatest [SUB] if enabled, a new subscription starts consuming
+	// from the latest message, otherwise the earliest.
+	StartFromLatest bool `url:",default=false"`
+	// EnableRetryNack [SUB] if enabled, NACKed message will be redelivered to
+	// its topic max MaxNackRetry times. if reached MaxNackRetry times, the
+	// message is published to the dead letter topic `<topic>_DLQ`. if disabled
+	// NACKed message is dropped.
+	EnableRetryNack bool `url:",default=true"`
+	// MaxNackRetry [SUB] max retry times for nack message
+	MaxNackRetry uint32 `url:",default=3"`
+	// NackRedeliveryDelay [SUB] delay of redelivering NACKed message
+	NackRedeliveryDelay types.Duration `url:",default=0s"`
+	// WorkerSize defines the concurrency level for message consumption.
+	// Behavior based on ConsumeMode:
+	// eg:
+	//	- mq.GlobalOrdered: Forced to 1 to ensure strict sequential processing.
+	//	- mq.PartitionOrdered: Messages are dispatched to specific workers based on
+	//	  a hash of the partition key, ensuring order within the same key.
+	//	- mq.Concurrent: messages are distributed across all workers (e.g., round-robin)
+	//	  to maximize throughput.
+	WorkerSize uint16 `url:",default=16"`
+	// WorkerBufferSize [SUB] prefetched messages of each worker
+	WorkerBufferSize uint16 `url:",default=64"`
+
+	// defaultPubOption default publisher option
+	defaultPubOption *PubOption
+	// defaultSubOption default subscriber option
+	defaultSubOption *SubOption
+}
+
+func (o *Option) SetDefault() {
+	must.NoErrorV(textx.SetDefault(o))
+
+	if o.Partitions == 0 {
+		o.Partitions = 1
+	}
+	if o.defaultPubOption == nil {
+		o.defaultPubOption = &PubOption{}
+	}
+	if !o.defaultPubOption._initialized {
+		o.defaultPubOption = &PubOption{}
+		o.defaultPubOption._initialized = true
+	}
+	if o.defaultSubOption == nil {
+		o.defaultSubOption = &SubOption{}
+	}
+	if !o.defaultSubOption._initialized {
+		o.defaultSubOption = &SubOption{
+			latest:    o.StartFromLatest,
+			retryNack: o.EnableRetryNack,
+			maxRetry:  o.MaxNackRetry,
+			nackDelay: time.Duration(o.NackRedeliveryDelay),
+			worker:    o.WorkerSize,
+			hasher:    mq.CRC,
+			buffer:    o.WorkerBufferSize,
+		}
+		o.defaultSubOption._initialized = true
+	}
+}
+
+func (o *Option) PubOption(appliers ...mq.OptionApplier) *PubOption {
+	opt := *o.defaultPubOption
+	for _, applier := range appliers {
+		applier.Apply(&opt)
+	}
+	must.BeTrueF(opt.topic != "", "producer topic is required")
+	return &opt
+}
+
+func (o *Option) SubOption(appliers ...mq.OptionApplier) *SubOption {
+	opt := *o.defaultSubOption
+	for _, applier := range appliers {
+		applier.Apply(&opt)
+	}
+
+	must.BeTrueF(len(opt.topic) > 0, "consumer topic is required")
+	if opt.name == "" {
+		opt.name = opt.topic
+	}
+	if opt.worker == 0 {
+		opt.worker = 16
+	}
+	if opt.mode == mq.GlobalOrdered {
+		opt.worker = 1
+	}
+	if opt.buffer == 0 {
+		opt.buffer = 16
+	}
+	if opt.hasher == nil {
+		opt.hasher = mq.CRC
+	}
+	return &opt
+}
+
+type PubOption struct {
+	_initialized bool
+	// callback is called when message is published
+	callback mq.AsyncPubCallback[ProducerMessage]
+	// middlewares wrap publishing, the first is the outermost
+	middlewares []mq.PubMiddleware[ProducerMessage]
+	// disableTracing if trace propagation middleware is not wrapped
+	disableTracing bool
+	// topic producer topic
+	topic string
+	// name producer name attached to message
+	name string
+}
+
+func (o *PubOption) Topic() string { return o.topic }
+
+func (*PubOption) OptionScheme() string { return "memq" }
+
+func (o *PubOption) AddPubMiddleware(middlewares ...mq.PubMiddleware[ProducerMessage]) {
+	o.middlewares = append(slices.Clip(o.middlewares), middlewares...)
+}
+
+func (o *PubOption) DisableTracing() { o.disableTracing = true }
+
+// WithPublishCallback sets callback called when message is published. memq
+// publishes synchronously, the callback is called before PublishMessage
+// returns.
+func WithPublishCallback(f mq.AsyncPubCallback[ProducerMessage]) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*PubOption); ok {
+			x.callback = f
+		}
+	})
+}
+
+func WithPubTopic(topic string) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*PubOption); ok {
+			x.topic = topic
+		}
+	})
+}
+
+func WithPublisherName(name string) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*PubOption); ok {
+			x.name = name
+		}
+	})
+}
+
+type SubOption struct {
+	_initialized bool
+	// disableAutoAck disable auto ack. if this option is set true, message ack
+	// should be handled by callback.
+	disableAutoAck bool
+	// callback it is called when message handled
+	callback mq.SubCallback[ConsumerMessage]
+	// middlewares wrap handler, the first is the outermost
+	middlewares []mq.SubMiddleware[ConsumerMessage]
+	// disableTracing if trace propagation middleware is not wrapped
+	disableTracing bool
+	// topic consuming topic
+	topic string
+	// name subscription name, consumers with the same subscription name share
+	// messages. default is topic
+	name string
+	// latest if a new subscription starts from the latest message
+	latest bool
+	// retryNack if enabled NACKed message will be redelivered
+	retryNack bool
+	// maxRetry max retry times for nack message
+	maxRetry uint32
+	// nackDelay delay of redelivering NACKed message
+	nackDelay time.Duration
+	// worker specifies the consumer concurrency level
+	worker uint16
+	// buffer prefetched messages of each worker
+	buffer uint16
+	// hasher helps to hash message partition key
+	hasher mq.Hasher
+	// mode consumer handling mode
+	mode mq.ConsumeHandleMode
+}
+
+func (*SubOption) OptionScheme() string { return "memq" }
+
+func (o *SubOption) AddSubMiddleware(middlewares ...mq.SubMiddleware[ConsumerMessage]) {
+	o.middlewares = append(slices.Clip(o.middlewares), middlewares...)
+}
+
+func (o *SubOption) DisableTracing() { o.disableTracing = true }
+
+// WithSubDisableAutoAck disables auto ack. if this option is set, message ack
+// should be handled by callback.
+func WithSubDisableAutoAck() mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.disableAutoAck = true
+		}
+	})
+}
+
+// WithSubCallback set subscriber's callback when message is handled.
+func WithSubCallback(f mq.SubCallback[ConsumerMessage]) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.callback = f
+		}
+	})
+}
+
+func WithSubTopic(topic string) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.topic = topic
+		}
+	})
+}
+
+func WithSubName(name string) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.name = name
+		}
+	})
+}
+
+// WithSubStartFromLatest makes a new subscription skip messages published
+// before it is created.
+func WithSubStartFromLatest() mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.latest = true
+		}
+	})
+}
+
+func WithSubEnableRetryNack(maxRetry uint32) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.retryNack = true
+			x.maxRetry = maxRetry
+		}
+	})
+}
+
+func WithSubDisableRetryNack() mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.retryNack = false
+		}
+	})
+}
+
+func WithSubNackRedeliveryDelay(d time.Duration) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.nackDelay = d
+		}
+	})
+}
+
+func WithSubWorkerSize(n uint16) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.worker = n
+		}
+	})
+}
+
+func WithSubWorkerBufferSize(n uint16) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.buffer = n
+		}
+	})
+}
+
+func WithSubOrderedKeyHasher(h mq.Hasher) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.hasher = h
+		}
+	})
+}
+
+func WithSubConsumingMode(mode mq.ConsumeHandleMode) mq.OptionApplier {
+	return mq.OptionApplyFunc(func(opt mq.Option) {
+		if x, ok := opt.(*SubOption); ok {
+			x.mode = mode
+		}
+	})
+}
+
+type (
+	Producer = mq.Producer[ProducerMessage]
+	Consumer = mq.Consumer[ConsumerMessage]
+	Observer = mq.Observer[ConsumerMessage]
+	PubSub   = mq.PubSub[Producer, Consumer]
+)
+
+var (
+	WithSubMiddleware = mq.WithSubMiddleware[ConsumerMessage]
+	WithPubMiddleware = mq.WithPubMiddleware[ProducerMessage]
+)
@@ -0,0 +1,108 @@
+package memq
+
+import (
+	"container/list"
+	"context"
+	"sync/atomic"
+
+	"github.com/xoctopus/logx"
+	"github.com/xoctopus/x/codex"
+
+	"github.com/xoctopus/confx/pkg/types/mq"
+)
+
+type producer struct {
+	cli    *Broker
+	elem   *list.Element
+	closed atomic.Bool
+
+	log      logx.Logger
+	topic    string
+	name     string
+	callback mq.AsyncPubCallback[ProducerMessage]
+
+	middlewares []mq.PubMiddleware[ProducerMessage]
+}
+
+var _ mq.Producer[ProducerMessage] = (*producer)(nil)
+
+func (p *producer) Topic() string {
+	return p.topic
+}
+
+func (p *producer) Publish(ctx context.Context, topic string, payload []byte) (ProducerMessage, error) {
+	msg := NewProducerMessage(topic, payload)
+	return msg, p.PublishMessage(ctx, msg)
+}
+
+func (p *producer) PublishWithKey(ctx context.Context, topic, key string, payload []byte) (ProducerMessage, error) {
+	msg := NewProducerMessage(topic, payload)
+	msg.SetPartitionKey(key)
+	return msg, p.PublishMessage(ctx, msg)
+}
+
+// PublishMessage publishes a copy of msg, modifying msg after published does
+// not affect message delivered.
+func (p *producer) PublishMessage(ctx context.Context, msg ProducerMessage) error {
+	return mq.ChainPub(p.send, p.middlewares...)(ctx, msg)
+}
+
+// send publishes msg without middlewares
+func (p *producer) send(ctx context.Context, msg ProducerMessage) (err error) {
+	_, log := logx.Enter(ctx)
+	defer func() {
+		if err != nil {
+			log.Error(err)
+		} else {
+			log.Info("published")
+		}
+		if p.callback != nil {
+			p.callback(msg, err)
+		}
+		log.End()
+	}()
+
+	if topic := msg.Topic(); topic != p.topic {
+		return codex.Errorf(
+			mq.ERROR__PUB_INVALID_MESSAGE,
+			"unexpected topic: expect `%s` but got `%s`",
+			p.topic, topic,
+		)
+	}
+
+	x, ok := msg.(*message)
+	if !ok {
+		return codex.Errorf(mq.ERROR__PUB_INVALID_MESSAGE, "message is not created by memq")
+	}
+
+	if p.cli.closed.Load() {
+		return codex.New(mq.ERROR__CLI_CLOSED)
+	}
+
+	if p.closed.Load() {
+		return codex.New(mq.ERROR__PUB_CLOSED)
+	}
+
+	x.RefreshPublishedAt()
+	m := x.clone()
+	m.producer = p.name
+	log = log.With("pub_at", m.publishedAt, "partition_key", m.key)
+	return p.cli.publish(m)
+}
+
+func (p *producer) Elem() *list.Element {
+	return p.elem
+}
+
+func (p *producer) SetElem(elem *list.Element) {
+	p.elem = elem
+}
+
+func (p *producer) Release(_ ...mq.ReleaseOptionFunc) error {
+	p.closed.Store(true)
+	return nil
+}
+
+func (p *producer) Close() error {
+	return p.cli.CloseProducer(p)
+}
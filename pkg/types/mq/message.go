@@ -121,6 +121,11 @@ type CanSetOffset interface {
 	SetOffset(int64)
 }
 
+// HasMessageID presents message id assigned by broker or producer
+type HasMessageID interface {
+	MessageID() string
+}
+
 type HasProducer interface {
 	ProducedBy() string
 }
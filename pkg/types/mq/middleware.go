@@ -0,0 +1,184 @@
+package mq
+
+import (
+	"context"
+	"time"
+
+	"github.com/xoctopus/x/codex"
+)
+
+type (
+	// SubMiddleware wraps SubHandler for cross-cutting behavior of consuming,
+	// such as recovering, metrics or tenant extraction.
+	SubMiddleware[CM any] func(SubHandler[CM]) SubHandler[CM]
+	// PubHandler publishes message. it is the innermost handler of producer's
+	// PublishMessage
+	PubHandler[PM any] func(context.Context, PM) error
+	// PubMiddleware wraps PubHandler for cross-cutting behavior of publishing,
+	// such as injecting extra or validation.
+	PubMiddleware[PM any] func(PubHandler[PM]) PubHandler[PM]
+)
+
+// Pub converts m to PubMiddleware, so that built-in middlewares can be used when
+// publishing
+func (m SubMiddleware[M]) Pub() PubMiddleware[M] {
+	return func(next PubHandler[M]) PubHandler[M] {
+		return PubHandler[M](m(SubHandler[M](next)))
+	}
+}
+
+// ChainSub wraps h with middlewares. the first middleware is the outermost.
+func ChainSub[CM any](h SubHandler[CM], middlewares ...SubMiddleware[CM]) SubHandler[CM] {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			h = middlewares[i](h)
+		}
+	}
+	return h
+}
+
+// ChainPub wraps h with middlewares. the first middleware is the outermost.
+func ChainPub[PM any](h PubHandler[PM], middlewares ...PubMiddleware[PM]) PubHandler[PM] {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			h = middlewares[i](h)
+		}
+	}
+	return h
+}
+
+// CanAddSubMiddleware is implemented by subscriber option of drivers which
+// honor WithSubMiddleware
+type CanAddSubMiddleware[CM any] interface {
+	AddSubMiddleware(...SubMiddleware[CM])
+}
+
+// CanAddPubMiddleware is implemented by publisher option of drivers which
+// honor WithPubMiddleware
+type CanAddPubMiddleware[PM any] interface {
+	AddPubMiddleware(...PubMiddleware[PM])
+}
+
+// WithSubMiddleware appends middlewares wrapping consumer's SubHandler. CM must
+// be the ConsumerMessage of driver, eg:
+//
+//	mq.WithSubMiddleware[confkafka.ConsumerMessage](mq.Timeout[confkafka.ConsumerMessage](time.Second))
+func WithSubMiddleware[CM any](middlewares ...SubMiddleware[CM]) OptionApplier {
+	return OptionApplyFunc(func(opt Option) {
+		if x, ok := opt.(CanAddSubMiddleware[CM]); ok {
+			x.AddSubMiddleware(middlewares...)
+		}
+	})
+}
+
+// WithPubMiddleware appends middlewares wrapping producer's PublishMessage. PM
+// must be the ProducerMessage of driver.
+func WithPubMiddleware[PM any](middlewares ...PubMiddleware[PM]) OptionApplier {
+	return OptionApplyFunc(func(opt Option) {
+		if x, ok := opt.(CanAddPubMiddleware[PM]); ok {
+			x.AddPubMiddleware(middlewares...)
+		}
+	})
+}
+
+// CanDisableTracing is implemented by publisher and subscriber option of drivers
+// which wrap trace propagation middleware (see mqotel) as the outermost by default
+type CanDisableTracing interface {
+	DisableTracing()
+}
+
+// WithoutTracing disables built-in trace propagation of producer or consumer. it
+// helps when tracing is not wanted or is placed by caller elsewhere in chain, eg:
+//
+//	mq.WithoutTracing(),
+//	mq.WithSubMiddleware[confkafka.ConsumerMessage](
+//		mq.Recover[confkafka.ConsumerMessage](),
+//		mqotel.SubTracing[confkafka.ConsumerMessage]("kafka"),
+//	)
+func WithoutTracing() OptionApplier {
+	return OptionApplyFunc(func(opt Option) {
+		if x, ok := opt.(CanDisableTracing); ok {
+			x.DisableTracing()
+		}
+	})
+}
+
+// Recover converts panic of handler to ERROR__SUB_HANDLER_PANICKED
+func Recover[M any]() SubMiddleware[M] {
+	return func(next SubHandler[M]) SubHandler[M] {
+		return func(ctx context.Context, m M) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = codex.Errorf(ERROR__SUB_HANDLER_PANICKED, "cause: %v", r)
+					if x, ok := r.(error); ok {
+						err = codex.Wrap(ERROR__SUB_HANDLER_PANICKED, x)
+					}
+				}
+			}()
+			return next(ctx, m)
+		}
+	}
+}
+
+// Timeout limits handling in d. handler should respect ctx, a handler not
+// returned in d is left running and ERROR__HANDLER_TIMEOUT is returned.
+func Timeout[M any](d time.Duration) SubMiddleware[M] {
+	return func(next SubHandler[M]) SubHandler[M] {
+		return func(ctx context.Context, m M) error {
+			if d <= 0 {
+				return next(ctx, m)
+			}
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() {
+				defer func() {
+					if r := recover(); r != nil {
+						done <- codex.Errorf(ERROR__SUB_HANDLER_PANICKED, "cause: %v", r)
+					}
+				}()
+				done <- next(ctx, m)
+			}()
+
+			select {
+			case err := <-done:
+				return err
+			case <-ctx.Done():
+				return codex.Errorf(ERROR__HANDLER_TIMEOUT, "in %s", d)
+			}
+		}
+	}
+}
+
+// Limiter limits rate of handling. *rate.Limiter of golang.org/x/time/rate
+// satisfies it.
+type Limiter interface {
+	Wait(context.Context) error
+}
+
+// RateLimit waits l before handling. the error of waiting is returned if ctx is
+// done before permitted.
+func RateLimit[M any](l Limiter) SubMiddleware[M] {
+	return func(next SubHandler[M]) SubHandler[M] {
+		return func(ctx context.Context, m M) error {
+			if err := l.Wait(ctx); err != nil {
+				return codex.Wrap(ERROR__RATE_LIMITED, err)
+			}
+			return next(ctx, m)
+		}
+	}
+}
+
+// Validate validates payload before handling, message failed to validate is
+// not passed to handler and ERROR__INVALID_PAYLOAD is returned.
+func Validate[M HasPayload](validate func([]byte) error) SubMiddleware[M] {
+	return func(next SubHandler[M]) SubHandler[M] {
+		return func(ctx context.Context, m M) error {
+			if err := validate(m.Payload()); err != nil {
+				return codex.Wrap(ERROR__INVALID_PAYLOAD, err)
+			}
+			return next(ctx, m)
+		}
+	}
+}
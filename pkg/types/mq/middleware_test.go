@@ -0,0 +1,116 @@
+package mq_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/types/mq"
+)
+
+type payload []byte
+
+func (p payload) Payload() []byte { return p }
+
+type limiter struct{ err error }
+
+func (l *limiter) Wait(context.Context) error { return l.err }
+
+type subOption struct {
+	sub     []mq.SubMiddleware[payload]
+	pub     []mq.PubMiddleware[payload]
+	untrace bool
+}
+
+func (*subOption) OptionScheme() string { return "testing" }
+
+func (o *subOption) AddSubMiddleware(mws ...mq.SubMiddleware[payload]) { o.sub = append(o.sub, mws...) }
+
+func (o *subOption) AddPubMiddleware(mws ...mq.PubMiddleware[payload]) { o.pub = append(o.pub, mws...) }
+
+func (o *subOption) DisableTracing() { o.untrace = true }
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Chain", func(t *testing.T) {
+		var order []string
+		trace := func(name string) mq.SubMiddleware[payload] {
+			return func(next mq.SubHandler[payload]) mq.SubHandler[payload] {
+				return func(ctx context.Context, m payload) error {
+					order = append(order, name)
+					return next(ctx, m)
+				}
+			}
+		}
+		h := mq.ChainSub(func(context.Context, payload) error {
+			order = append(order, "handler")
+			return nil
+		}, trace("a"), nil, trace("b"))
+		Expect(t, h(ctx, nil), Succeed())
+		Expect(t, order, Equal([]string{"a", "b", "handler"}))
+
+		order = order[:0]
+		p := mq.ChainPub(func(context.Context, payload) error {
+			order = append(order, "send")
+			return nil
+		}, trace("a").Pub(), trace("b").Pub())
+		Expect(t, p(ctx, nil), Succeed())
+		Expect(t, order, Equal([]string{"a", "b", "send"}))
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		opt := &subOption{}
+		mq.WithSubMiddleware[payload](mq.Recover[payload]()).Apply(opt)
+		mq.WithPubMiddleware[payload](mq.Recover[payload]().Pub()).Apply(opt)
+		mq.WithSubMiddleware[[]byte](mq.Recover[[]byte]()).Apply(opt)
+		Expect(t, opt.sub, HaveLen[[]mq.SubMiddleware[payload]](1))
+		Expect(t, opt.pub, HaveLen[[]mq.PubMiddleware[payload]](1))
+
+		Expect(t, opt.untrace, BeFalse())
+		mq.WithoutTracing().Apply(opt)
+		Expect(t, opt.untrace, BeTrue())
+	})
+
+	t.Run("Recover", func(t *testing.T) {
+		h := mq.Recover[payload]()(func(context.Context, payload) error {
+			panic(errors.New("any"))
+		})
+		Expect(t, h(ctx, nil), IsCodeError(mq.ERROR__SUB_HANDLER_PANICKED))
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		h := mq.Timeout[payload](10 * time.Millisecond)(func(ctx context.Context, _ payload) error {
+			<-ctx.Done()
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		})
+		Expect(t, h(ctx, nil), IsCodeError(mq.ERROR__HANDLER_TIMEOUT))
+
+		h = mq.Timeout[payload](time.Second)(func(context.Context, payload) error { return nil })
+		Expect(t, h(ctx, nil), Succeed())
+	})
+
+	t.Run("RateLimit", func(t *testing.T) {
+		l := &limiter{}
+		h := mq.RateLimit[payload](l)(func(context.Context, payload) error { return nil })
+		Expect(t, h(ctx, nil), Succeed())
+
+		l.err = context.Canceled
+		Expect(t, h(ctx, nil), IsCodeError(mq.ERROR__RATE_LIMITED))
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		h := mq.Validate[payload](func(b []byte) error {
+			if len(b) == 0 {
+				return errors.New("empty payload")
+			}
+			return nil
+		})(func(context.Context, payload) error { return nil })
+		Expect(t, h(ctx, payload("{}")), Succeed())
+		Expect(t, h(ctx, nil), IsCodeError(mq.ERROR__INVALID_PAYLOAD))
+	})
+}
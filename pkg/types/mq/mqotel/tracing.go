@@ -0,0 +1,169 @@
+// Package mqotel propagates OpenTelemetry trace context through message queues.
+// W3C traceparent, tracestate and baggage are written to message extra when
+// publishing and extracted when consuming, spans follow OTel messaging semantic
+// conventions. TracerProvider is taken from context injected by confotel, and
+// falls back to the global one.
+package mqotel
+
+import (
+	"context"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/xoctopus/confx/internal/otel/providers"
+	"github.com/xoctopus/confx/pkg/types/mq"
+)
+
+// ScopeName is the instrumentation scope of mq tracer
+const ScopeName = "github.com/xoctopus/confx/pkg/types/mq"
+
+// Propagator propagates W3C trace context and baggage
+var Propagator propagation.TextMapPropagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{},
+	propagation.Baggage{},
+)
+
+type (
+	// PubMessage is producer message can carry trace context
+	PubMessage interface {
+		mq.HasTopic
+		mq.HasExtra
+		mq.CanAppendExtra
+	}
+	// SubMessage is consumer message carried trace context
+	SubMessage interface {
+		mq.HasTopic
+		mq.HasExtra
+	}
+)
+
+// carrier adapts message extra to propagation.TextMapCarrier
+type carrier struct {
+	mq.HasExtra
+	set func(k, v string)
+}
+
+func (c carrier) Get(k string) string {
+	v, _ := c.ExtraValueOf(k)
+	return v
+}
+
+func (c carrier) Set(k, v string) {
+	if c.set != nil {
+		c.set(k, v)
+	}
+}
+
+func (c carrier) Keys() []string {
+	keys := make([]string, 0, len(c.Extra()))
+	for k := range c.Extra() {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Inject writes trace context and baggage of ctx to message extra
+func Inject(ctx context.Context, m PubMessage) {
+	Propagator.Inject(ctx, carrier{HasExtra: m, set: m.AddExtra})
+}
+
+// Extract returns ctx with remote span context and baggage carried by message
+func Extract(ctx context.Context, m mq.HasExtra) context.Context {
+	return Propagator.Extract(ctx, carrier{HasExtra: m})
+}
+
+func tracer(ctx context.Context) trace.Tracer {
+	tp, ok := providers.TracerProviderFrom(ctx)
+	if !ok || tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(ScopeName)
+}
+
+// PubTracing starts a producer span `send <topic>` for each publishing and
+// injects its context to message. system is the messaging system, such as
+// kafka, pulsar or rabbitmq. attrs are attached to span.
+func PubTracing[PM PubMessage](system string, attrs ...attribute.KeyValue) mq.PubMiddleware[PM] {
+	return func(next mq.PubHandler[PM]) mq.PubHandler[PM] {
+		return func(ctx context.Context, m PM) (err error) {
+			ctx, span := tracer(ctx).Start(
+				ctx, "send "+m.Topic(),
+				trace.WithSpanKind(trace.SpanKindProducer),
+				trace.WithAttributes(attributes(system, semconv.MessagingOperationTypeSend, "send", m, attrs)...),
+			)
+			defer func() {
+				end(span, err)
+			}()
+
+			Inject(ctx, m)
+			return next(ctx, m)
+		}
+	}
+}
+
+// SubTracing starts a consumer span `process <topic>` for each handling, the
+// span is linked to producer span carried by message and baggage is restored
+// to ctx. attrs are attached to span, such as subscription name.
+func SubTracing[CM SubMessage](system string, attrs ...attribute.KeyValue) mq.SubMiddleware[CM] {
+	return func(next mq.SubHandler[CM]) mq.SubHandler[CM] {
+		return func(ctx context.Context, m CM) (err error) {
+			remote := Extract(ctx, m)
+			options := []trace.SpanStartOption{
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(attributes(system, semconv.MessagingOperationTypeProcess, "process", m, attrs)...),
+				trace.WithAttributes(delivered(m)...),
+			}
+			if sc := trace.SpanContextFromContext(remote); sc.IsValid() {
+				options = append(options, trace.WithLinks(trace.Link{SpanContext: sc}))
+			}
+			// keeps local parent and takes baggage from message
+			ctx = trace.ContextWithSpanContext(remote, trace.SpanContextFromContext(ctx))
+			ctx, span := tracer(ctx).Start(ctx, "process "+m.Topic(), options...)
+			defer func() {
+				end(span, err)
+			}()
+
+			return next(ctx, m)
+		}
+	}
+}
+
+func attributes(system string, op attribute.KeyValue, name string, m any, attrs []attribute.KeyValue) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs)+5)
+	kvs = append(kvs, semconv.MessagingSystemKey.String(system), op, semconv.MessagingOperationName(name))
+	if x, ok := m.(mq.HasTopic); ok {
+		kvs = append(kvs, semconv.MessagingDestinationName(x.Topic()))
+	}
+	if x, ok := m.(mq.HasPayload); ok {
+		kvs = append(kvs, semconv.MessagingMessageBodySize(len(x.Payload())))
+	}
+	return append(kvs, attrs...)
+}
+
+// delivered returns attributes assigned when message is delivered
+func delivered(m any) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, 2)
+	if x, ok := m.(mq.HasPartitionID); ok {
+		kvs = append(kvs, semconv.MessagingDestinationPartitionID(strconv.FormatInt(x.PartitionID(), 10)))
+	}
+	if x, ok := m.(mq.HasMessageID); ok {
+		if id := x.MessageID(); id != "" {
+			kvs = append(kvs, semconv.MessagingMessageID(id))
+		}
+	}
+	return kvs
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,166 @@
+package mqotel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/xoctopus/x/testx"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/xoctopus/confx/internal/otel/providers"
+	"github.com/xoctopus/confx/pkg/types/mq"
+	"github.com/xoctopus/confx/pkg/types/mq/memq"
+	. "github.com/xoctopus/confx/pkg/types/mq/mqotel"
+)
+
+func TestInjectExtract(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("").Start(context.Background(), "parent")
+	defer span.End()
+	member, _ := baggage.NewMember("tenant", "t1")
+	bag, _ := baggage.New(member)
+	ctx = baggage.ContextWithBaggage(ctx, bag)
+
+	m := memq.NewProducerMessage("topic", nil)
+	Inject(ctx, m)
+	_, ok := m.ExtraValueOf("traceparent")
+	Expect(t, ok, BeTrue())
+
+	extracted := Extract(context.Background(), m)
+	Expect(t, trace.SpanContextFromContext(extracted).TraceID(), Equal(span.SpanContext().TraceID()))
+	Expect(t, trace.SpanContextFromContext(extracted).SpanID(), Equal(span.SpanContext().SpanID()))
+	Expect(t, baggage.FromContext(extracted).Member("tenant").Value(), Equal("t1"))
+
+	extracted = Extract(context.Background(), memq.NewProducerMessage("topic", nil))
+	Expect(t, trace.SpanContextFromContext(extracted).IsValid(), BeFalse())
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx := providers.WithTracerProvider(context.Background(), tp)
+
+	b := memq.NewBroker()
+	defer b.Close()
+
+	p, err := b.NewProducer(ctx, memq.WithPubTopic("traced"))
+	Expect(t, err, Succeed())
+	c, err := b.NewConsumer(ctx, memq.WithSubTopic("traced"), memq.WithSubName("sub"))
+	Expect(t, err, Succeed())
+
+	pctx, parent := tp.Tracer("").Start(ctx, "parent")
+	member, _ := baggage.NewMember("tenant", "t1")
+	bag, _ := baggage.New(member)
+	pctx = baggage.ContextWithBaggage(pctx, bag)
+	_, err = p.Publish(pctx, "traced", []byte("payload"))
+	Expect(t, err, Succeed())
+	parent.End()
+
+	type handled struct {
+		span   trace.SpanContext
+		tenant string
+	}
+	results := make(chan handled, 1)
+	cctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	go func() {
+		_ = c.Run(cctx, func(ctx context.Context, m memq.ConsumerMessage) error {
+			results <- handled{
+				span:   trace.SpanContextFromContext(ctx),
+				tenant: baggage.FromContext(ctx).Member("tenant").Value(),
+			}
+			return nil
+		})
+	}()
+
+	var result handled
+	select {
+	case result = <-results:
+	case <-cctx.Done():
+		t.Fatal("message not consumed in timeout")
+	}
+	Expect(t, c.Close(), Succeed())
+	Expect(t, result.tenant, Equal("t1"))
+
+	var producer, consumer sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		switch s.SpanKind() {
+		case trace.SpanKindProducer:
+			producer = s
+		case trace.SpanKindConsumer:
+			consumer = s
+		}
+	}
+	Expect(t, producer.Name(), Equal("send traced"))
+	Expect(t, producer.Parent().SpanID(), Equal(parent.SpanContext().SpanID()))
+
+	Expect(t, consumer.Name(), Equal("process traced"))
+	Expect(t, consumer.SpanContext().SpanID(), Equal(result.span.SpanID()))
+	Expect(t, consumer.Links(), HaveLen[[]sdktrace.Link](1))
+	Expect(t, consumer.Links()[0].SpanContext.SpanID(), Equal(producer.SpanContext().SpanID()))
+
+	attrs := consumer.Attributes()
+	for _, kv := range []struct {
+		key   string
+		value string
+	}{
+		{string(semconv.MessagingSystemKey), "memq"},
+		{string(semconv.MessagingDestinationNameKey), "traced"},
+		{string(semconv.MessagingDestinationSubscriptionNameKey), "sub"},
+		{string(semconv.MessagingDestinationPartitionIDKey), "0"},
+		{string(semconv.MessagingMessageIDKey), "0"},
+	} {
+		found := false
+		for _, attr := range attrs {
+			if string(attr.Key) == kv.key {
+				found = true
+				Expect(t, attr.Value.Emit(), Equal(kv.value))
+			}
+		}
+		Expect(t, found, BeTrue())
+	}
+}
+
+func TestWithoutTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx := providers.WithTracerProvider(context.Background(), tp)
+
+	b := memq.NewBroker()
+	defer b.Close()
+
+	p, err := b.NewProducer(ctx, memq.WithPubTopic("untraced"), mq.WithoutTracing())
+	Expect(t, err, Succeed())
+	c, err := b.NewConsumer(ctx, memq.WithSubTopic("untraced"), mq.WithoutTracing())
+	Expect(t, err, Succeed())
+
+	pctx, parent := tp.Tracer("").Start(ctx, "parent")
+	_, err = p.Publish(pctx, "untraced", []byte("payload"))
+	Expect(t, err, Succeed())
+	parent.End()
+
+	results := make(chan bool, 1)
+	cctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	go func() {
+		_ = c.Run(cctx, func(ctx context.Context, m memq.ConsumerMessage) error {
+			_, ok := m.ExtraValueOf("traceparent")
+			results <- ok
+			return nil
+		})
+	}()
+
+	select {
+	case injected := <-results:
+		Expect(t, injected, BeFalse())
+	case <-cctx.Done():
+		t.Fatal("message not consumed in timeout")
+	}
+	Expect(t, c.Close(), Succeed())
+	Expect(t, recorder.Ended(), HaveLen[[]sdktrace.ReadOnlySpan](1))
+}
@@ -1,5 +1,7 @@
 package types
 
+import "context"
+
 const MaskedPassword = "--------"
 
 // Password as a string underlying and implements SecurityStringer
@@ -8,3 +10,15 @@ type Password string
 func (p Password) String() string { return string(p) }
 
 func (p Password) SecurityString() string { return MaskedPassword }
+
+// Resolve resolves p if it is a secret reference, such as
+// file:///run/secrets/db or env://OTHER_VAR. it is called by Userinfo.Init,
+// see RegisterSecretResolver
+func (p *Password) Resolve(ctx context.Context) error {
+	v, err := ResolveSecret(ctx, string(*p))
+	if err != nil {
+		return err
+	}
+	*p = Password(v)
+	return nil
+}
@@ -0,0 +1,241 @@
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// algorithm limits events by state of key. lua scripts take KEYS[1] as state
+// key, ARGV[1] as requested events and ARGV[2:] from args, and reply
+// {allowed, remaining, retry_after_ms}. take is the local implementation of
+// script, now is unix milliseconds, next is the updated state and not updated
+// if it is empty.
+type algorithm interface {
+	init(Limit)
+	capacity() int64
+	script() string
+	args() []any
+	take(state string, now, n float64) (next string, ttl time.Duration, r Result)
+}
+
+// luaNow returns unix milliseconds by redis clock
+const luaNow = `local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + tonumber(t[2]) / 1000
+`
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// parseFloats parses state formatted as `f1|f2|...`, nil is returned if state is
+// invalid.
+func parseFloats(state string, n int) []float64 {
+	parts := strings.Split(state, "|")
+	if len(parts) != n {
+		return nil
+	}
+	fs := make([]float64, n)
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return nil
+		}
+		fs[i] = f
+	}
+	return fs
+}
+
+func millis(ms float64) time.Duration {
+	return time.Duration(math.Ceil(ms)) * time.Millisecond
+}
+
+// tokenBucket state is `tokens|last_refilled_ms`
+type tokenBucket struct {
+	burst float64
+	// rate is tokens refilled per millisecond
+	rate float64
+}
+
+func (a *tokenBucket) init(l Limit) {
+	a.burst = float64(l.Burst)
+	a.rate = float64(l.Rate) / float64(l.Period.Milliseconds())
+}
+
+func (a *tokenBucket) capacity() int64 { return int64(a.burst) }
+
+func (a *tokenBucket) script() string {
+	return luaNow + `local n, burst, rate = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local tokens, last = burst, now
+local s = redis.call('GET', KEYS[1])
+if s then
+	local i = string.find(s, '|', 1, true)
+	if i then
+		tokens = tonumber(string.sub(s, 1, i - 1)) or burst
+		last = tonumber(string.sub(s, i + 1)) or now
+	end
+end
+tokens = math.min(burst, tokens + math.max(0, now - last) * rate)
+if tokens < n then
+	return {0, math.floor(tokens), math.ceil((n - tokens) / rate)}
+end
+tokens = tokens - n
+redis.call('SET', KEYS[1], tostring(tokens) .. '|' .. tostring(now), 'PX', math.ceil(burst / rate))
+return {1, math.floor(tokens), 0}`
+}
+
+func (a *tokenBucket) args() []any {
+	return []any{formatFloat(a.burst), formatFloat(a.rate)}
+}
+
+func (a *tokenBucket) take(state string, now, n float64) (string, time.Duration, Result) {
+	tokens, last := a.burst, now
+	if fs := parseFloats(state, 2); fs != nil {
+		tokens, last = fs[0], fs[1]
+	}
+	tokens = min(a.burst, tokens+max(0, now-last)*a.rate)
+	if tokens < n {
+		return "", 0, Result{
+			Remaining:  int64(tokens),
+			RetryAfter: millis((n - tokens) / a.rate),
+		}
+	}
+	tokens -= n
+	next := formatFloat(tokens) + "|" + formatFloat(now)
+	return next, millis(a.burst / a.rate), Result{Allowed: true, Remaining: int64(tokens)}
+}
+
+// slidingWindow state is `window_start_ms|current_count|previous_count`
+type slidingWindow struct {
+	limit  float64
+	period float64
+}
+
+func (a *slidingWindow) init(l Limit) {
+	a.limit = float64(l.Rate)
+	a.period = float64(l.Period.Milliseconds())
+}
+
+func (a *slidingWindow) capacity() int64 { return int64(a.limit) }
+
+func (a *slidingWindow) script() string {
+	return luaNow + `local n, limit, period = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local start = math.floor(now / period) * period
+local ws, curr, prev = start, 0, 0
+local s = redis.call('GET', KEYS[1])
+if s then
+	local i = string.find(s, '|', 1, true)
+	local j = i and string.find(s, '|', i + 1, true)
+	if j then
+		ws = tonumber(string.sub(s, 1, i - 1)) or start
+		curr = tonumber(string.sub(s, i + 1, j - 1)) or 0
+		prev = tonumber(string.sub(s, j + 1)) or 0
+	end
+end
+if ws ~= start then
+	if start - ws == period then prev = curr else prev = 0 end
+	curr = 0
+end
+local elapsed = now - start
+local estimated = prev * (period - elapsed) / period + curr
+if estimated + n > limit then
+	local retry = period - elapsed
+	if prev > 0 and limit - curr - n >= 0 then
+		retry = period - elapsed - (limit - curr - n) * period / prev
+	end
+	return {0, 0, math.max(1, math.ceil(retry))}
+end
+curr = curr + n
+redis.call('SET', KEYS[1], string.format('%d|%d|%d', start, curr, prev), 'PX', math.ceil(period * 2))
+return {1, math.floor(limit - estimated - n), 0}`
+}
+
+func (a *slidingWindow) args() []any {
+	return []any{formatFloat(a.limit), formatFloat(a.period)}
+}
+
+func (a *slidingWindow) take(state string, now, n float64) (string, time.Duration, Result) {
+	start := math.Floor(now/a.period) * a.period
+	ws, curr, prev := start, 0.0, 0.0
+	if fs := parseFloats(state, 3); fs != nil {
+		ws, curr, prev = fs[0], fs[1], fs[2]
+	}
+	if ws != start {
+		if start-ws == a.period {
+			prev = curr
+		} else {
+			prev = 0
+		}
+		curr = 0
+	}
+
+	elapsed := now - start
+	estimated := prev*(a.period-elapsed)/a.period + curr
+	if estimated+n > a.limit {
+		retry := a.period - elapsed
+		if prev > 0 && a.limit-curr-n >= 0 {
+			retry = a.period - elapsed - (a.limit-curr-n)*a.period/prev
+		}
+		return "", 0, Result{RetryAfter: millis(max(1, retry))}
+	}
+	curr += n
+	next := formatFloat(start) + "|" + formatFloat(curr) + "|" + formatFloat(prev)
+	return next, millis(a.period * 2), Result{
+		Allowed:   true,
+		Remaining: int64(a.limit - estimated - n),
+	}
+}
+
+// gcra state is `theoretical_arrival_time_ms`
+type gcra struct {
+	burst float64
+	// interval is emission interval in milliseconds
+	interval float64
+}
+
+func (a *gcra) init(l Limit) {
+	a.burst = float64(l.Burst)
+	a.interval = float64(l.Period.Milliseconds()) / float64(l.Rate)
+}
+
+func (a *gcra) capacity() int64 { return int64(a.burst) }
+
+func (a *gcra) script() string {
+	return luaNow + `local n, burst, interval = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local tat = tonumber(redis.call('GET', KEYS[1]) or '') or now
+tat = math.max(tat, now)
+local offset = burst * interval
+local next = tat + n * interval
+local allow_at = next - offset
+if now < allow_at then
+	return {0, math.max(0, math.floor((offset - (tat - now)) / interval)), math.ceil(allow_at - now)}
+end
+redis.call('SET', KEYS[1], tostring(next), 'PX', math.max(1, math.ceil(next - now)))
+return {1, math.floor((offset - (next - now)) / interval), 0}`
+}
+
+func (a *gcra) args() []any {
+	return []any{formatFloat(a.burst), formatFloat(a.interval)}
+}
+
+func (a *gcra) take(state string, now, n float64) (string, time.Duration, Result) {
+	tat := now
+	if fs := parseFloats(state, 1); fs != nil {
+		tat = fs[0]
+	}
+	tat = max(tat, now)
+
+	offset := a.burst * a.interval
+	next := tat + n*a.interval
+	if allowAt := next - offset; now < allowAt {
+		return "", 0, Result{
+			Remaining:  int64(max(0, math.Floor((offset-(tat-now))/a.interval))),
+			RetryAfter: millis(allowAt - now),
+		}
+	}
+	return formatFloat(next), millis(max(1, next-now)), Result{
+		Allowed:   true,
+		Remaining: int64(math.Floor((offset - (next - now)) / a.interval)),
+	}
+}
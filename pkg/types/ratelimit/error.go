@@ -0,0 +1,11 @@
+package ratelimit
+
+// Error presents error codes of rate limiter
+// +genx:code
+type Error int8
+
+const (
+	ERROR_UNDEFINED             Error = iota
+	ERROR__LIMITER_STORE_FAILED       // limiter store failed
+	ERROR__EXCEEDS_BURST              // requested tokens exceed burst of limit
+)
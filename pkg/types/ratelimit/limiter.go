@@ -0,0 +1,212 @@
+// Package ratelimit provides distributed rate limiters on top of kv.Store and
+// kg.KeyGen.
+//
+// Token bucket, sliding window and GCRA are supported. Limiter state of each key
+// is kept in store key <kg.SharedKey(Domain, name:key)>, so that peers of the
+// same creator share limits. If the store implements kv.Executor, such as
+// confredis.Endpoint, limiting is executed atomically by lua scripts with the
+// clock of redis. Otherwise, or the store is kv.InProcess, such as memkv.Store,
+// it falls back to local operations serialized by the limiter, which is only
+// safe in a single process.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xoctopus/x/codex"
+	"github.com/xoctopus/x/misc/must"
+
+	"github.com/xoctopus/confx/pkg/types/kg"
+	"github.com/xoctopus/confx/pkg/types/kv"
+)
+
+// Domain is kg domain of limiter keys
+const Domain = "RATELIMIT"
+
+// Limit allows Rate events per Period with bursts of at most Burst events
+type Limit struct {
+	Rate   int64
+	Period time.Duration
+	// Burst is capacity of token bucket and max burst of GCRA. it is ignored by
+	// sliding window. default is Rate.
+	Burst int64
+}
+
+// PerSecond returns Limit allows rate events per second
+func PerSecond(rate int64) Limit {
+	return Limit{Rate: rate, Period: time.Second}
+}
+
+// PerMinute returns Limit allows rate events per minute
+func PerMinute(rate int64) Limit {
+	return Limit{Rate: rate, Period: time.Minute}
+}
+
+// Result is the result of limiting
+type Result struct {
+	// Allowed reports if events are allowed
+	Allowed bool
+	// Remaining is the number of events can be allowed immediately
+	Remaining int64
+	// RetryAfter is the duration to wait before events can be allowed. it is 0
+	// if allowed.
+	RetryAfter time.Duration
+}
+
+// NewTokenBucket creates Limiter by token bucket algorithm. the bucket holds at
+// most Burst tokens and is refilled Rate tokens per Period.
+func NewTokenBucket(s kv.Store, g *kg.KeyGen, name string, l Limit) *Limiter {
+	return newLimiter(s, g, name, l, &tokenBucket{})
+}
+
+// NewSlidingWindow creates Limiter by sliding window counter. events in the
+// last Period are estimated from counters of the current and previous fixed
+// windows, and at most Rate events are allowed.
+func NewSlidingWindow(s kv.Store, g *kg.KeyGen, name string, l Limit) *Limiter {
+	return newLimiter(s, g, name, l, &slidingWindow{})
+}
+
+// NewGCRA creates Limiter by generic cell rate algorithm. events are spaced by
+// Period/Rate, and at most Burst events are allowed at once.
+func NewGCRA(s kv.Store, g *kg.KeyGen, name string, l Limit) *Limiter {
+	return newLimiter(s, g, name, l, &gcra{})
+}
+
+func newLimiter(s kv.Store, g *kg.KeyGen, name string, l Limit, a algorithm) *Limiter {
+	must.BeTrueF(s != nil, "ratelimit: store is required")
+	must.BeTrueF(g != nil, "ratelimit: key generator is required")
+	must.BeTrueF(name != "", "ratelimit: name is required")
+	must.BeTrueF(l.Rate > 0, "ratelimit: rate should be positive")
+	must.BeTrueF(l.Period >= time.Millisecond, "ratelimit: period should be at least 1ms")
+	if l.Burst <= 0 {
+		l.Burst = l.Rate
+	}
+	a.init(l)
+
+	x := &Limiter{store: s, kg: g, name: name, limit: l, algo: a}
+	if e, ok := s.(kv.Executor); ok {
+		if _, local := s.(kv.InProcess); !local {
+			x.exec = e
+		}
+	}
+	return x
+}
+
+// Limiter limits events of keys
+type Limiter struct {
+	store kv.Store
+	exec  kv.Executor
+	kg    *kg.KeyGen
+	name  string
+	limit Limit
+	algo  algorithm
+	// mtx serializes local limiting without kv.Executor
+	mtx sync.Mutex
+}
+
+// Limit returns limit of l
+func (l *Limiter) Limit() Limit {
+	return l.limit
+}
+
+// Key returns store key of key
+func (l *Limiter) Key(key string) string {
+	return l.kg.SharedKey(Domain, l.name+":"+key)
+}
+
+// Allow reports if an event of key is allowed
+func (l *Limiter) Allow(ctx context.Context, key string) (Result, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN reports if n events of key are allowed. ERROR__EXCEEDS_BURST is
+// returned if n exceeds the max number of events allowed at once.
+func (l *Limiter) AllowN(ctx context.Context, key string, n int64) (Result, error) {
+	if n <= 0 {
+		return Result{Allowed: true}, nil
+	}
+	if capacity := l.algo.capacity(); n > capacity {
+		return Result{}, codex.Errorf(ERROR__EXCEEDS_BURST, "ratelimit: %d > %d", n, capacity)
+	}
+
+	k := l.Key(key)
+	if l.exec != nil {
+		args := append([]any{l.algo.script(), 1, k, n}, l.algo.args()...)
+		v, err := l.exec.Exec(ctx, "EVAL", args...)
+		if err != nil {
+			return Result{}, codex.Wrap(ERROR__LIMITER_STORE_FAILED, err)
+		}
+		r, err := toResult(v)
+		if err != nil {
+			return Result{}, codex.Wrap(ERROR__LIMITER_STORE_FAILED, err)
+		}
+		return r, nil
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	state, _, err := l.store.Get(ctx, k)
+	if err != nil {
+		return Result{}, codex.Wrap(ERROR__LIMITER_STORE_FAILED, err)
+	}
+	now := float64(time.Now().UnixMicro()) / 1000
+	next, ttl, r := l.algo.take(state, now, float64(n))
+	if next != "" {
+		if err = l.store.Set(ctx, k, next, ttl); err != nil {
+			return Result{}, codex.Wrap(ERROR__LIMITER_STORE_FAILED, err)
+		}
+	}
+	return r, nil
+}
+
+// Wait waits until an event of key is allowed or ctx done
+func (l *Limiter) Wait(ctx context.Context, key string) error {
+	for {
+		r, err := l.Allow(ctx, key)
+		if err != nil {
+			return err
+		}
+		if r.Allowed {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-time.After(max(r.RetryAfter, time.Millisecond)):
+		}
+	}
+}
+
+// toResult converts script reply {allowed, remaining, retry_after_ms}
+func toResult(v any) (Result, error) {
+	vs, ok := v.([]any)
+	if !ok || len(vs) != 3 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected script result %v", v)
+	}
+	ns := [3]int64{}
+	for i := range vs {
+		switch x := vs[i].(type) {
+		case int64:
+			ns[i] = x
+		case string:
+			n, err := strconv.ParseInt(strings.TrimSpace(x), 10, 64)
+			if err != nil {
+				return Result{}, err
+			}
+			ns[i] = n
+		default:
+			return Result{}, fmt.Errorf("ratelimit: unexpected script result %T", vs[i])
+		}
+	}
+	return Result{
+		Allowed:    ns[0] == 1,
+		Remaining:  ns[1],
+		RetryAfter: time.Duration(ns[2]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,146 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/types/kg"
+	"github.com/xoctopus/confx/pkg/types/kv/memkv"
+	"github.com/xoctopus/confx/pkg/types/mq"
+	. "github.com/xoctopus/confx/pkg/types/ratelimit"
+)
+
+func newKeyGen() *kg.KeyGen {
+	g := &kg.KeyGen{}
+	g.Init("svc-ratelimit")
+	return g
+}
+
+func allowed(t *testing.T, l *Limiter, key string, n int) int {
+	t.Helper()
+	count := 0
+	for range n {
+		r, err := l.Allow(context.Background(), key)
+		Expect(t, err, Succeed())
+		if r.Allowed {
+			count++
+		}
+	}
+	return count
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	limit := Limit{Rate: 5, Period: 100 * time.Millisecond}
+
+	t.Run("TokenBucket", func(t *testing.T) {
+		l := NewTokenBucket(memkv.NewStore(), newKeyGen(), "api", Limit{Rate: 5, Period: 100 * time.Millisecond, Burst: 3})
+		Expect(t, l.Key("k"), Equal("SVC_RATELIMIT:PEER:RATELIMIT:api:k"))
+		Expect(t, allowed(t, l, "k", 10), Equal(3))
+		// keys are limited separately
+		Expect(t, allowed(t, l, "other", 1), Equal(1))
+
+		r, err := l.Allow(ctx, "k")
+		Expect(t, err, Succeed())
+		Expect(t, r.Allowed, BeFalse())
+		Expect(t, r.RetryAfter > 0 && r.RetryAfter <= 20*time.Millisecond, BeTrue())
+
+		time.Sleep(45 * time.Millisecond)
+		Expect(t, allowed(t, l, "k", 10), Equal(2))
+
+		_, err = l.AllowN(ctx, "k", 4)
+		Expect(t, err, IsCodeError(ERROR__EXCEEDS_BURST))
+	})
+
+	t.Run("SlidingWindow", func(t *testing.T) {
+		l := NewSlidingWindow(memkv.NewStore(), newKeyGen(), "api", limit)
+		r, err := l.AllowN(ctx, "k", 2)
+		Expect(t, err, Succeed())
+		Expect(t, r.Allowed, BeTrue())
+		Expect(t, r.Remaining, Equal(int64(3)))
+		Expect(t, allowed(t, l, "k", 10), Equal(3))
+
+		r, _ = l.Allow(ctx, "k")
+		Expect(t, r.Allowed, BeFalse())
+		Expect(t, r.RetryAfter > 0, BeTrue())
+
+		// events of previous window are weighted
+		time.Sleep(r.RetryAfter)
+		Expect(t, allowed(t, l, "k", 10) < 5, BeTrue())
+	})
+
+	t.Run("GCRA", func(t *testing.T) {
+		l := NewGCRA(memkv.NewStore(), newKeyGen(), "api", Limit{Rate: 5, Period: 100 * time.Millisecond, Burst: 2})
+		Expect(t, allowed(t, l, "k", 10), Equal(2))
+
+		r, _ := l.Allow(ctx, "k")
+		Expect(t, r.Allowed, BeFalse())
+		Expect(t, r.RetryAfter > 0 && r.RetryAfter <= 20*time.Millisecond, BeTrue())
+
+		// events are spaced by 20ms
+		time.Sleep(r.RetryAfter)
+		Expect(t, allowed(t, l, "k", 10), Equal(1))
+	})
+
+	t.Run("Wait", func(t *testing.T) {
+		l := NewGCRA(memkv.NewStore(), newKeyGen(), "api", Limit{Rate: 100, Period: time.Second, Burst: 1})
+		started := time.Now()
+		for range 4 {
+			Expect(t, l.Wait(ctx, "k"), Succeed())
+		}
+		Expect(t, time.Since(started) >= 25*time.Millisecond, BeTrue())
+
+		timeout, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+		defer cancel()
+		Expect(t, l.Wait(timeout, "other"), Succeed())
+		err := l.Wait(timeout, "other")
+		Expect(t, errors.Is(err, context.DeadlineExceeded), BeTrue())
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("Sub", func(t *testing.T) {
+		l := NewTokenBucket(memkv.NewStore(), newKeyGen(), "sub", Limit{Rate: 1, Period: time.Minute})
+		handled := 0
+		h := mq.ChainSub(
+			func(context.Context, string) error { handled++; return nil },
+			Sub(l, func(m string) string { return m }),
+		)
+		Expect(t, h(context.Background(), "tenant"), Succeed())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		Expect(t, h(ctx, "tenant"), IsCodeError(mq.ERROR__RATE_LIMITED))
+		Expect(t, handled, Equal(1))
+	})
+
+	t.Run("HTTP", func(t *testing.T) {
+		l := NewTokenBucket(memkv.NewStore(), newKeyGen(), "http", Limit{Rate: 1, Period: time.Minute})
+		h := HTTP(l, func(r *http.Request) string { return r.Header.Get("X-Api-Key") })(
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) }),
+		)
+
+		serve := func() *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("X-Api-Key", "key")
+			h.ServeHTTP(w, r)
+			return w
+		}
+
+		w := serve()
+		Expect(t, w.Code, Equal(http.StatusNoContent))
+		Expect(t, w.Header().Get("X-RateLimit-Limit"), Equal("1"))
+		Expect(t, w.Header().Get("X-RateLimit-Remaining"), Equal("0"))
+
+		w = serve()
+		Expect(t, w.Code, Equal(http.StatusTooManyRequests))
+		Expect(t, w.Header().Get("Retry-After"), Equal("60"))
+	})
+}
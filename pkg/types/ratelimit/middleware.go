@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/xoctopus/logx"
+	"github.com/xoctopus/x/codex"
+
+	"github.com/xoctopus/confx/pkg/types/mq"
+)
+
+// Sub returns mq.SubMiddleware waits l before handling. messages are limited by
+// key, eg: tenant or partner. mq.ERROR__RATE_LIMITED is returned if ctx is done
+// before permitted.
+func Sub[M any](l *Limiter, key func(M) string) mq.SubMiddleware[M] {
+	return func(next mq.SubHandler[M]) mq.SubHandler[M] {
+		return func(ctx context.Context, m M) error {
+			if err := l.Wait(ctx, key(m)); err != nil {
+				return codex.Wrap(mq.ERROR__RATE_LIMITED, err)
+			}
+			return next(ctx, m)
+		}
+	}
+}
+
+// HTTP returns net/http middleware limits requests by key, eg: client ip or api
+// key. limited request is responded 429 with header `Retry-After`. requests are
+// passed if limiter store failed.
+func HTTP(l *Limiter, key func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := l.Allow(r.Context(), key(r))
+			if err != nil {
+				logx.From(r.Context()).Warn(err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(l.limit.Rate, 10))
+			w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(res.Remaining, 10))
+			if !res.Allowed {
+				seconds := int64(math.Ceil(res.RetryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package types
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/xoctopus/x/reflectx"
+)
+
+// Reloadable is implemented by components which can apply configuration
+// changes at runtime. changed holds the env keys (relative to the component)
+// whose values were changed, only the changed values are assigned before Reload
+// called, and it is done while component locked if it implements sync.Locker.
+// values resolved by Init, such as Userinfo and Password, are assigned as a
+// whole and initialized again.
+type Reloadable interface {
+	Reload(ctx context.Context, changed []string) error
+}
+
+func IsReloadable(v any) bool {
+	switch x := v.(type) {
+	case Reloadable:
+		return true
+	case reflect.Value:
+		x = reflectx.IndirectNew(v)
+		if x == reflectx.InvalidValue {
+			return false
+		}
+		if x.CanInterface() {
+			if IsReloadable(x.Interface()) {
+				return true
+			}
+		}
+		if x.CanAddr() {
+			if x.Addr().CanInterface() {
+				if IsReloadable(x.Addr().Interface()) {
+					return true
+				}
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func Reload(ctx context.Context, v any, changed []string) error {
+	switch x := v.(type) {
+	case Reloadable:
+		return x.Reload(ctx, changed)
+	case reflect.Value:
+		x = reflectx.IndirectNew(v)
+		if x == reflectx.InvalidValue {
+			return nil
+		}
+		if x.CanInterface() {
+			if IsReloadable(x.Interface()) {
+				return Reload(ctx, x.Interface(), changed)
+			}
+		}
+		if x.CanAddr() {
+			if x.Addr().CanInterface() {
+				if IsReloadable(x.Addr().Interface()) {
+					return Reload(ctx, x.Addr().Interface(), changed)
+				}
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+}
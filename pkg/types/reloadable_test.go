@@ -0,0 +1,59 @@
+package types_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	. "github.com/xoctopus/x/testx"
+
+	"github.com/xoctopus/confx/pkg/types"
+)
+
+type Reloadable struct {
+	changed []string
+}
+
+func (r *Reloadable) Reload(_ context.Context, changed []string) error {
+	r.changed = changed
+	if len(changed) == 0 {
+		return errors.New("nothing changed")
+	}
+	return nil
+}
+
+func TestIsReloadable(t *testing.T) {
+	for i, v := range [...]struct {
+		v   any
+		can bool
+	}{
+		{&Reloadable{}, true},
+		{reflect.ValueOf(&Reloadable{}), true},
+		{Reloadable{}, false},
+		{reflect.ValueOf(Reloadable{}), false},
+		{reflect.ValueOf(&struct{ Reloadable }{}), true},
+		{reflect.ValueOf(&struct{ _v Reloadable }{}).Elem().Field(0), false},
+		{reflect.ValueOf(&struct{ V_ Reloadable }{}).Elem().Field(0), true},
+		{reflect.ValueOf(&struct{ V_ *Reloadable }{}).Elem().Field(0), true},
+	} {
+		_ = i
+		Expect(t, types.IsReloadable(v.v), Equal(v.can))
+	}
+}
+
+func TestReload(t *testing.T) {
+	ctx := context.Background()
+
+	v := &Reloadable{}
+	Expect(t, types.Reload(ctx, v, []string{"Level"}), Succeed())
+	Expect(t, v.changed, Equal([]string{"Level"}))
+
+	v = &Reloadable{}
+	Expect(t, types.Reload(ctx, reflect.ValueOf(v), []string{"PoolSize"}), Succeed())
+	Expect(t, v.changed, Equal([]string{"PoolSize"}))
+
+	Expect(t, types.Reload(ctx, reflect.ValueOf(v), nil), Failed())
+	Expect(t, types.Reload(ctx, &struct{}{}, nil), Succeed())
+	Expect(t, types.Reload(ctx, reflect.ValueOf((*Reloadable)(nil)), nil), Succeed())
+}
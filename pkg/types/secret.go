@@ -0,0 +1,168 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SecretRef is a reference to a secret held by a Password, formatted as
+// `scheme://path#key`, eg:
+//
+//	file:///run/secrets/db
+//	env://OTHER_VAR
+//	vault://secret/data/db#password
+type SecretRef struct {
+	Scheme string
+	Path   string
+	Key    string
+}
+
+func (r SecretRef) String() string {
+	s := r.Scheme + "://" + r.Path
+	if r.Key != "" {
+		s += "#" + r.Key
+	}
+	return s
+}
+
+// LiteralSecretPrefix escapes a plain value formatted as SecretRef, the prefix
+// is trimmed and the rest is not resolved, eg: `literal:env://x` is `env://x`.
+const LiteralSecretPrefix = "literal:"
+
+// ParseSecretRef parses s as a SecretRef. it returns false if s is not formatted
+// as `scheme://path` or no resolver registered for the scheme, and s should be
+// treated as a plain value.
+func ParseSecretRef(s string) (SecretRef, bool) {
+	scheme, rest, ok := strings.Cut(s, "://")
+	if !ok || scheme == "" {
+		return SecretRef{}, false
+	}
+	if _, ok = SecretResolverOf(scheme); !ok {
+		return SecretRef{}, false
+	}
+	path, key, _ := strings.Cut(rest, "#")
+	return SecretRef{Scheme: scheme, Path: path, Key: key}, true
+}
+
+// SecretResolver resolves a secret reference to its plain value.
+type SecretResolver interface {
+	Resolve(ctx context.Context, ref SecretRef) (string, error)
+}
+
+// SecretResolverFunc is an adaptor to allow the use of ordinary functions as
+// SecretResolver.
+type SecretResolverFunc func(ctx context.Context, ref SecretRef) (string, error)
+
+func (f SecretResolverFunc) Resolve(ctx context.Context, ref SecretRef) (string, error) {
+	return f(ctx, ref)
+}
+
+var resolvers = struct {
+	sync.RWMutex
+	m map[string]SecretResolver
+}{
+	m: map[string]SecretResolver{
+		"file": SecretResolverFunc(resolveFile),
+		"env":  SecretResolverFunc(resolveEnv),
+	},
+}
+
+// RegisterSecretResolver registers resolver for scheme, the registered one is
+// replaced. `file` and `env` are registered by default, applications register
+// others such as `vault` and `kms` before components initialized. a nil resolver
+// unregisters the scheme.
+func RegisterSecretResolver(scheme string, resolver SecretResolver) {
+	resolvers.Lock()
+	defer resolvers.Unlock()
+
+	if resolver == nil {
+		delete(resolvers.m, scheme)
+		return
+	}
+	resolvers.m[scheme] = resolver
+}
+
+// SecretResolverOf returns registered resolver of scheme
+func SecretResolverOf(scheme string) (SecretResolver, bool) {
+	resolvers.RLock()
+	defer resolvers.RUnlock()
+
+	r, ok := resolvers.m[scheme]
+	return r, ok
+}
+
+// ResolveSecret resolves s if it is a SecretRef, otherwise s is returned. s
+// escaped by LiteralSecretPrefix is returned without the prefix.
+func ResolveSecret(ctx context.Context, s string) (string, error) {
+	if v, ok := strings.CutPrefix(s, LiteralSecretPrefix); ok {
+		return v, nil
+	}
+	ref, ok := ParseSecretRef(s)
+	if !ok {
+		return s, nil
+	}
+	r, _ := SecretResolverOf(ref.Scheme)
+	v, err := r.Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret `%s`: %w", ref, err)
+	}
+	return v, nil
+}
+
+// resolveFile reads secret from file, eg: file:///run/secrets/db. trailing
+// newlines are trimmed.
+func resolveFile(_ context.Context, ref SecretRef) (string, error) {
+	data, err := os.ReadFile(ref.Path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// resolveEnv reads secret from env var, eg: env://OTHER_VAR
+func resolveEnv(_ context.Context, ref SecretRef) (string, error) {
+	v, ok := os.LookupEnv(ref.Path)
+	if !ok {
+		return "", fmt.Errorf("env var `%s` is not set", ref.Path)
+	}
+	return v, nil
+}
+
+// NewMemorySecretResolver returns an in-memory SecretResolver for testing.
+// secrets are keyed by `path` or `path#key`.
+func NewMemorySecretResolver(secrets map[string]string) *MemorySecretResolver {
+	r := &MemorySecretResolver{secrets: make(map[string]string)}
+	for k, v := range secrets {
+		r.secrets[k] = v
+	}
+	return r
+}
+
+type MemorySecretResolver struct {
+	mtx     sync.RWMutex
+	secrets map[string]string
+}
+
+// Set sets secret of `path` or `path#key`
+func (r *MemorySecretResolver) Set(ref, secret string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.secrets[ref] = secret
+}
+
+func (r *MemorySecretResolver) Resolve(_ context.Context, ref SecretRef) (string, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	k := ref.Path
+	if ref.Key != "" {
+		k += "#" + ref.Key
+	}
+	if v, ok := r.secrets[k]; ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("secret `%s` not found", k)
+}
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"net/url"
@@ -9,7 +10,8 @@ import (
 )
 
 // Userinfo likes url.Userinfo but embed aes decryptor if DecryptKey is set.
-// and implemented SecurityString to hide password
+// and implemented SecurityString to hide password. Password can be a secret
+// reference which is resolved by registered SecretResolver when Init.
 type Userinfo struct {
 	Username string
 	Password Password
@@ -17,9 +19,30 @@ type Userinfo struct {
 	// DecryptKey if not empty decrypt password by AES with ECB mode and PKCS7
 	// padding. the value is the raw decrypt key, not an environment variable name.
 	DecryptKey string
+
+	// initialized marks Password resolved and decrypted
+	initialized bool
+}
+
+// Init resolves and decrypts Password with background context
+func (u *Userinfo) Init() error {
+	return u.InitByContext(context.Background())
 }
 
-func (u *Userinfo) Init() (err error) {
+// InitByContext resolves and decrypts Password, ctx is passed to SecretResolver.
+// it takes effect once, Password is kept when initialized again, eg: by its
+// endpoint and by appx.
+func (u *Userinfo) InitByContext(ctx context.Context) (err error) {
+	if u.initialized {
+		return nil
+	}
+	defer func() {
+		u.initialized = err == nil
+	}()
+
+	if err = u.Password.Resolve(ctx); err != nil {
+		return err
+	}
 	if len(u.Password) > 0 && len(u.DecryptKey) > 0 {
 		var (
 			cipher []byte
@@ -20,6 +20,9 @@ func TestUserinfo(t *testing.T) {
 	Expect(t, u.IsZero(), BeFalse())
 	Expect(t, u.Init(), Succeed())
 	Expect(t, u.Password.String(), Equal("rhdsicyjzbwbtdwnxcei"))
+	// decrypted password is kept when initialized again
+	Expect(t, u.Init(), Succeed())
+	Expect(t, u.Password.String(), Equal("rhdsicyjzbwbtdwnxcei"))
 	Expect(t, u.String(), Equal("username:rhdsicyjzbwbtdwnxcei"))
 	Expect(t, u.SecurityString(), Equal("username:"+types.MaskedPassword))
 
@@ -29,6 +29,19 @@ func (v *Endpoint[Option]) DocOf(names ...string) ([]string, bool) {
 	return []string{"a connectable endpoint"}, true
 }
 
+func (v *MemorySecretResolver) DocOf(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "mtx":
+			return []string{}, true
+		case "secrets":
+			return []string{}, true
+		}
+		return []string{}, false
+	}
+	return []string{}, true
+}
+
 func (v *Password) DocOf(names ...string) ([]string, bool) {
 	if len(names) > 0 {
 		return []string{}, false
@@ -36,6 +49,21 @@ func (v *Password) DocOf(names ...string) ([]string, bool) {
 	return []string{"as a string underlying and implements SecurityStringer"}, true
 }
 
+func (v *SecretRef) DocOf(names ...string) ([]string, bool) {
+	if len(names) > 0 {
+		switch names[0] {
+		case "Scheme":
+			return []string{}, true
+		case "Path":
+			return []string{}, true
+		case "Key":
+			return []string{}, true
+		}
+		return []string{}, false
+	}
+	return []string{"is a reference to a secret held by a Password, formatted as", "`scheme://path#key`, eg:", "", "\tfile:///run/secrets/db", "\tenv://OTHER_VAR", "\tvault://secret/data/db#password"}, true
+}
+
 func (v *Userinfo) DocOf(names ...string) ([]string, bool) {
 	if len(names) > 0 {
 		switch names[0] {
@@ -48,5 +76,5 @@ func (v *Userinfo) DocOf(names ...string) ([]string, bool) {
 		}
 		return []string{}, false
 	}
-	return []string{"likes url.Userinfo but embed aes decryptor if DecryptKey is set.", "and implemented SecurityString to hide password"}, true
+	return []string{"likes url.Userinfo but embed aes decryptor if DecryptKey is set.", "and implemented SecurityString to hide password. Password can be a secret", "reference which is resolved by registered SecretResolver when Init."}, true
 }